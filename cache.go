@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// typeKey identifies a built sszType by the Go type and the size tags applied
+// on it, as the same Go type can map to different ssz types under different tags.
+type typeKey struct {
+	typ  types.Type
+	tags string
+}
+
+// buildFrame is an entry of the build stack. It's either a named type being
+// built or a boundary marking that a variable-length list was entered.
+type buildFrame struct {
+	key   typeKey
+	named *types.Named // nil for list boundaries
+}
+
+// typeCache shares the sszType nodes built for named types. Besides avoiding
+// rebuilding types referenced from many places, it allows types that refer to
+// themselves to be built, e.g. a struct holding a list of its own pointers.
+type typeCache struct {
+	types map[typeKey]sszType
+	stack []buildFrame
+}
+
+func newTypeCache() *typeCache {
+	return &typeCache{
+		types: make(map[typeKey]sszType),
+	}
+}
+
+func (c *typeCache) key(typ types.Type, tags []sizeTag) typeKey {
+	return typeKey{typ: typ, tags: fmt.Sprint(tags)}
+}
+
+// lookup returns the type node built or being built for the given key. An error
+// is returned if the reference forms a recursion which is not broken by any
+// variable-length list, since such a type has an infinite size.
+func (c *typeCache) lookup(named *types.Named, key typeKey) (sszType, bool, error) {
+	pos := -1
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i].named != nil && c.stack[i].key == key {
+			pos = i
+			break
+		}
+	}
+	typ, ok := c.types[key]
+	if pos == -1 {
+		return typ, ok, nil
+	}
+	// The type is still being built, check the recursion is legal
+	var (
+		path  []string
+		valid bool
+	)
+	for _, frame := range c.stack[pos:] {
+		if frame.named == nil {
+			valid = true
+			continue
+		}
+		path = append(path, frame.named.Obj().Name())
+	}
+	path = append(path, named.Obj().Name())
+	if !valid {
+		return nil, false, fmt.Errorf("recursive type %s has infinite size (%s)", named.Obj().Name(), strings.Join(path, " -> "))
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("recursive type %s is not supported, recursion is only allowed through structs (%s)", named.Obj().Name(), strings.Join(path, " -> "))
+	}
+	return typ, true, nil
+}
+
+// enter marks the named type as being built.
+func (c *typeCache) enter(named *types.Named, key typeKey) {
+	c.stack = append(c.stack, buildFrame{key: key, named: named})
+}
+
+// enterList marks that a variable-length list was entered. Recursive references
+// beyond it are legal, as the recursion can be terminated by an empty list.
+func (c *typeCache) enterList() {
+	c.stack = append(c.stack, buildFrame{})
+}
+
+// leave pops the innermost frame from the build stack.
+func (c *typeCache) leave() {
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// define registers the node of the named type currently being built, making
+// it visible to recursive references before its construction is finished.
+func (c *typeCache) define(named *types.Named, typ sszType) {
+	if named == nil {
+		return
+	}
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i].named == named {
+			c.types[c.stack[i].key] = typ
+			return
+		}
+	}
+}
+
+// store caches the fully built node of the named type.
+func (c *typeCache) store(key typeKey, typ sszType) {
+	c.types[key] = typ
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// isValue reports whether the Go representation of the type holds no
+// references, in which case it's copyable and comparable as a plain value.
+func isValue(typ sszType) bool {
+	switch t := typ.(type) {
+	case *sszBasic:
+		return true
+	case *sszVector:
+		return isValue(t.elem)
+	}
+	return false
+}
+
+func (b *sszBasic) genCopy(ctx *genContext, dst string, src string) string {
+	return fmt.Sprintf("%s = %s\n", dst, src)
+}
+
+func (v *sszVector) genCopy(ctx *genContext, dst string, src string) string {
+	if isValue(v) {
+		return fmt.Sprintf("%s = %s\n", dst, src)
+	}
+	var (
+		b   bytes.Buffer
+		cnt = ctx.tmpVar("i")
+	)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+	fmt.Fprint(&b, v.elem.genCopy(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (l *sszList) genCopy(ctx *genContext, dst string, src string) string {
+	var (
+		b   bytes.Buffer
+		typ = ctx.typeString(l.slice)
+	)
+	if l.named != nil {
+		typ = ctx.typeString(l.named)
+	}
+	// Nil lists are kept as nil to not change the representation
+	fmt.Fprintf(&b, "if %s != nil {\n", src)
+	fmt.Fprintf(&b, "%s = make(%s, len(%s))\n", dst, typ, src)
+	if isValue(l.elem) {
+		fmt.Fprintf(&b, "copy(%s, %s)\n", dst, src)
+	} else {
+		cnt := ctx.tmpVar("i")
+		fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+		fmt.Fprint(&b, l.elem.genCopy(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+		fmt.Fprint(&b, "}\n")
+	}
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (s *sszStruct) genCopy(ctx *genContext, dst string, src string) string {
+	if !ctx.topType {
+		return fmt.Sprintf("%s = *%s.Copy()\n", dst, src)
+	}
+	ctx.topType = false
+
+	// Shallow copy all the fields first, including the ones not covered by
+	// ssz, and then deep copy the ssz fields holding references. The hash
+	// cache is dropped from the copy instead of sharing the cached trees.
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%s = *%s\n", dst, src)
+	fmt.Fprint(&b, s.genCacheReset(dst))
+	for i, field := range s.fields {
+		if isValue(field) {
+			continue
+		}
+		fmt.Fprint(&b, field.genCopy(ctx, fmt.Sprintf("%s.%s", dst, s.fieldNames[i]), fmt.Sprintf("%s.%s", src, s.fieldNames[i])))
+	}
+	return b.String()
+}
+
+func (p *sszPointer) genCopy(ctx *genContext, dst string, src string) string {
+	if _, ok := p.elem.(*sszStruct); ok {
+		return fmt.Sprintf("%s = %s.Copy()\n", dst, src)
+	}
+	var (
+		b   bytes.Buffer
+		cid = ctx.tmpVar("c")
+	)
+	fmt.Fprintf(&b, "if %s != nil {\n", src)
+	fmt.Fprintf(&b, "%s := new(%s)\n", cid, p.elem.typeName())
+	fmt.Fprint(&b, p.elem.genCopy(ctx, "*"+cid, "*"+src))
+	fmt.Fprintf(&b, "%s = %s\n", dst, cid)
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// eachDirective is the comment opting the list and vector fields of a container
+// into the decoders visiting their items one by one:
+//
+//	//sszgen:each BeaconState Validators Balances
+const eachDirective = "//sszgen:each "
+
+// parseEachDirectives marks the fields named by the each directives declared
+// in the files on the built types.
+func parseEachDirectives(files []*ast.File, built []sszType) error {
+	structs := make(map[string]*sszStruct)
+	for _, typ := range built {
+		if s, ok := typ.(*sszStruct); ok {
+			structs[s.typeName()] = s
+		}
+	}
+	for _, file := range files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if !strings.HasPrefix(comment.Text, eachDirective) {
+					continue
+				}
+				if err := parseEachFields(strings.TrimPrefix(comment.Text, eachDirective), structs); err != nil {
+					return fmt.Errorf("invalid each directive %q: %v", comment.Text, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseEachFields(input string, structs map[string]*sszStruct) error {
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		return fmt.Errorf("no field specified")
+	}
+	s, ok := structs[fields[0]]
+	if !ok {
+		return fmt.Errorf("no container named %s", fields[0])
+	}
+	views := make(map[*sszStruct]*sszStruct)
+	for _, name := range fields[1:] {
+		index := -1
+		for i := range s.fields {
+			if s.fieldNames[i] == name {
+				index = i
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("no field named %s", name)
+		}
+		field := s.fields[index]
+		if forkGated(s) {
+			field = forkView(field, ssz.ForkPhase0, views)
+		}
+		if _, _, ok := itemsLayout(field); !ok {
+			return fmt.Errorf("field %s is not a list or vector", name)
+		}
+		if s.each == nil {
+			s.each = make(map[string]bool)
+		}
+		s.each[name] = true
+	}
+	return nil
+}
+
+// generateEach generates the decoding of the struct which visits the items of
+// a list or vector field one by one as they are read from the stream, instead
+// of collecting them into the field. The item is decoded into the same value
+// each time, which is only valid until the visitor returns. The field itself
+// is left untouched. Only the fields opted in by the each directives have it.
+func generateEach(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok || len(s.each) == 0 {
+		return nil, nil
+	}
+	var (
+		forked = forkGated(s)
+		stream = ctx.qualifier(pkgPath, "Stream")
+		views  = make(map[*sszStruct]*sszStruct)
+	)
+	ctx.addImport(pkgPath, "")
+	for i, field := range s.fields {
+		if !s.each[s.fieldNames[i]] {
+			continue
+		}
+		// The item types are resolved at any fork, they're decoded at the fork
+		// of this struct anyway.
+		if forked {
+			field = forkView(field, ssz.ForkPhase0, views)
+		}
+		elem, _, ok := itemsLayout(field)
+		if !ok {
+			continue
+		}
+		var (
+			name = s.fieldNames[i]
+			fn   = fmt.Sprintf("func(i int, v %s) error", goType(ctx, elem))
+		)
+		if forked {
+			fmt.Fprintf(&b, "func (obj *%s) Decode%sEachForFork(s *%s, fork %s, fn %s) error {\n", s.typeName(), name, stream, ctx.qualifier(pkgPath, "Fork"), fn)
+			fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+				for j := range view.fields {
+					if view.fieldNames[j] == name {
+						return genStructEach(ctx, view, j) + "return nil\n"
+					}
+				}
+				return fmt.Sprintf("return %s\n", ctx.qualifier(pkgPath, "ErrUnknownField"))
+			}))
+		} else {
+			ctx.reset()
+			fmt.Fprintf(&b, "func (obj *%s) Decode%sEach(s *%s, fn %s) error {\n", s.typeName(), name, stream, fn)
+			fmt.Fprint(&b, genStructEach(ctx, s, i))
+			fmt.Fprint(&b, "return nil\n")
+		}
+		fmt.Fprint(&b, "}\n\n")
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// genStructEach generates the decoding of the struct with the items of the
+// field visited by fn, in the same order as the decoding of the whole struct.
+func genStructEach(ctx *genContext, s *sszStruct, field int) string {
+	var b bytes.Buffer
+	ctx.topType = false
+
+	decode := func(i int) string {
+		if i == field {
+			return genItemsEach(ctx, "s", s.fields[i])
+		}
+		return s.fields[i].genDecoder(ctx, "s", "obj."+s.fieldNames[i])
+	}
+	fmt.Fprint(&b, s.genCacheReset("obj"))
+	for i, field := range s.fields {
+		if field.fixed() {
+			fmt.Fprint(&b, decode(i))
+		} else {
+			decodeOffset(ctx, "s", &b)
+		}
+	}
+	for i, field := range s.fields {
+		if field.fixed() {
+			continue
+		}
+		wrapList(ctx, "s", &b, func() {
+			fmt.Fprint(&b, decode(i))
+		})
+	}
+	return b.String()
+}
+
+// genItemsEach generates the decoding of the list or vector which passes the
+// items to fn one by one. The length is resolved and checked against the limit
+// before any item is read, the same as decoding the whole value.
+func genItemsEach(ctx *genContext, r string, typ sszType) string {
+	var (
+		b              bytes.Buffer
+		elem, items, _ = itemsLayout(typ)
+		cnt            = ctx.tmpVar("i")
+		num            = ctx.tmpVar("n")
+		err            = ctx.tmpVar("e")
+		val            = ctx.tmpVar("v")
+	)
+	switch {
+	case !items.List:
+		fmt.Fprintf(&b, "%s := %d\n", num, items.Max)
+	case items.Size != 0:
+		fmt.Fprintf(&b, "%s, %s := %s.ListLength(%d, %d)\n", num, err, r, items.Size, items.Max)
+	default:
+		fmt.Fprintf(&b, "%s, %s := %s.DecodeOffsets(%d)\n", num, err, r, items.Max)
+	}
+	if items.List {
+		fmt.Fprintf(&b, "if %s != nil {\n", err)
+		fmt.Fprintf(&b, "return %s\n", err)
+		fmt.Fprint(&b, "}\n")
+	}
+	// Decode offsets, they're already decoded if the length is unknown
+	if items.Size == 0 && !items.List {
+		fmt.Fprintf(&b, "for %s := 0; %s < %s; %s += 1 {\n", cnt, cnt, num, cnt)
+		fmt.Fprintf(&b, "if err := %s.DecodeOffset(); err != nil{\n", r)
+		fmt.Fprint(&b, "return err\n")
+		fmt.Fprint(&b, "}\n") // curly brace for if
+		fmt.Fprint(&b, "}\n") // curly brace for loop
+	}
+	// Decode elements into the same value and visit them
+	fmt.Fprintf(&b, "var %s %s\n", val, goType(ctx, elem))
+	fmt.Fprintf(&b, "for %s := 0; %s < %s; %s += 1 {\n", cnt, cnt, num, cnt)
+	if items.Size != 0 {
+		fmt.Fprint(&b, elem.genDecoder(ctx, r, val))
+	} else {
+		wrapList(ctx, r, &b, func() {
+			fmt.Fprint(&b, elem.genDecoder(ctx, r, val))
+		})
+	}
+	fmt.Fprintf(&b, "if err := fn(%s, %s); err != nil {\n", cnt, val)
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n") // curly brace for if
+	fmt.Fprint(&b, "}\n") // curly brace for loop
+	return b.String()
+}
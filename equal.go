@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+)
+
+func (b *sszBasic) genEqual(ctx *genContext, x string, y string) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "if %s != %s {\n", x, y)
+	fmt.Fprint(&buf, "return false\n")
+	fmt.Fprint(&buf, "}\n")
+	return buf.String()
+}
+
+func (v *sszVector) genEqual(ctx *genContext, x string, y string) string {
+	var b bytes.Buffer
+	if isValue(v) {
+		fmt.Fprintf(&b, "if %s != %s {\n", x, y)
+		fmt.Fprint(&b, "return false\n")
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	cnt := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, x)
+	fmt.Fprint(&b, v.elem.genEqual(ctx, fmt.Sprintf("%s[%s]", x, cnt), fmt.Sprintf("%s[%s]", y, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// genEqual compares the lists by content, nil lists are equal to empty ones.
+// The empty lists with fixed size are encoded as the zero value, so they are
+// equal to the zero value as well.
+func (l *sszList) genEqual(ctx *genContext, x string, y string) string {
+	var b bytes.Buffer
+	if l.tag.size != 0 && l.elem.fixed() {
+		typ := ctx.typeString(l.slice)
+		if l.named != nil {
+			typ = ctx.typeString(l.named)
+		}
+		var (
+			xid = ctx.tmpVar("l")
+			yid = ctx.tmpVar("l")
+		)
+		fmt.Fprintf(&b, "%s, %s := %s, %s\n", xid, yid, x, y)
+		fmt.Fprintf(&b, "if len(%s) == 0 {\n", xid)
+		fmt.Fprintf(&b, "%s = make(%s, %d)\n", xid, typ, l.tag.size)
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "if len(%s) == 0 {\n", yid)
+		fmt.Fprintf(&b, "%s = make(%s, %d)\n", yid, typ, l.tag.size)
+		fmt.Fprint(&b, "}\n")
+		x, y = xid, yid
+	}
+	if elem, ok := l.elem.(*sszBasic); ok && elem.named == nil && elem.basic.Kind() == types.Uint8 {
+		ctx.addImport("bytes", "")
+		fmt.Fprintf(&b, "if !bytes.Equal(%s, %s) {\n", x, y)
+		fmt.Fprint(&b, "return false\n")
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "if len(%s) != len(%s) {\n", x, y)
+	fmt.Fprint(&b, "return false\n")
+	fmt.Fprint(&b, "}\n")
+
+	cnt := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, x)
+	fmt.Fprint(&b, l.elem.genEqual(ctx, fmt.Sprintf("%s[%s]", x, cnt), fmt.Sprintf("%s[%s]", y, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (s *sszStruct) genEqual(ctx *genContext, x string, y string) string {
+	var b bytes.Buffer
+	if !ctx.topType {
+		fmt.Fprintf(&b, "if !%s.Equal(&%s) {\n", x, y)
+		fmt.Fprint(&b, "return false\n")
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	ctx.topType = false
+
+	for i, field := range s.fields {
+		fmt.Fprint(&b, field.genEqual(ctx, fmt.Sprintf("%s.%s", x, s.fieldNames[i]), fmt.Sprintf("%s.%s", y, s.fieldNames[i])))
+	}
+	return b.String()
+}
+
+// genEqual compares the pointers by the pointed values, nil pointers are equal
+// to the zero values.
+func (p *sszPointer) genEqual(ctx *genContext, x string, y string) string {
+	var b bytes.Buffer
+	if _, ok := p.elem.(*sszStruct); ok {
+		fmt.Fprintf(&b, "if !%s.Equal(%s) {\n", x, y)
+		fmt.Fprint(&b, "return false\n")
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	var (
+		xid = ctx.tmpVar("p")
+		yid = ctx.tmpVar("p")
+	)
+	fmt.Fprintf(&b, "%s, %s := %s, %s\n", xid, yid, x, y)
+	fmt.Fprintf(&b, "if %s == nil {\n", xid)
+	fmt.Fprintf(&b, "%s = new(%s)\n", xid, p.elem.typeName())
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "if %s == nil {\n", yid)
+	fmt.Fprintf(&b, "%s = new(%s)\n", yid, p.elem.typeName())
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, p.elem.genEqual(ctx, "*"+xid, "*"+yid))
+	return b.String()
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// forkDirective is the comment declaring a family of fork-versioned containers,
+// listing the forks in the order of activation along with their types:
+//
+//	//sszgen:fork BeaconBlockBody phase0=BeaconBlockBodyPhase0 altair=BeaconBlockBodyAltair
+//
+// The forks are listed alone for the container with the fork-gated fields,
+// which is the type at all of them:
+//
+//	//sszgen:fork BeaconState phase0 altair bellatrix capella
+const forkDirective = "//sszgen:fork "
+
+// forkFamily is a family of containers versioned by the forks. The generated
+// interface Any<name> exposes the fields shared by all the containers, and the
+// wrapper Versioned<name> holds a container along with its fork. The family of
+// a fork-gated container has no interface, the wrapper holds the container
+// itself.
+type forkFamily struct {
+	name  string
+	forks []ssz.Fork
+	types []*sszStruct
+	gated bool // whether the family is the fork-gated container at the forks
+}
+
+// parseForkDirectives collects the fork families declared in the files, with
+// the containers resolved from the built types.
+func parseForkDirectives(files []*ast.File, built []sszType) ([]*forkFamily, error) {
+	structs := make(map[string]*sszStruct)
+	for _, typ := range built {
+		if s, ok := typ.(*sszStruct); ok {
+			structs[s.typeName()] = s
+		}
+	}
+	var families []*forkFamily
+	for _, file := range files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if !strings.HasPrefix(comment.Text, forkDirective) {
+					continue
+				}
+				family, err := parseForkFamily(strings.TrimPrefix(comment.Text, forkDirective), structs)
+				if err != nil {
+					return nil, fmt.Errorf("invalid fork directive %q: %v", comment.Text, err)
+				}
+				families = append(families, family)
+			}
+		}
+	}
+	return families, nil
+}
+
+func parseForkFamily(input string, structs map[string]*sszStruct) (*forkFamily, error) {
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("no fork specified")
+	}
+	family := &forkFamily{name: fields[0], gated: !strings.Contains(fields[1], "=")}
+	for _, field := range fields[1:] {
+		forkName, name, ok := strings.Cut(field, "=")
+		if family.gated {
+			forkName, name, ok = field, family.name, !ok
+		}
+		if !ok || forkName == "" {
+			return nil, fmt.Errorf("invalid fork mapping %s", field)
+		}
+		fork, ok := ssz.ForkByName(forkName)
+		if !ok {
+			return nil, fmt.Errorf("unknown fork %s", forkName)
+		}
+		if n := len(family.forks); n > 0 && fork <= family.forks[n-1] {
+			return nil, fmt.Errorf("fork %s out of order", forkName)
+		}
+		s, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("no container named %s", name)
+		}
+		if forkGated(s) != family.gated {
+			if family.gated {
+				return nil, fmt.Errorf("container %s has no fork-gated fields", name)
+			}
+			return nil, fmt.Errorf("container %s has fork-gated fields", name)
+		}
+		family.forks = append(family.forks, fork)
+		family.types = append(family.types, s)
+	}
+	return family, nil
+}
+
+// sharedFields returns the names and types of the fields shared by all the
+// containers of the family, in the order of the first one.
+func (f *forkFamily) sharedFields() ([]string, []types.Type) {
+	var (
+		names []string
+		typs  []types.Type
+	)
+	for _, name := range f.types[0].fieldNames {
+		typ := fieldType(f.types[0], name)
+		shared := true
+		for _, s := range f.types[1:] {
+			other := fieldType(s, name)
+			shared = shared && other != nil && types.Identical(typ, other)
+		}
+		if shared {
+			names, typs = append(names, name), append(typs, typ)
+		}
+	}
+	return names, typs
+}
+
+// fieldType returns the Go type of the ssz field, or nil if there is none.
+func fieldType(s *sszStruct, name string) types.Type {
+	for _, n := range s.fieldNames {
+		if n != name {
+			continue
+		}
+		for i := 0; i < s.NumFields(); i++ {
+			if s.Field(i).Name() == name {
+				return s.Field(i).Type()
+			}
+		}
+	}
+	return nil
+}
+
+// generateForkFamily generates the interface, the accessors of the shared fields
+// and the versioned wrapper of the family. The accessors defined already for
+// the other families are skipped.
+func generateForkFamily(ctx *genContext, f *forkFamily, defined map[string]bool) []byte {
+	if f.gated {
+		return generateGatedFamily(ctx, f)
+	}
+	var (
+		b            bytes.Buffer
+		names, typs  = f.sharedFields()
+		iface        = "Any" + f.name
+		wrapper      = "Versioned" + f.name
+		fieldTypes   = make([]string, len(typs))
+		unknownFork  = ctx.qualifier(pkgPath, "ErrUnknownFork")
+		forkType     = ctx.qualifier(pkgPath, "Fork")
+		streamType   = ctx.qualifier(pkgPath, "Stream")
+		hasherType   = ctx.qualifier(pkgPath, "Hasher")
+		encoderIface = ctx.qualifier(pkgPath, "Encoder")
+		decoderIface = ctx.qualifier(pkgPath, "Decoder")
+	)
+	ctx.addImport(pkgPath, "")
+	for i, typ := range typs {
+		fieldTypes[i] = ctx.typeString(typ)
+	}
+	fmt.Fprintf(&b, "type %s interface {\n", iface)
+	fmt.Fprintf(&b, "%s\n%s\n", encoderIface, decoderIface)
+	fmt.Fprint(&b, "HashTreeRoot() ([32]byte, error)\n")
+	fmt.Fprintf(&b, "HashTreeRootWith(h *%s) error\n", hasherType)
+	for i, name := range names {
+		fmt.Fprintf(&b, "Get%s() %s\n", name, fieldTypes[i])
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	for _, s := range f.types {
+		for i, name := range names {
+			key := s.typeName() + ".Get" + name
+			if defined[key] {
+				continue
+			}
+			defined[key] = true
+			fmt.Fprintf(&b, "func (obj *%s) Get%s() %s {\n", s.typeName(), name, fieldTypes[i])
+			fmt.Fprintf(&b, "return obj.%s\n", name)
+			fmt.Fprint(&b, "}\n\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "type %s struct {\n", wrapper)
+	fmt.Fprintf(&b, "Fork %s\n", forkType)
+	fmt.Fprintf(&b, "%s\n", iface)
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) DecodeForFork(fork %s, s *%s) error {\n", wrapper, forkType, streamType)
+	fmt.Fprintf(&b, "var obj %s\n", iface)
+	fmt.Fprint(&b, "switch fork {\n")
+	for i, fork := range f.forks {
+		fmt.Fprintf(&b, "case %s:\n", forkIdent(ctx, fork))
+		fmt.Fprintf(&b, "obj = new(%s)\n", f.types[i].typeName())
+	}
+	fmt.Fprint(&b, "default:\n")
+	fmt.Fprintf(&b, "return %s\n", unknownFork)
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "if err := obj.UnmarshalSSZ(s); err != nil {\n")
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "v.Fork, v.%s = fork, obj\n", iface)
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n\n")
+	fmt.Fprint(&b, genDecodeForDigest(ctx, wrapper))
+	return b.Bytes()
+}
+
+// generateGatedFamily generates the versioned wrapper of the fork-gated
+// container, which is decoded at the forks of the family only.
+func generateGatedFamily(ctx *genContext, f *forkFamily) []byte {
+	var (
+		b       bytes.Buffer
+		name    = f.types[0].typeName()
+		wrapper = "Versioned" + f.name
+		fork    = ctx.qualifier(pkgPath, "Fork")
+		forks   = make([]string, len(f.forks))
+	)
+	ctx.addImport(pkgPath, "")
+	for i, f := range f.forks {
+		forks[i] = forkIdent(ctx, f)
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", wrapper)
+	fmt.Fprintf(&b, "Fork %s\n", fork)
+	fmt.Fprintf(&b, "*%s\n", name)
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) DecodeForFork(fork %s, s *%s) error {\n", wrapper, fork, ctx.qualifier(pkgPath, "Stream"))
+	fmt.Fprintf(&b, "obj := new(%s)\n", name)
+	fmt.Fprint(&b, "switch fork {\n")
+	fmt.Fprintf(&b, "case %s:\n", strings.Join(forks, ", "))
+	fmt.Fprint(&b, "if err := obj.UnmarshalSSZForFork(s, fork); err != nil {\n")
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "default:\n")
+	fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrUnknownFork"))
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "v.Fork, v.%s = fork, obj\n", name)
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n\n")
+	fmt.Fprint(&b, genDecodeForDigest(ctx, wrapper))
+	fmt.Fprint(&b, "\n")
+
+	// The container bound to its fork implements the fork independent methods
+	fmt.Fprintf(&b, "func (v *%s) Bound() *%s {\n", wrapper, ctx.qualifier(pkgPath, "ForkBound"))
+	fmt.Fprintf(&b, "return %s(v.%s, v.Fork)\n", ctx.qualifier(pkgPath, "AtFork"), name)
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// genDecodeForDigest generates the decoding of the versioned wrapper at the
+// fork of the digest.
+func genDecodeForDigest(ctx *genContext, wrapper string) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "func (v *%s) DecodeForDigest(digests %s, digest [4]byte, s *%s) error {\n", wrapper, ctx.qualifier(pkgPath, "ForkDigests"), ctx.qualifier(pkgPath, "Stream"))
+	fmt.Fprint(&b, "fork, ok := digests[digest]\n")
+	fmt.Fprint(&b, "if !ok {\n")
+	fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrUnknownFork"))
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "return v.DecodeForFork(fork, s)\n")
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// forkGated returns whether the encoding of the type depends on the fork, i.e.
+// it has the fields tagged with ssz-fork or refers to the types having them.
+func forkGated(typ sszType) bool {
+	return len(gatingForks(typ)) > 1
+}
+
+// gatingForks returns the forks in order at which the fields of the type or the
+// types referred are added or removed, along with the first fork.
+func gatingForks(typ sszType) []ssz.Fork {
+	var (
+		set   = map[ssz.Fork]bool{ssz.ForkPhase0: true}
+		visit func(typ sszType)
+		seen  = make(map[*sszStruct]bool)
+	)
+	visit = func(typ sszType) {
+		switch t := typ.(type) {
+		case *sszStruct:
+			if seen[t] {
+				return
+			}
+			seen[t] = true
+			for i, field := range t.fields {
+				if t.forks[i].gated() {
+					set[t.forks[i].from] = true
+					if t.forks[i].until != 0 {
+						set[t.forks[i].until] = true
+					}
+				}
+				visit(field)
+			}
+		case *sszVector:
+			visit(t.elem)
+		case *sszList:
+			visit(t.elem)
+		case *sszPointer:
+			visit(t.elem)
+		}
+	}
+	visit(typ)
+
+	var forks []ssz.Fork
+	for fork := range set {
+		forks = append(forks, fork)
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+	return forks
+}
+
+// forkView returns the type as it is at the fork, with the fields absent in the
+// fork dropped from the structs. The views of the structs are memoized in views
+// for the recursive references, the types not gated by the forks are returned
+// as they are.
+func forkView(typ sszType, fork ssz.Fork, views map[*sszStruct]*sszStruct) sszType {
+	if !forkGated(typ) {
+		return typ
+	}
+	switch t := typ.(type) {
+	case *sszStruct:
+		if v, ok := views[t]; ok {
+			return v
+		}
+		v := &sszStruct{Struct: t.Struct, named: t.named, atFork: true, cache: t.cache}
+		views[t] = v
+		for i, field := range t.fields {
+			if !t.forks[i].active(fork) {
+				continue
+			}
+			v.fields = append(v.fields, forkView(field, fork, views))
+			v.fieldNames = append(v.fieldNames, t.fieldNames[i])
+			v.jsonNames = append(v.jsonNames, t.jsonNames[i])
+			v.forks = append(v.forks, forkRange{})
+		}
+		return v
+	case *sszVector:
+		cpy := *t
+		cpy.elem = forkView(t.elem, fork, views)
+		return &cpy
+	case *sszList:
+		cpy := *t
+		cpy.elem = forkView(t.elem, fork, views)
+		return &cpy
+	case *sszPointer:
+		cpy := *t
+		cpy.elem = forkView(t.elem, fork, views)
+		return &cpy
+	}
+	return typ
+}
+
+// forkIdent returns the identifier of the fork constant in the ssz package.
+func forkIdent(ctx *genContext, fork ssz.Fork) string {
+	name := fork.String()
+	return ctx.qualifier(pkgPath, "Fork"+strings.ToUpper(name[:1])+name[1:])
+}
+
+// genForkSwitch generates the switch over the fork, with the code generated by
+// gen for the views of the struct at the forks. The forks sharing the same code
+// are merged into a single case.
+func genForkSwitch(ctx *genContext, s *sszStruct, gen func(view *sszStruct) string) string {
+	var (
+		b     bytes.Buffer
+		forks = gatingForks(s)
+		froms []ssz.Fork
+		codes []string
+	)
+	ctx.addImport(pkgPath, "")
+	for i := len(forks) - 1; i >= 0; i-- {
+		ctx.reset()
+		code := gen(forkView(s, forks[i], make(map[*sszStruct]*sszStruct)).(*sszStruct))
+		if n := len(codes); n > 0 && codes[n-1] == code {
+			froms[n-1] = forks[i]
+			continue
+		}
+		froms, codes = append(froms, forks[i]), append(codes, code)
+	}
+	fmt.Fprint(&b, "switch {\n")
+	for i, code := range codes {
+		if i == len(codes)-1 {
+			fmt.Fprint(&b, "default:\n")
+		} else {
+			fmt.Fprintf(&b, "case fork >= %s:\n", forkIdent(ctx, froms[i]))
+		}
+		fmt.Fprint(&b, code)
+	}
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// generateForkSSZSize generates the sizing of the fork-gated struct at the fork.
+func generateForkSSZSize(ctx *genContext, s *sszStruct) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "func (obj *%s) SizeSSZForFork(fork %s) int {\n", s.typeName(), ctx.qualifier(pkgPath, "Fork"))
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return view.genSize(ctx, "s", "obj") + "return s\n"
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// generateForkSizeBounds generates the static bounds of the encoded size of the
+// fork-gated struct at the fork.
+func generateForkSizeBounds(ctx *genContext, s *sszStruct) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "func (obj *%s) MinSizeSSZForFork(fork %s) uint64 {\n", s.typeName(), ctx.qualifier(pkgPath, "Fork"))
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return fmt.Sprintf("return %d\n", view.minSize())
+	}))
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (obj *%s) MaxSizeSSZForFork(fork %s) uint64 {\n", s.typeName(), ctx.qualifier(pkgPath, "Fork"))
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return fmt.Sprintf("return %d\n", view.maxSize())
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// generateForkEncoder generates the encoding of the fork-gated struct at the fork.
+func generateForkEncoder(ctx *genContext, s *sszStruct) []byte {
+	var (
+		b    bytes.Buffer
+		fork = ctx.qualifier(pkgPath, "Fork")
+	)
+	fmt.Fprintf(&b, "func (obj *%s) MarshalSSZForFork(fork %s) ([]byte, error) {\n", s.typeName(), fork)
+	fmt.Fprint(&b, "return obj.MarshalSSZToForFork(make([]byte, 0, obj.SizeSSZForFork(fork)), fork)\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (obj *%s) MarshalSSZToForFork(w []byte, fork %s) ([]byte, error) {\n", s.typeName(), fork)
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return view.genEncoder(ctx, "obj") + "return w, nil\n"
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// generateForkDecoder generates the decoding of the fork-gated struct at the
+// fork. The fields absent in the fork are left untouched.
+func generateForkDecoder(ctx *genContext, s *sszStruct) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "func (obj *%s) UnmarshalSSZForFork(s *%s, fork %s) error {\n", s.typeName(), ctx.qualifier(pkgPath, "Stream"), ctx.qualifier(pkgPath, "Fork"))
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return view.genDecoder(ctx, "s", "obj") + "return nil\n"
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// generateForkHasher generates the hashing of the fork-gated struct at the fork.
+func generateForkHasher(ctx *genContext, s *sszStruct) []byte {
+	var (
+		b    bytes.Buffer
+		fork = ctx.qualifier(pkgPath, "Fork")
+	)
+	fmt.Fprintf(&b, "func (obj *%s) HashTreeRootForFork(fork %s) ([32]byte, error) {\n", s.typeName(), fork)
+	fmt.Fprintf(&b, "h := %s()\n", ctx.qualifier(pkgPath, "GetHasher"))
+	fmt.Fprintf(&b, "defer %s(h)\n", ctx.qualifier(pkgPath, "PutHasher"))
+	fmt.Fprint(&b, "if err := obj.HashTreeRootWithForFork(h, fork); err != nil {\n")
+	fmt.Fprint(&b, "return [32]byte{}, err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "return h.HashRoot()\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (obj *%s) HashTreeRootWithForFork(h *%s, fork %s) error {\n", s.typeName(), ctx.qualifier(pkgPath, "Hasher"), fork)
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		return view.genHasher(ctx, "obj") + "return nil\n"
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
+
+// generateForkJSON generates the json conversion of the fork-gated struct at
+// the fork. The fields absent in the fork are left out of the json, and left
+// untouched by the decoding.
+func generateForkJSON(ctx *genContext, s *sszStruct) []byte {
+	var (
+		b    bytes.Buffer
+		fork = ctx.qualifier(pkgPath, "Fork")
+	)
+	ctx.addImport("encoding/json", "")
+	fmt.Fprintf(&b, "func (obj *%s) MarshalJSONForFork(fork %s) ([]byte, error) {\n", s.typeName(), fork)
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "var enc %s\n", jsonStruct(ctx, view))
+		fmt.Fprint(&b, view.genMarshalJSON(ctx, "enc", "obj"))
+		fmt.Fprint(&b, "return json.Marshal(&enc)\n")
+		return b.String()
+	}))
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (obj *%s) UnmarshalJSONForFork(input []byte, fork %s) error {\n", s.typeName(), fork)
+	fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "var dec %s\n", jsonStruct(ctx, view))
+		fmt.Fprint(&b, "if err := json.Unmarshal(input, &dec); err != nil {\n")
+		fmt.Fprint(&b, "return err\n")
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprint(&b, view.genUnmarshalJSON(ctx, "obj", "dec"))
+		fmt.Fprint(&b, "return nil\n")
+		return b.String()
+	}))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes()
+}
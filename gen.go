@@ -5,21 +5,26 @@ import (
 	"fmt"
 	"go/types"
 	"sort"
+	"strings"
 )
 
 const pkgPath = "github.com/rjl493456442/sszgen/ssz"
 
 type genContext struct {
-	topType bool
-	pkg     *types.Package
-	imports map[string]string
-	nvar    int
+	topType   bool
+	pkg       *types.Package
+	imports   map[string]string
+	nvar      int
+	nilPolicy nilPolicy // policy for the pointers without explicit one
+	consts    map[string]bool
 }
 
-func newGenContext(pkg *types.Package) *genContext {
+func newGenContext(pkg *types.Package, policy nilPolicy) *genContext {
 	return &genContext{
-		pkg:     pkg,
-		imports: make(map[string]string),
+		pkg:       pkg,
+		imports:   make(map[string]string),
+		nilPolicy: policy,
+		consts:    make(map[string]bool),
 	}
 }
 
@@ -30,6 +35,18 @@ func (ctx *genContext) qualifier(path string, obj string) string {
 	return fmt.Sprintf("%s.%s", pkgName(path), obj)
 }
 
+// typeString returns the Go type expression of typ, qualified with the package
+// names and importing the packages needed.
+func (ctx *genContext) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg.Path() == ctx.pkg.Path() {
+			return ""
+		}
+		ctx.addImport(pkg.Path(), "")
+		return pkg.Name()
+	})
+}
+
 func (ctx *genContext) addImport(path string, alias string) error {
 	if path == ctx.pkg.Path() {
 		return nil
@@ -75,6 +92,16 @@ func (ctx *genContext) header() []byte {
 	return b.Bytes()
 }
 
+// defineConst registers the name of the package level constant, rejecting the
+// ones defined already.
+func (ctx *genContext) defineConst(name string) error {
+	if ctx.consts[name] {
+		return fmt.Errorf("duplicated constant %s", name)
+	}
+	ctx.consts[name] = true
+	return nil
+}
+
 func (ctx *genContext) tmpVar(name string) string {
 	id := fmt.Sprintf("_%s%d", name, ctx.nvar)
 	ctx.nvar += 1
@@ -91,9 +118,13 @@ func generateSSZSize(ctx *genContext, typ sszType) ([]byte, error) {
 	ctx.reset()
 
 	// TODO non-struct types are not supported yet
-	if _, ok := typ.(*sszStruct); !ok {
+	s, ok := typ.(*sszStruct)
+	if !ok {
 		return nil, nil
 	}
+	if forkGated(s) {
+		return generateForkSSZSize(ctx, s), nil
+	}
 	fmt.Fprintf(&b, "func (obj *%s) SizeSSZ() int {\n", typ.typeName())
 	fmt.Fprint(&b, typ.genSize(ctx, "s", "obj"))
 	fmt.Fprint(&b, "return s\n")
@@ -101,18 +132,57 @@ func generateSSZSize(ctx *genContext, typ sszType) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// generateSizeBounds generates the static bounds of the encoded size, along with
+// the size constant if the type is fixed-size.
+func generateSizeBounds(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	if forkGated(s) {
+		return generateForkSizeBounds(ctx, s), nil
+	}
+	min, max := fmt.Sprint(typ.minSize()), fmt.Sprint(typ.maxSize())
+	if typ.fixed() {
+		fmt.Fprintf(&b, "const %sSSZSize = %d\n\n", typ.typeName(), typ.fixedSize())
+		min = fmt.Sprintf("%sSSZSize", typ.typeName())
+		max = min
+	}
+	fmt.Fprintf(&b, "func (obj *%s) MinSizeSSZ() uint64 {\n", typ.typeName())
+	fmt.Fprintf(&b, "return %s\n", min)
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (obj *%s) MaxSizeSSZ() uint64 {\n", typ.typeName())
+	fmt.Fprintf(&b, "return %s\n", max)
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
 func generateEncoder(ctx *genContext, typ sszType) ([]byte, error) {
 	var b bytes.Buffer
 	ctx.reset()
 
 	// TODO non-struct types are not supported yet
-	if _, ok := typ.(*sszStruct); !ok {
+	s, ok := typ.(*sszStruct)
+	if !ok {
 		return nil, nil
 	}
-	// Generate `MarshalSSZTo` binding
-	fmt.Fprintf(&b, "func (obj *%s) MarshalSSZTo(w []byte) error {\n", typ.typeName())
+	if forkGated(s) {
+		return generateForkEncoder(ctx, s), nil
+	}
+	// Generate `MarshalSSZ` binding
+	fmt.Fprintf(&b, "func (obj *%s) MarshalSSZ() ([]byte, error) {\n", typ.typeName())
+	fmt.Fprint(&b, "return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	// Generate `MarshalSSZTo` binding, which appends the encoding to w
+	fmt.Fprintf(&b, "func (obj *%s) MarshalSSZTo(w []byte) ([]byte, error) {\n", typ.typeName())
 	fmt.Fprint(&b, typ.genEncoder(ctx, "obj"))
-	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "return w, nil\n")
 	fmt.Fprint(&b, "}\n")
 	return b.Bytes(), nil
 }
@@ -122,9 +192,13 @@ func generateDecoder(ctx *genContext, typ sszType) ([]byte, error) {
 	ctx.reset()
 
 	// TODO non-struct types are not supported yet
-	if _, ok := typ.(*sszStruct); !ok {
+	s, ok := typ.(*sszStruct)
+	if !ok {
 		return nil, nil
 	}
+	if forkGated(s) {
+		return generateForkDecoder(ctx, s), nil
+	}
 	// Generate `UnmarshalSSZ` binding
 	ctx.addImport(pkgPath, "")
 	fmt.Fprintf(&b, "func (obj *%s) UnmarshalSSZ(s *%s) error {\n", typ.typeName(), ctx.qualifier(pkgPath, "Stream"))
@@ -134,13 +208,215 @@ func generateDecoder(ctx *genContext, typ sszType) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-func generate(ctx *genContext, typ sszType) ([]byte, error) {
+func generateHasher(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	if forkGated(s) {
+		return generateForkHasher(ctx, s), nil
+	}
+	// Generate `HashTreeRoot` binding
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "func (obj *%s) HashTreeRoot() ([32]byte, error) {\n", typ.typeName())
+	fmt.Fprintf(&b, "h := %s()\n", ctx.qualifier(pkgPath, "GetHasher"))
+	fmt.Fprintf(&b, "defer %s(h)\n", ctx.qualifier(pkgPath, "PutHasher"))
+	fmt.Fprint(&b, "if err := obj.HashTreeRootWith(h); err != nil {\n")
+	fmt.Fprint(&b, "return [32]byte{}, err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "return h.HashRoot()\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	// Generate `HashTreeRootWith` binding, which hashes the object into the
+	// given hasher
+	fmt.Fprintf(&b, "func (obj *%s) HashTreeRootWith(h *%s) error {\n", typ.typeName(), ctx.qualifier(pkgPath, "Hasher"))
+	fmt.Fprint(&b, typ.genHasher(ctx, "obj"))
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+// generateProver generates the merkle proof of the node at the generalized index
+// in the tree of the object.
+func generateProver(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	ctx.addImport(pkgPath, "")
+	if forkGated(s) {
+		fmt.Fprintf(&b, "func (obj *%s) ProveSSZForFork(gindex uint64, fork %s) ([][32]byte, error) {\n", s.typeName(), ctx.qualifier(pkgPath, "Fork"))
+		fmt.Fprintf(&b, "return %s(%s(obj, fork), gindex)\n", ctx.qualifier(pkgPath, "Prove"), ctx.qualifier(pkgPath, "AtFork"))
+		fmt.Fprint(&b, "}\n")
+		return b.Bytes(), nil
+	}
+	fmt.Fprintf(&b, "func (obj *%s) ProveSSZ(gindex uint64) ([][32]byte, error) {\n", s.typeName())
+	fmt.Fprintf(&b, "return %s(obj, gindex)\n", ctx.qualifier(pkgPath, "Prove"))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+// generateMarkDirty generates the invalidation of the cached trees of the fields
+// for the structs opted in the hash cache. The fields not cached are accepted
+// as they are always rehashed.
+func generateMarkDirty(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	s, ok := typ.(*sszStruct)
+	if !ok || s.cache == "" {
+		return nil, nil
+	}
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "func (obj *%s) MarkDirty(field string, index int) error {\n", s.typeName())
+	fmt.Fprint(&b, "switch field {\n")
+	var (
+		seen     = map[string]bool{"": true}
+		uncached []string
+	)
+	for i, field := range s.fields {
+		var names []string
+		for _, name := range []string{s.fieldNames[i], s.jsonNames[i]} {
+			if !seen[name] && name != "-" {
+				seen[name] = true
+				names = append(names, fmt.Sprintf("%q", name))
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		if !cacheable(field) {
+			uncached = append(uncached, names...)
+			continue
+		}
+		fmt.Fprintf(&b, "case %s:\n", strings.Join(names, ", "))
+		fmt.Fprintf(&b, "obj.%s.MarkDirty(%d, index)\n", s.cache, s.fieldIndex(s.fieldNames[i]))
+	}
+	if len(uncached) > 0 {
+		fmt.Fprintf(&b, "case %s:\n", strings.Join(uncached, ", "))
+	}
+	fmt.Fprint(&b, "default:\n")
+	fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrUnknownField"))
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+func generateCopy(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	if _, ok := typ.(*sszStruct); !ok {
+		return nil, nil
+	}
+	// Generate `Copy` binding
+	fmt.Fprintf(&b, "func (obj *%s) Copy() *%s {\n", typ.typeName(), typ.typeName())
+	fmt.Fprint(&b, "if obj == nil {\n")
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "cpy := new(%s)\n", typ.typeName())
+	fmt.Fprint(&b, typ.genCopy(ctx, "cpy", "obj"))
+	fmt.Fprint(&b, "return cpy\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+func generateEqual(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	if _, ok := typ.(*sszStruct); !ok {
+		return nil, nil
+	}
+	// Generate `Equal` binding, nil objects are equal to the zero values
+	fmt.Fprintf(&b, "func (obj *%s) Equal(other *%s) bool {\n", typ.typeName(), typ.typeName())
+	fmt.Fprint(&b, "if obj == other {\n")
+	fmt.Fprint(&b, "return true\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "if obj == nil {\n")
+	fmt.Fprintf(&b, "obj = new(%s)\n", typ.typeName())
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "if other == nil {\n")
+	fmt.Fprintf(&b, "other = new(%s)\n", typ.typeName())
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, typ.genEqual(ctx, "obj", "other"))
+	fmt.Fprint(&b, "return true\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+func generateRandom(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	if _, ok := typ.(*sszStruct); !ok {
+		return nil, nil
+	}
+	// Generate `GenerateRandomSSZ` binding, which fills the object with random
+	// values respecting the ssz tags
+	ctx.addImport("math/rand", "")
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "func (obj *%s) GenerateRandomSSZ(r *rand.Rand, opts *%s) {\n", typ.typeName(), ctx.qualifier(pkgPath, "RandomOptions"))
+	fmt.Fprint(&b, typ.genRandom(ctx, "obj"))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+func generateJSON(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	// The json representation of the fork-gated structs differs between
+	// the forks.
+	if forkGated(s) {
+		return generateForkJSON(ctx, s), nil
+	}
+	// Generate `MarshalJSON` binding, which converts the object to the
+	// intermediate struct with the json representation of the fields
+	ctx.addImport("encoding/json", "")
+	fmt.Fprintf(&b, "func (obj *%s) MarshalJSON() ([]byte, error) {\n", s.typeName())
+	fmt.Fprintf(&b, "var enc %s\n", jsonStruct(ctx, s))
+	fmt.Fprint(&b, s.genMarshalJSON(ctx, "enc", "obj"))
+	fmt.Fprint(&b, "return json.Marshal(&enc)\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	// Generate `UnmarshalJSON` binding, which checks the sizes of the fields
+	// against the ssz tags
+	ctx.reset()
+	fmt.Fprintf(&b, "func (obj *%s) UnmarshalJSON(input []byte) error {\n", s.typeName())
+	fmt.Fprintf(&b, "var dec %s\n", jsonStruct(ctx, s))
+	fmt.Fprint(&b, "if err := json.Unmarshal(input, &dec); err != nil {\n")
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, s.genUnmarshalJSON(ctx, "obj", "dec"))
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+// generator produces the code of a method set for the given type.
+type generator func(ctx *genContext, typ sszType) ([]byte, error)
+
+func generate(ctx *genContext, typ sszType, generators []generator) ([]byte, error) {
 	var codes [][]byte
-	for _, fn := range []func(ctx *genContext, typ sszType) ([]byte, error){
-		generateSSZSize,
-		generateEncoder,
-		generateDecoder,
-	} {
+	for _, fn := range generators {
 		code, err := fn(ctx, typ)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// gindexDirective is the comment naming the generalized indexes of the paths
+// into a container, which are emitted as the constants GIndex<Type><Name>:
+//
+//	//sszgen:gindex BeaconState FinalizedRoot=finalized_checkpoint.root
+const gindexDirective = "//sszgen:gindex "
+
+// gindexName is a named generalized index of a container.
+type gindexName struct {
+	typ    *sszStruct
+	name   string
+	path   string
+	gindex uint64
+}
+
+// parseGIndexDirectives collects the named generalized indexes declared in the
+// files, with the paths resolved against the built types.
+func parseGIndexDirectives(files []*ast.File, built []sszType) ([]*gindexName, error) {
+	structs := make(map[string]*sszStruct)
+	for _, typ := range built {
+		if s, ok := typ.(*sszStruct); ok {
+			structs[s.typeName()] = s
+		}
+	}
+	var names []*gindexName
+	for _, file := range files {
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if !strings.HasPrefix(comment.Text, gindexDirective) {
+					continue
+				}
+				parsed, err := parseGIndexNames(strings.TrimPrefix(comment.Text, gindexDirective), structs)
+				if err != nil {
+					return nil, fmt.Errorf("invalid gindex directive %q: %v", comment.Text, err)
+				}
+				names = append(names, parsed...)
+			}
+		}
+	}
+	return names, nil
+}
+
+func parseGIndexNames(input string, structs map[string]*sszStruct) ([]*gindexName, error) {
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("no path specified")
+	}
+	s, ok := structs[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("no container named %s", fields[0])
+	}
+	var names []*gindexName
+	for _, field := range fields[1:] {
+		name, path, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid path mapping %s", field)
+		}
+		elems, err := ssz.ParsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("path %s: %v", path, err)
+		}
+		gindex, err := forkInvariantGIndex(s, elems)
+		if err != nil {
+			return nil, fmt.Errorf("path %s: %v", path, err)
+		}
+		names = append(names, &gindexName{typ: s, name: name, path: path, gindex: gindex})
+	}
+	return names, nil
+}
+
+// forkInvariantGIndex resolves the path into the struct, which must be the same
+// in all the forks having it if the struct is gated by the forks.
+func forkInvariantGIndex(s *sszStruct, path []ssz.PathElem) (uint64, error) {
+	var gindex uint64
+	for _, fork := range gatingForks(s) {
+		view := forkView(s, fork, make(map[*sszStruct]*sszStruct))
+		g, err := gindexResolver(view)(path)
+		if err != nil {
+			continue // absent in the fork
+		}
+		if gindex != 0 && gindex != g {
+			return 0, fmt.Errorf("generalized index differs between the forks")
+		}
+		gindex = g
+	}
+	if gindex == 0 {
+		return 0, ssz.ErrInvalidPath
+	}
+	return gindex, nil
+}
+
+// gindexResolver returns the resolver of the paths into the type, in the same
+// way as the generated GIndexSSZ methods.
+func gindexResolver(typ sszType) ssz.Resolver {
+	switch t := typ.(type) {
+	case *sszBasic:
+		return ssz.BasicGIndex
+	case *sszVector:
+		if t.bits != 0 {
+			return ssz.PackedVectorGIndex(uint64(t.bits), 1)
+		}
+		if b, ok := t.elem.(*sszBasic); ok {
+			return ssz.PackedVectorGIndex(uint64(t.len), uint64(b.size*8))
+		}
+		return ssz.VectorGIndex(uint64(t.len), gindexResolver(t.elem))
+	case *sszList:
+		return listResolver(t)
+	case *sszPointer:
+		return gindexResolver(t.elem)
+	case *sszStruct:
+		// The fields are resolved on demand, as the struct might refer to
+		// itself through the lists.
+		return func(path []ssz.PathElem) (uint64, error) {
+			if len(path) == 0 {
+				return 1, nil
+			}
+			for i, field := range t.fields {
+				if path[0].Name == "" || (path[0].Name != t.fieldNames[i] && path[0].Name != t.jsonNames[i]) {
+					continue
+				}
+				child, err := gindexResolver(field)(path[1:])
+				if err != nil {
+					return 0, err
+				}
+				return ssz.ConcatGIndex(fieldGIndex(t, i), child)
+			}
+			return 0, ssz.ErrInvalidPath
+		}
+	}
+	return nil
+}
+
+func listResolver(l *sszList) ssz.Resolver {
+	switch {
+	case l.bits != 0:
+		return ssz.PackedVectorGIndex(uint64(l.bits), 1)
+	case l.bitlist:
+		return ssz.PackedListGIndex(uint64(l.tag.limit), 1)
+	case l.tag.size != 0:
+		if b, ok := l.elem.(*sszBasic); ok {
+			return ssz.PackedVectorGIndex(uint64(l.tag.size), uint64(b.size*8))
+		}
+		return ssz.VectorGIndex(uint64(l.tag.size), gindexResolver(l.elem))
+	default:
+		if b, ok := l.elem.(*sszBasic); ok {
+			return ssz.PackedListGIndex(uint64(l.tag.limit), uint64(b.size*8))
+		}
+		return ssz.ListGIndex(uint64(l.tag.limit), gindexResolver(l.elem))
+	}
+}
+
+// fieldGIndex returns the generalized index of the field in the struct.
+func fieldGIndex(s *sszStruct, i int) uint64 {
+	var depth uint
+	for 1<<depth < len(s.fields) {
+		depth++
+	}
+	return 1<<depth | uint64(i)
+}
+
+// gindexExpr returns the expression of the resolver of the paths into the type,
+// mirroring gindexResolver.
+func gindexExpr(ctx *genContext, typ sszType) string {
+	switch t := typ.(type) {
+	case *sszBasic:
+		return ctx.qualifier(pkgPath, "BasicGIndex")
+	case *sszVector:
+		if t.bits != 0 {
+			return fmt.Sprintf("%s(%d, 1)", ctx.qualifier(pkgPath, "PackedVectorGIndex"), t.bits)
+		}
+		if b, ok := t.elem.(*sszBasic); ok {
+			return fmt.Sprintf("%s(%d, %d)", ctx.qualifier(pkgPath, "PackedVectorGIndex"), t.len, b.size*8)
+		}
+		return fmt.Sprintf("%s(%d, %s)", ctx.qualifier(pkgPath, "VectorGIndex"), t.len, gindexExpr(ctx, t.elem))
+	case *sszList:
+		return listExpr(ctx, t)
+	case *sszPointer:
+		return gindexExpr(ctx, t.elem)
+	case *sszStruct:
+		if t.atFork {
+			return fmt.Sprintf("func(path []%s) (uint64, error) { return (*%s)(nil).GIndexSSZForFork(path, fork) }", ctx.qualifier(pkgPath, "PathElem"), t.typeName())
+		}
+		return fmt.Sprintf("(*%s)(nil).GIndexSSZ", t.typeName())
+	}
+	return ""
+}
+
+func listExpr(ctx *genContext, l *sszList) string {
+	var (
+		packedVector = ctx.qualifier(pkgPath, "PackedVectorGIndex")
+		packedList   = ctx.qualifier(pkgPath, "PackedListGIndex")
+	)
+	switch {
+	case l.bits != 0:
+		return fmt.Sprintf("%s(%d, 1)", packedVector, l.bits)
+	case l.bitlist:
+		return fmt.Sprintf("%s(%d, 1)", packedList, l.tag.limit)
+	case l.tag.size != 0:
+		if b, ok := l.elem.(*sszBasic); ok {
+			return fmt.Sprintf("%s(%d, %d)", packedVector, l.tag.size, b.size*8)
+		}
+		return fmt.Sprintf("%s(%d, %s)", ctx.qualifier(pkgPath, "VectorGIndex"), l.tag.size, gindexExpr(ctx, l.elem))
+	default:
+		if b, ok := l.elem.(*sszBasic); ok {
+			return fmt.Sprintf("%s(%d, %d)", packedList, l.tag.limit, b.size*8)
+		}
+		return fmt.Sprintf("%s(%d, %s)", ctx.qualifier(pkgPath, "ListGIndex"), l.tag.limit, gindexExpr(ctx, l.elem))
+	}
+}
+
+// genGIndexSwitch generates the resolution of the paths into the struct, by the
+// field names in either Go or json.
+func genGIndexSwitch(ctx *genContext, s *sszStruct) string {
+	var b bytes.Buffer
+	fmt.Fprint(&b, "if len(path) == 0 {\n")
+	fmt.Fprint(&b, "return 1, nil\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "var field %s\n", ctx.qualifier(pkgPath, "Resolver"))
+	fmt.Fprint(&b, "var gindex uint64\n")
+	fmt.Fprint(&b, "switch path[0].Name {\n")
+	seen := map[string]bool{"": true}
+	for i, field := range s.fields {
+		var names []string
+		for _, name := range []string{s.fieldNames[i], s.jsonNames[i]} {
+			if !seen[name] && name != "-" {
+				seen[name] = true
+				names = append(names, fmt.Sprintf("%q", name))
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "case %s:\n", strings.Join(names, ", "))
+		fmt.Fprintf(&b, "gindex, field = %d, %s\n", fieldGIndex(s, i), gindexExpr(ctx, field))
+	}
+	fmt.Fprint(&b, "default:\n")
+	fmt.Fprintf(&b, "return 0, %s\n", ctx.qualifier(pkgPath, "ErrInvalidPath"))
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "child, err := field(path[1:])\n")
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return 0, err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprintf(&b, "return %s(gindex, child)\n", ctx.qualifier(pkgPath, "ConcatGIndex"))
+	return b.String()
+}
+
+// gindexConstants returns the names and the generalized indexes of the fields
+// of the struct, e.g. GIndexBeaconStateFinalizedCheckpoint. The fields with the
+// index differing between the forks are skipped. The paths into the nested
+// structs are not covered, as the names concatenated might be ambiguous, e.g.
+// Attestation.Data.Slot and AttestationData.Slot, which are named explicitly by
+// the gindex directive instead.
+func gindexConstants(s *sszStruct) ([]string, []uint64) {
+	var (
+		names   []string
+		values  = make(map[string]uint64)
+		varying = make(map[string]bool)
+	)
+	for _, fork := range gatingForks(s) {
+		view := forkView(s, fork, make(map[*sszStruct]*sszStruct)).(*sszStruct)
+		for i := range view.fields {
+			name, gindex := "GIndex"+s.typeName()+view.fieldNames[i], fieldGIndex(view, i)
+			if v, ok := values[name]; !ok {
+				names, values[name] = append(names, name), gindex
+			} else if v != gindex {
+				varying[name] = true
+			}
+		}
+	}
+	var (
+		consts   []string
+		gindexes []uint64
+	)
+	for _, name := range names {
+		if !varying[name] {
+			consts, gindexes = append(consts, name), append(gindexes, values[name])
+		}
+	}
+	return consts, gindexes
+}
+
+// generateGIndex generates the generalized index constants of the fields and
+// the resolution of the paths into the struct.
+func generateGIndex(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	ctx.addImport(pkgPath, "")
+	names, gindexes := gindexConstants(s)
+	if len(names) > 0 {
+		fmt.Fprint(&b, "const (\n")
+		for i, name := range names {
+			if err := ctx.defineConst(name); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "%s = %d\n", name, gindexes[i])
+		}
+		fmt.Fprint(&b, ")\n\n")
+	}
+	pathType := ctx.qualifier(pkgPath, "PathElem")
+	if forkGated(s) {
+		fmt.Fprintf(&b, "func (obj *%s) GIndexSSZForFork(path []%s, fork %s) (uint64, error) {\n", s.typeName(), pathType, ctx.qualifier(pkgPath, "Fork"))
+		fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+			return genGIndexSwitch(ctx, view)
+		}))
+		fmt.Fprint(&b, "}\n")
+		return b.Bytes(), nil
+	}
+	fmt.Fprintf(&b, "func (obj *%s) GIndexSSZ(path []%s) (uint64, error) {\n", s.typeName(), pathType)
+	fmt.Fprint(&b, genGIndexSwitch(ctx, s))
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+// generateGIndexNames generates the constants of the named generalized indexes.
+func generateGIndexNames(ctx *genContext, names []*gindexName) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprint(&b, "const (\n")
+	for _, n := range names {
+		name := "GIndex" + n.typ.typeName() + n.name
+		if err := ctx.defineConst(name); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s = %d // %s\n", name, n.gindex, n.path)
+	}
+	fmt.Fprint(&b, ")\n")
+	return b.Bytes(), nil
+}
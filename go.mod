@@ -2,8 +2,12 @@ module github.com/rjl493456442/sszgen
 
 go 1.20
 
+require (
+	github.com/golang/snappy v0.0.4
+	golang.org/x/tools v0.7.0
+)
+
 require (
 	golang.org/x/mod v0.9.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
-	golang.org/x/tools v0.7.0 // indirect
 )
@@ -1 +1,312 @@
 package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// hasherSuffix returns the suffix of the hasher methods for the basic type.
+func hasherSuffix(b *sszBasic) string {
+	if b.basic.Kind() == types.Bool {
+		return "Bool"
+	}
+	return fmt.Sprintf("Uint%d", b.size*8)
+}
+
+// hasherValue returns the expression of the basic value accepted by the hasher.
+func hasherValue(b *sszBasic, obj string) string {
+	if b.named != nil {
+		return fmt.Sprintf("%s(%s)", b.typeName(), obj) // explicit type conversion
+	}
+	return obj
+}
+
+func (b *sszBasic) genHasher(ctx *genContext, obj string) string {
+	return fmt.Sprintf("h.Put%s(%s)\n", hasherSuffix(b), hasherValue(b, obj))
+}
+
+func (v *sszVector) genHasher(ctx *genContext, obj string) string {
+	if isBytes(v.elem) {
+		return fmt.Sprintf("h.PutBytes(%s[:])\n", obj)
+	}
+	var (
+		b    bytes.Buffer
+		indx = ctx.tmpVar("i")
+	)
+	fmt.Fprintf(&b, "%s := h.Index()\n", indx)
+	fmt.Fprint(&b, genItemsHasher(ctx, v.elem, obj))
+	fmt.Fprintf(&b, "h.Merkleize(%s)\n", indx)
+	return b.String()
+}
+
+// genItemsHasher generates the hashing of the items of vector or list, which
+// are packed if they are basic values, or hashed as the sub-trees otherwise.
+func genItemsHasher(ctx *genContext, elem sszType, obj string) string {
+	var b bytes.Buffer
+	if basic, ok := elem.(*sszBasic); ok {
+		vid := ctx.tmpVar("v")
+		fmt.Fprintf(&b, "for _, %s := range %s {\n", vid, obj)
+		fmt.Fprintf(&b, "h.Append%s(%s)\n", hasherSuffix(basic), hasherValue(basic, vid))
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprint(&b, "h.FillUpTo32()\n")
+		return b.String()
+	}
+	// The composite items are hashed in ranges, which are spread across the
+	// goroutines if there are many of them.
+	var (
+		err   = ctx.tmpVar("e")
+		from  = ctx.tmpVar("from")
+		to    = ctx.tmpVar("to")
+		items = ctx.tmpVar("s")
+	)
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "if %s := h.HashItems(len(%s), func(h *%s, %s, %s int) error {\n", err, obj, ctx.qualifier(pkgPath, "Hasher"), from, to)
+	fmt.Fprintf(&b, "%s := %s[%s:%s]\n", items, obj, from, to)
+	fmt.Fprint(&b, genItemsLoop(ctx, elem, items))
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprintf(&b, "}); %s != nil {\n", err)
+	fmt.Fprintf(&b, "return %s\n", err)
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// genItemsLoop generates the hashing of the composite items one by one.
+func genItemsLoop(ctx *genContext, elem sszType, obj string) string {
+	var (
+		b   bytes.Buffer
+		cnt = ctx.tmpVar("i")
+	)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, obj)
+	fmt.Fprint(&b, elem.genHasher(ctx, fmt.Sprintf("%s[%s]", obj, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (l *sszList) genHasher(ctx *genContext, obj string) string {
+	var b bytes.Buffer
+	ctx.addImport(pkgPath, "")
+
+	// The lists with fixed size are hashed as vectors, and the empty ones are
+	// regarded as the zero value if the zero value has fixed encoding, in the
+	// same way as the encoding.
+	if l.tag.size != 0 {
+		if isBytes(l.elem) {
+			fmt.Fprintf(&b, "if len(%s) != 0 && len(%s) != %d {\n", obj, obj, l.tag.size)
+			fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrSizeMismatch"))
+			fmt.Fprint(&b, "}\n")
+			fmt.Fprintf(&b, "h.PutBytesN(%s, %d)\n", obj, l.tag.size)
+			return b.String()
+		}
+		var (
+			lid  = l.genSizeCheck(ctx, &b, obj)
+			indx = ctx.tmpVar("i")
+		)
+		fmt.Fprintf(&b, "%s := h.Index()\n", indx)
+		fmt.Fprint(&b, genItemsHasher(ctx, l.elem, lid))
+		fmt.Fprintf(&b, "h.Merkleize(%s)\n", indx)
+		return b.String()
+	}
+	if l.bitlist {
+		err := ctx.tmpVar("e")
+		fmt.Fprintf(&b, "if %s := %s(%s, %d); %s != nil {\n", err, ctx.qualifier(pkgPath, "ValidateBitlist"), obj, l.tag.limit, err)
+		fmt.Fprintf(&b, "return %s\n", err)
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "h.PutBitlist(%s, %d)\n", obj, l.tag.limit)
+		return b.String()
+	}
+	if l.tag.limit != 0 {
+		fmt.Fprintf(&b, "if len(%s) > %d {\n", obj, l.tag.limit)
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrListTooBig"))
+		fmt.Fprint(&b, "}\n")
+	}
+	// The limit of the tree is counted in chunks, which is the item limit for
+	// the composite items, or the chunks to pack the basic items.
+	limit := l.tag.limit
+	if basic, ok := l.elem.(*sszBasic); ok {
+		limit = (limit*int64(basic.size) + 31) / 32
+	}
+	indx := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "%s := h.Index()\n", indx)
+	if isBytes(l.elem) {
+		fmt.Fprintf(&b, "h.AppendBytes32(%s)\n", obj)
+	} else {
+		fmt.Fprint(&b, genItemsHasher(ctx, l.elem, obj))
+	}
+	fmt.Fprintf(&b, "h.MerkleizeWithMixin(%s, uint64(len(%s)), %d)\n", indx, obj, limit)
+	return b.String()
+}
+
+// genSizeCheck generates the check of the list with fixed size, returning the
+// variable holding the list. The empty list is replaced by the zero value if
+// the zero value has fixed encoding.
+func (l *sszList) genSizeCheck(ctx *genContext, b *bytes.Buffer, obj string) string {
+	var (
+		lid = ctx.tmpVar("l")
+		typ = ctx.typeString(l.slice)
+	)
+	if l.named != nil {
+		typ = ctx.typeString(l.named)
+	}
+	fmt.Fprintf(b, "%s := %s\n", lid, obj)
+	if l.elem.fixed() {
+		fmt.Fprintf(b, "if len(%s) == 0 {\n", lid)
+		fmt.Fprintf(b, "%s = make(%s, %d)\n", lid, typ, l.tag.size)
+		fmt.Fprintf(b, "} else if len(%s) != %d {\n", lid, l.tag.size)
+	} else {
+		fmt.Fprintf(b, "if len(%s) != %d {\n", lid, l.tag.size)
+	}
+	fmt.Fprintf(b, "return %s\n", ctx.qualifier(pkgPath, "ErrSizeMismatch"))
+	fmt.Fprint(b, "}\n")
+	return lid
+}
+
+func (s *sszStruct) genHasher(ctx *genContext, obj string) string {
+	var b bytes.Buffer
+	if !ctx.topType {
+		err := ctx.tmpVar("e")
+		fmt.Fprintf(&b, "if %s := %s.%s; %s != nil {\n", err, obj, s.call("HashTreeRootWith", "h"), err)
+		fmt.Fprintf(&b, "return %s\n", err)
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	ctx.topType = false
+
+	indx := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "%s := h.Index()\n", indx)
+	for i, field := range s.fields {
+		if s.cache != "" && cacheable(field) {
+			fmt.Fprint(&b, genCachedHasher(ctx, field, fmt.Sprintf("%s.%s", obj, s.cache), s.fieldIndex(s.fieldNames[i]), fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
+			continue
+		}
+		fmt.Fprint(&b, field.genHasher(ctx, fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
+	}
+	fmt.Fprintf(&b, "h.Merkleize(%s)\n", indx)
+	return b.String()
+}
+
+// genHasher hashes the nil pointer as the zero value, or rejects it, following
+// the same nil policy as the encoding.
+func (p *sszPointer) genHasher(ctx *genContext, obj string) string {
+	var b bytes.Buffer
+	if p.policy(ctx) == nilReject {
+		ctx.addImport(pkgPath, "")
+		fmt.Fprintf(&b, "if %s == nil {\n", obj)
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrNilPointer"))
+		fmt.Fprint(&b, "}\n")
+	} else {
+		pid := ctx.tmpVar("p")
+		fmt.Fprintf(&b, "%s := %s\n", pid, obj)
+		fmt.Fprintf(&b, "if %s == nil {\n", pid)
+		fmt.Fprintf(&b, "%s = new(%s)\n", pid, p.elem.typeName())
+		fmt.Fprint(&b, "}\n")
+		obj = pid
+	}
+	if _, ok := p.elem.(*sszStruct); !ok {
+		obj = "(*" + obj + ")"
+	}
+	fmt.Fprint(&b, p.elem.genHasher(ctx, obj))
+	return b.String()
+}
+
+// cacheMinChunks is the minimum number of leaves of the field trees kept in the
+// hash cache, the smaller fields are cheap enough to rehash.
+const cacheMinChunks = 64
+
+// cacheLayout returns the number of the items packed into a chunk, and the
+// number of leaves in the tree of the list or vector.
+func cacheLayout(typ sszType) (int, int64) {
+	var (
+		elem  sszType
+		count int64
+	)
+	switch t := typ.(type) {
+	case *sszVector:
+		elem, count = t.elem, t.len
+	case *sszList:
+		elem, count = t.elem, t.tag.size
+		if count == 0 {
+			count = t.tag.limit
+		}
+	default:
+		return 0, 0
+	}
+	if basic, ok := elem.(*sszBasic); ok {
+		perChunk := ssz.BytesPerChunk / basic.size
+		return perChunk, (count + int64(perChunk) - 1) / int64(perChunk)
+	}
+	return 1, count
+}
+
+// cacheable returns whether the field of the container is hashed through the
+// hash cache, which are the large lists and vectors except the byte vectors and
+// the bitlists.
+func cacheable(typ sszType) bool {
+	switch t := typ.(type) {
+	case *sszVector:
+		if isBytes(t.elem) {
+			return false
+		}
+	case *sszList:
+		if t.bitlist || (t.tag.size == 0 && t.tag.limit == 0) || (t.tag.size != 0 && isBytes(t.elem)) {
+			return false
+		}
+	default:
+		return false
+	}
+	_, leaves := cacheLayout(typ)
+	return leaves >= cacheMinChunks
+}
+
+// genCachedHasher generates the hashing of the list or vector field through the
+// hash cache, which calls back for hashing the chunks modified only.
+func genCachedHasher(ctx *genContext, typ sszType, cache string, field int, obj string) string {
+	var (
+		b           bytes.Buffer
+		perChunk, _ = cacheLayout(typ)
+		elem        sszType
+		method      string
+		limit       string
+		data        = "nil"
+	)
+	ctx.addImport(pkgPath, "")
+	switch t := typ.(type) {
+	case *sszVector:
+		elem, method = t.elem, "HashVector"
+	case *sszList:
+		elem, data = t.elem, fmt.Sprintf("%s(%s)", ctx.qualifier(pkgPath, "SliceData"), obj)
+		if t.tag.size != 0 {
+			obj, method = t.genSizeCheck(ctx, &b, obj), "HashVector"
+			break
+		}
+		fmt.Fprintf(&b, "if len(%s) > %d {\n", obj, t.tag.limit)
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrListTooBig"))
+		fmt.Fprint(&b, "}\n")
+
+		_, leaves := cacheLayout(typ)
+		method, limit = "HashList", fmt.Sprintf(", %d", leaves)
+	}
+	var (
+		err   = ctx.tmpVar("e")
+		from  = ctx.tmpVar("from")
+		to    = ctx.tmpVar("to")
+		items = ctx.tmpVar("s")
+	)
+	fmt.Fprintf(&b, "if %s := %s.%s(h, %d, %s, len(%s), %d%s, func(h *%s, %s, %s int) error {\n", err, cache, method, field, data, obj, perChunk, limit, ctx.qualifier(pkgPath, "Hasher"), from, to)
+	fmt.Fprintf(&b, "%s := %s[%s:%s]\n", items, obj, from, to)
+	switch {
+	case isBytes(elem):
+		fmt.Fprintf(&b, "h.AppendBytes32(%s)\n", items)
+	case perChunk > 1:
+		fmt.Fprint(&b, genItemsHasher(ctx, elem, items))
+	default:
+		fmt.Fprint(&b, genItemsLoop(ctx, elem, items))
+	}
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprintf(&b, "}); %s != nil {\n", err)
+	fmt.Fprintf(&b, "return %s\n", err)
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
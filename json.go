@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsonField returns the name of the field in the intermediate json struct,
+// which must be exported for encoding/json.
+func jsonField(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[n:]
+}
+
+// jsonStruct returns the intermediate struct definition used for converting
+// the struct from and to json.
+func jsonStruct(ctx *genContext, s *sszStruct) string {
+	var b bytes.Buffer
+	fmt.Fprint(&b, "struct {\n")
+	for i, field := range s.fields {
+		if s.jsonNames[i] == "-" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s `json:\"%s\"`\n", jsonField(s.fieldNames[i]), field.jsonType(ctx), s.jsonNames[i])
+	}
+	fmt.Fprint(&b, "}")
+	return b.String()
+}
+
+// jsonBytesType returns the json type of the byte sequence.
+func jsonBytesType(ctx *genContext, uint256 bool) string {
+	ctx.addImport(pkgPath, "")
+	if uint256 {
+		return ctx.qualifier(pkgPath, "JSONUint256")
+	}
+	return ctx.qualifier(pkgPath, "JSONBytes")
+}
+
+func (b *sszBasic) jsonType(ctx *genContext) string {
+	if b.basic.Kind() == types.Bool {
+		return "bool"
+	}
+	ctx.addImport(pkgPath, "")
+	return ctx.qualifier(pkgPath, "JSONUint")
+}
+
+func (b *sszBasic) genMarshalJSON(ctx *genContext, dst string, src string) string {
+	return fmt.Sprintf("%s = %s(%s)\n", dst, b.jsonType(ctx), src)
+}
+
+func (b *sszBasic) genUnmarshalJSON(ctx *genContext, dst string, src string) string {
+	var buf bytes.Buffer
+	if b.basic.Kind() != types.Bool && b.size < 8 {
+		fmt.Fprintf(&buf, "if %s > %d {\n", src, uint64(1)<<(b.size*8)-1)
+		fmt.Fprintf(&buf, "return %s\n", ctx.qualifier(pkgPath, "ErrUintOverflow"))
+		fmt.Fprint(&buf, "}\n")
+	}
+	typ := b.typeName()
+	if b.named != nil {
+		typ = b.named.Obj().Name()
+	}
+	fmt.Fprintf(&buf, "%s = %s(%s)\n", dst, typ, src)
+	return buf.String()
+}
+
+func (v *sszVector) jsonType(ctx *genContext) string {
+	if isBytes(v.elem) {
+		return jsonBytesType(ctx, v.uint256)
+	}
+	return "[]" + v.elem.jsonType(ctx)
+}
+
+func (v *sszVector) genMarshalJSON(ctx *genContext, dst string, src string) string {
+	if isBytes(v.elem) {
+		return fmt.Sprintf("%s = %s[:]\n", dst, src)
+	}
+	var (
+		b   bytes.Buffer
+		cnt = ctx.tmpVar("i")
+	)
+	fmt.Fprintf(&b, "%s = make(%s, len(%s))\n", dst, v.jsonType(ctx), src)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+	fmt.Fprint(&b, v.elem.genMarshalJSON(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (v *sszVector) genUnmarshalJSON(ctx *genContext, dst string, src string) string {
+	var b bytes.Buffer
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "if len(%s) != %d {\n", src, v.len)
+	fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrSizeMismatch"))
+	fmt.Fprint(&b, "}\n")
+	if isBytes(v.elem) {
+		if v.bits != 0 {
+			fmt.Fprint(&b, genBitvectorCheck(ctx, src, v.bits, ""))
+		}
+		fmt.Fprintf(&b, "copy(%s[:], %s)\n", dst, src)
+		return b.String()
+	}
+	cnt := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+	fmt.Fprint(&b, v.elem.genUnmarshalJSON(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (l *sszList) jsonType(ctx *genContext) string {
+	if isBytes(l.elem) {
+		return jsonBytesType(ctx, l.uint256)
+	}
+	return "[]" + l.elem.jsonType(ctx)
+}
+
+func (l *sszList) genMarshalJSON(ctx *genContext, dst string, src string) string {
+	if isBytes(l.elem) {
+		return fmt.Sprintf("%s = %s(%s)\n", dst, l.jsonType(ctx), src)
+	}
+	var (
+		b   bytes.Buffer
+		cnt = ctx.tmpVar("i")
+	)
+	// The list is always allocated, so that the empty ones are encoded as []
+	// instead of null.
+	fmt.Fprintf(&b, "%s = make(%s, len(%s))\n", dst, l.jsonType(ctx), src)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+	fmt.Fprint(&b, l.elem.genMarshalJSON(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (l *sszList) genUnmarshalJSON(ctx *genContext, dst string, src string) string {
+	var b bytes.Buffer
+	ctx.addImport(pkgPath, "")
+
+	switch {
+	case l.bitlist:
+		err := ctx.tmpVar("e")
+		fmt.Fprintf(&b, "if %s := %s(%s, %d); %s != nil {\n", err, ctx.qualifier(pkgPath, "ValidateBitlist"), src, l.tag.limit, err)
+		fmt.Fprintf(&b, "return %s\n", err)
+		fmt.Fprint(&b, "}\n")
+	case l.tag.size != 0:
+		fmt.Fprintf(&b, "if len(%s) != %d {\n", src, l.tag.size)
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrSizeMismatch"))
+		fmt.Fprint(&b, "}\n")
+		if l.bits != 0 {
+			fmt.Fprint(&b, genBitvectorCheck(ctx, src, l.bits, ""))
+		}
+	case l.tag.limit != 0:
+		fmt.Fprintf(&b, "if len(%s) > %d {\n", src, l.tag.limit)
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrListTooBig"))
+		fmt.Fprint(&b, "}\n")
+	}
+	typ := ctx.typeString(l.slice)
+	if l.named != nil {
+		typ = ctx.typeString(l.named)
+	}
+	if isBytes(l.elem) {
+		fmt.Fprintf(&b, "%s = %s(%s)\n", dst, typ, src)
+		return b.String()
+	}
+	cnt := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "%s = make(%s, len(%s))\n", dst, typ, src)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, src)
+	fmt.Fprint(&b, l.elem.genUnmarshalJSON(ctx, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", src, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// jsonType returns the json type of the struct, which is the raw json for the
+// fork-gated structs converted at the fork by their own methods.
+func (s *sszStruct) jsonType(ctx *genContext) string {
+	if s.atFork {
+		ctx.addImport("encoding/json", "")
+		return "json.RawMessage"
+	}
+	return "*" + s.typeName()
+}
+
+func (s *sszStruct) genMarshalJSON(ctx *genContext, dst string, src string) string {
+	if !ctx.topType && s.atFork {
+		var (
+			b   bytes.Buffer
+			enc = ctx.tmpVar("j")
+			err = ctx.tmpVar("e")
+		)
+		fmt.Fprintf(&b, "%s, %s := %s.%s\n", enc, err, src, s.call("MarshalJSON"))
+		fmt.Fprintf(&b, "if %s != nil {\n", err)
+		fmt.Fprintf(&b, "return nil, %s\n", err)
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "%s = %s\n", dst, enc)
+		return b.String()
+	}
+	if !ctx.topType {
+		return fmt.Sprintf("%s = &%s\n", dst, src)
+	}
+	ctx.topType = false
+
+	var b bytes.Buffer
+	for i, field := range s.fields {
+		if s.jsonNames[i] == "-" {
+			continue
+		}
+		fmt.Fprint(&b, field.genMarshalJSON(ctx, fmt.Sprintf("%s.%s", dst, jsonField(s.fieldNames[i])), fmt.Sprintf("%s.%s", src, s.fieldNames[i])))
+	}
+	return b.String()
+}
+
+func (s *sszStruct) genUnmarshalJSON(ctx *genContext, dst string, src string) string {
+	var b bytes.Buffer
+	if !ctx.topType && s.atFork {
+		fmt.Fprintf(&b, "if %s != nil {\n", src)
+		fmt.Fprintf(&b, "if err := %s.%s; err != nil {\n", dst, s.call("UnmarshalJSON", src))
+		fmt.Fprint(&b, "return err\n")
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	if !ctx.topType {
+		fmt.Fprintf(&b, "if %s != nil {\n", src)
+		fmt.Fprintf(&b, "%s = *%s\n", dst, src)
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	ctx.topType = false
+
+	fmt.Fprint(&b, s.genCacheReset(dst))
+	for i, field := range s.fields {
+		if s.jsonNames[i] == "-" {
+			continue
+		}
+		fmt.Fprint(&b, field.genUnmarshalJSON(ctx, fmt.Sprintf("%s.%s", dst, s.fieldNames[i]), fmt.Sprintf("%s.%s", src, jsonField(s.fieldNames[i]))))
+	}
+	return b.String()
+}
+
+func (p *sszPointer) jsonType(ctx *genContext) string {
+	return p.elem.jsonType(ctx)
+}
+
+// genMarshalJSON converts the pointer to json, nil is regarded as the zero
+// value of the pointed type.
+func (p *sszPointer) genMarshalJSON(ctx *genContext, dst string, src string) string {
+	var b bytes.Buffer
+	if s, ok := p.elem.(*sszStruct); ok && !s.atFork {
+		fmt.Fprintf(&b, "%s = %s\n", dst, src)
+		fmt.Fprintf(&b, "if %s == nil {\n", dst)
+		fmt.Fprintf(&b, "%s = new(%s)\n", dst, p.elem.typeName())
+		fmt.Fprint(&b, "}\n")
+		return b.String()
+	}
+	ptr := ctx.tmpVar("p")
+	fmt.Fprintf(&b, "%s := %s\n", ptr, src)
+	fmt.Fprintf(&b, "if %s == nil {\n", ptr)
+	fmt.Fprintf(&b, "%s = new(%s)\n", ptr, p.elem.typeName())
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, p.elem.genMarshalJSON(ctx, dst, "(*"+ptr+")"))
+	return b.String()
+}
+
+func (p *sszPointer) genUnmarshalJSON(ctx *genContext, dst string, src string) string {
+	if s, ok := p.elem.(*sszStruct); ok && !s.atFork {
+		return fmt.Sprintf("%s = %s\n", dst, src)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s = new(%s)\n", dst, p.elem.typeName())
+	fmt.Fprint(&b, p.elem.genUnmarshalJSON(ctx, "(*"+dst+")", src))
+	return b.String()
+}
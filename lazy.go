@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// readerName returns the name of the type reading the fields of the struct
+// lazily from its encoding.
+func readerName(s *sszStruct) string {
+	return s.typeName() + "Reader"
+}
+
+// fieldLayouts returns the layouts of the fields in the encoding of the struct.
+func fieldLayouts(s *sszStruct) []ssz.Field {
+	var (
+		layouts = make([]ssz.Field, len(s.fields))
+		pos     int
+		last    = -1 // index of the last variable-size field
+	)
+	for i, field := range s.fields {
+		layouts[i].Offset = pos
+		if field.fixed() {
+			layouts[i].Size = field.fixedSize()
+		} else {
+			if last >= 0 {
+				layouts[last].Next = pos
+				layouts[i].Prev = layouts[last].Offset
+			} else {
+				layouts[i].First = true
+			}
+			last = i
+		}
+		pos += field.fixedSize()
+	}
+	for i := range layouts {
+		layouts[i].Fixed = pos
+	}
+	return layouts
+}
+
+// layoutLiteral returns the composite literal of the field layout, without the
+// type if it's elided in the enclosing literal.
+func layoutLiteral(ctx *genContext, f ssz.Field, elided bool) string {
+	var elems []string
+	if f.Fixed != 0 {
+		elems = append(elems, fmt.Sprintf("Offset: %d", f.Offset))
+		if f.Size != 0 {
+			elems = append(elems, fmt.Sprintf("Size: %d", f.Size))
+		}
+		if f.First {
+			elems = append(elems, "First: true")
+		} else if f.Size == 0 {
+			elems = append(elems, fmt.Sprintf("Prev: %d", f.Prev))
+		}
+		if f.Next != 0 {
+			elems = append(elems, fmt.Sprintf("Next: %d", f.Next))
+		}
+		elems = append(elems, fmt.Sprintf("Fixed: %d", f.Fixed))
+	}
+	literal := "{" + strings.Join(elems, ", ") + "}"
+	if elided {
+		return literal
+	}
+	return ctx.qualifier(pkgPath, "Field") + literal
+}
+
+// generateLazy generates the reader of the struct, which decodes the fields one
+// by one from the encoding without the rest. The fixed-size fields are located
+// by their static positions, and the variable-size ones by the offsets. The
+// items of the lists and vectors are accessed by the index in the same way.
+func generateLazy(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	var (
+		reader = readerName(s)
+		forked = forkGated(s)
+		fork   = ctx.qualifier(pkgPath, "Fork")
+		fields = s.fields
+	)
+	ctx.addImport(pkgPath, "")
+	ctx.addImport("io", "")
+	fmt.Fprintf(&b, "type %s struct {\n", reader)
+	fmt.Fprintf(&b, "c *%s\n", ctx.qualifier(pkgPath, "Container"))
+	if forked {
+		fmt.Fprintf(&b, "fork %s\n", fork)
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	if forked {
+		fmt.Fprintf(&b, "func New%s(r io.ReaderAt, size int64, fork %s) *%s {\n", reader, fork, reader)
+		fmt.Fprintf(&b, "return &%s{c: %s(r, size), fork: fork}\n", reader, ctx.qualifier(pkgPath, "NewContainer"))
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func New%sBytes(buf []byte, fork %s) *%s {\n", reader, fork, reader)
+		fmt.Fprintf(&b, "return &%s{c: %s(buf), fork: fork}\n", reader, ctx.qualifier(pkgPath, "NewContainerBytes"))
+	} else {
+		fmt.Fprintf(&b, "func New%s(r io.ReaderAt, size int64) *%s {\n", reader, reader)
+		fmt.Fprintf(&b, "return &%s{c: %s(r, size)}\n", reader, ctx.qualifier(pkgPath, "NewContainer"))
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func New%sBytes(buf []byte) *%s {\n", reader, reader)
+		fmt.Fprintf(&b, "return &%s{c: %s(buf)}\n", reader, ctx.qualifier(pkgPath, "NewContainerBytes"))
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	// The fields of the fork-gated struct are placed by the fork, resolve the
+	// layouts at runtime with the zero one for the fields absent.
+	var layout func(i int) string
+	if forked {
+		fmt.Fprintf(&b, "func (r *%s) field(i int) %s {\n", reader, ctx.qualifier(pkgPath, "Field"))
+		fmt.Fprint(&b, "fork := r.fork\n")
+		fmt.Fprint(&b, genForkSwitch(ctx, s, func(view *sszStruct) string {
+			var (
+				code    bytes.Buffer
+				layouts = fieldLayouts(view)
+			)
+			fmt.Fprintf(&code, "return [...]%s{\n", ctx.qualifier(pkgPath, "Field"))
+			for _, name := range s.fieldNames {
+				var f ssz.Field
+				for j := range view.fields {
+					if view.fieldNames[j] == name {
+						f = layouts[j]
+					}
+				}
+				fmt.Fprintf(&code, "%s,\n", layoutLiteral(ctx, f, true))
+			}
+			fmt.Fprint(&code, "}[i]\n")
+			return code.String()
+		}))
+		fmt.Fprint(&b, "}\n\n")
+
+		layout = func(i int) string {
+			return fmt.Sprintf("r.field(%d)", i)
+		}
+		// The types of the fields are resolved at any fork, the nested structs
+		// are decoded at the fork of this one anyway.
+		views := make(map[*sszStruct]*sszStruct)
+		fields = make([]sszType, len(s.fields))
+		for i, field := range s.fields {
+			fields[i] = forkView(field, ssz.ForkPhase0, views)
+		}
+	} else {
+		layouts := fieldLayouts(s)
+		layout = func(i int) string {
+			return layoutLiteral(ctx, layouts[i], false)
+		}
+	}
+	for i, field := range fields {
+		ctx.nvar, ctx.topType = 0, false
+
+		typ := goType(ctx, field)
+		fmt.Fprintf(&b, "func (r *%s) %s() (%s, error) {\n", reader, s.fieldNames[i], typ)
+		fmt.Fprintf(&b, "var x %s\n", typ)
+		fmt.Fprintf(&b, "err := r.c.Decode(%s, func(s *%s) error {\n", layout(i), ctx.qualifier(pkgPath, "Stream"))
+		if forkGated(s.fields[i]) {
+			fmt.Fprint(&b, "fork := r.fork\n")
+		}
+		fmt.Fprint(&b, field.genDecoder(ctx, "s", "x"))
+		fmt.Fprint(&b, "return nil\n")
+		fmt.Fprint(&b, "})\n")
+		fmt.Fprint(&b, "return x, err\n")
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprint(&b, genLazyItems(ctx, reader, s.fieldNames[i], field, forkGated(s.fields[i]), layout(i)))
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// itemsLayout returns the layout of the items of the list or vector, or false
+// if the items are not accessed one by one, e.g. the bytes.
+func itemsLayout(typ sszType) (sszType, ssz.Items, bool) {
+	var (
+		elem  sszType
+		items ssz.Items
+	)
+	switch t := typ.(type) {
+	case *sszVector:
+		elem, items.Max = t.elem, uint64(t.len)
+	case *sszList:
+		if t.bitlist {
+			return nil, items, false
+		}
+		elem = t.elem
+		if t.tag.size != 0 {
+			items.Max = uint64(t.tag.size)
+		} else {
+			items.Max, items.List = uint64(t.tag.limit), true
+		}
+	default:
+		return nil, items, false
+	}
+	if isBytes(elem) {
+		return nil, items, false
+	}
+	if elem.fixed() {
+		items.Size = elem.fixedSize()
+	}
+	return elem, items, true
+}
+
+// genLazyItems generates the access to the items of the list or vector field
+// by the index, located in the encoding without decoding the others.
+func genLazyItems(ctx *genContext, reader string, name string, field sszType, forked bool, layout string) string {
+	elem, items, ok := itemsLayout(field)
+	if !ok {
+		return ""
+	}
+	var (
+		b     bytes.Buffer
+		elems []string
+	)
+	if items.Size != 0 {
+		elems = append(elems, fmt.Sprintf("Size: %d", items.Size))
+	}
+	if items.Max != 0 {
+		elems = append(elems, fmt.Sprintf("Max: %d", items.Max))
+	}
+	if items.List {
+		elems = append(elems, "List: true")
+	}
+	literal := fmt.Sprintf("%s{%s}", ctx.qualifier(pkgPath, "Items"), strings.Join(elems, ", "))
+
+	fmt.Fprintf(&b, "func (r *%s) Len%s() (int, error) {\n", reader, name)
+	fmt.Fprintf(&b, "return r.c.Len(%s, %s)\n", layout, literal)
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (r *%s) %sBytesAt(i int) ([]byte, error) {\n", reader, name)
+	fmt.Fprintf(&b, "return r.c.ItemBytes(%s, %s, i)\n", layout, literal)
+	fmt.Fprint(&b, "}\n\n")
+
+	ctx.nvar, ctx.topType = 0, false
+	typ := goType(ctx, elem)
+	fmt.Fprintf(&b, "func (r *%s) %sAt(i int) (%s, error) {\n", reader, name, typ)
+	fmt.Fprintf(&b, "var x %s\n", typ)
+	fmt.Fprintf(&b, "err := r.c.DecodeItem(%s, %s, i, func(s *%s) error {\n", layout, literal, ctx.qualifier(pkgPath, "Stream"))
+	if forked {
+		fmt.Fprint(&b, "fork := r.fork\n")
+	}
+	fmt.Fprint(&b, elem.genDecoder(ctx, "s", "x"))
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "})\n")
+	fmt.Fprint(&b, "return x, err\n")
+	fmt.Fprint(&b, "}\n\n")
+	return b.String()
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"go/format"
 	"os"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -18,14 +19,37 @@ func main() {
 		pkgdir   = flag.String("dir", ".", "input package")
 		output   = flag.String("out", "-", "output file (default is stdout)")
 		typename = flag.String("type", "", "type to generate methods for")
+		nilmode  = flag.String("nil", "zero", "nil pointer handling in sizing and encoding (zero or reject)")
+		gencopy  = flag.Bool("copy", false, "generate Copy methods for deep copying")
+		genequal = flag.Bool("equal", false, "generate Equal methods for comparing by ssz semantics")
+		genrand  = flag.Bool("random", false, "generate GenerateRandomSSZ methods for creating random instances")
+		genjson  = flag.Bool("json", false, "generate MarshalJSON and UnmarshalJSON methods following the consensus json conventions")
+		gentests = flag.Bool("tests", false, "generate round-trip tests, fuzz targets and benchmarks next to the output")
+		genviews = flag.Bool("views", false, "generate the tree-backed views with copy-on-write updates")
+		genlazy  = flag.Bool("lazy", false, "generate the readers decoding single fields from the encoding")
 	)
 	flag.Parse()
 
+	policy, err := parseNilPolicy(*nilmode)
+	if err != nil {
+		fatal(err)
+	}
 	cfg := Config{
-		Dir:  *pkgdir,
-		Type: *typename,
+		Dir:       *pkgdir,
+		Type:      *typename,
+		NilPolicy: policy,
+		Copy:      *gencopy,
+		Equal:     *genequal,
+		Random:    *genrand,
+		JSON:      *genjson,
+		Tests:     *gentests,
+		Views:     *genviews,
+		Lazy:      *genlazy,
+	}
+	if cfg.Tests && *output == "-" {
+		fatal("generating tests requires the output file")
 	}
-	code, err := cfg.process()
+	code, tests, err := cfg.process()
 	if err != nil {
 		fatal(err)
 	}
@@ -34,6 +58,12 @@ func main() {
 	} else if err := os.WriteFile(*output, code, 0600); err != nil {
 		fatal(err)
 	}
+	if cfg.Tests {
+		path := strings.TrimSuffix(*output, ".go") + "_ssz_test.go"
+		if err := os.WriteFile(path, tests, 0600); err != nil {
+			fatal(err)
+		}
+	}
 }
 
 func fatal(args ...interface{}) {
@@ -42,51 +72,125 @@ func fatal(args ...interface{}) {
 }
 
 type Config struct {
-	Dir  string // input package directory
-	Type string
+	Dir       string // input package directory
+	Type      string
+	NilPolicy nilPolicy // nil pointer handling unless overridden by the ssz-nil tag
+	Copy      bool      // whether to generate the Copy methods
+	Equal     bool      // whether to generate the Equal methods
+	Random    bool      // whether to generate the GenerateRandomSSZ methods
+	JSON      bool      // whether to generate the MarshalJSON and UnmarshalJSON methods
+	Tests     bool      // whether to generate the tests, which implies Equal and Random
+	Views     bool      // whether to generate the tree-backed views
+	Lazy      bool      // whether to generate the readers decoding the fields lazily
+}
+
+// generators returns the code generators enabled by the config.
+func (cfg *Config) generators() []generator {
+	generators := []generator{
+		generateSSZSize,
+		generateSizeBounds,
+		generateEncoder,
+		generateDecoder,
+		generateHasher,
+		generateProver,
+		generateGIndex,
+		generateMarkDirty,
+		generateEach,
+	}
+	if cfg.Copy {
+		generators = append(generators, generateCopy)
+	}
+	if cfg.Equal || cfg.Tests {
+		generators = append(generators, generateEqual)
+	}
+	if cfg.Random || cfg.Tests {
+		generators = append(generators, generateRandom)
+	}
+	if cfg.JSON {
+		generators = append(generators, generateJSON)
+	}
+	if cfg.Views {
+		generators = append(generators, generateView)
+	}
+	if cfg.Lazy {
+		generators = append(generators, generateLazy)
+	}
+	return generators
 }
 
-// process generates the Go code.
-func (cfg *Config) process() ([]byte, error) {
+// process generates the Go code, along with the test code if it's enabled.
+func (cfg *Config) process() ([]byte, []byte, error) {
 	// Load packages.
 	pcfg := &packages.Config{
-		Mode:       packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Mode:       packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps,
 		Dir:        cfg.Dir,
 		BuildFlags: []string{"-tags", "nosszgen"},
 	}
 	ps, err := packages.Load(pcfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if len(ps) == 0 {
-		return nil, fmt.Errorf("no Go package found in %s", cfg.Dir)
+		return nil, nil, fmt.Errorf("no Go package found in %s", cfg.Dir)
 	}
 	if len(ps) != 1 {
-		return nil, fmt.Errorf("at most one package can be processed at the same time")
+		return nil, nil, fmt.Errorf("at most one package can be processed at the same time")
 	}
 	packages.PrintErrors(ps)
 
 	pkg := ps[0]
 	if len(pkg.Errors) > 0 {
-		return nil, fmt.Errorf("package %s has errors", pkg.PkgPath)
+		return nil, nil, fmt.Errorf("package %s has errors", pkg.PkgPath)
 	}
 	types, err := parsePackage(pkg.Types, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	families, err := parseForkDirectives(pkg.Syntax, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	gindexes, err := parseGIndexDirectives(pkg.Syntax, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := parseEachDirectives(pkg.Syntax, types); err != nil {
+		return nil, nil, err
 	}
 	var (
-		ctx    = newGenContext(pkg.Types)
-		chunks [][]byte
+		ctx        = newGenContext(pkg.Types, cfg.NilPolicy)
+		generators = cfg.generators()
+		chunks     [][]byte
 	)
 	for _, typ := range types {
-		ret, err := generate(ctx, typ)
+		ret, err := generate(ctx, typ, generators)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		chunks = append(chunks, ret)
 	}
-	code := bytes.Join(chunks, []byte("\n\n"))
+	defined := make(map[string]bool)
+	for _, family := range families {
+		chunks = append(chunks, generateForkFamily(ctx, family, defined))
+	}
+	if len(gindexes) > 0 {
+		chunk, err := generateGIndexNames(ctx, gindexes)
+		if err != nil {
+			return nil, nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	code := finalize(ctx, bytes.Join(chunks, []byte("\n\n")))
+	if !cfg.Tests {
+		return code, nil, nil
+	}
+	tctx := newGenContext(pkg.Types, cfg.NilPolicy)
+	return code, finalize(tctx, generateTests(tctx, types, cfg.JSON)), nil
+}
 
+// finalize adds the package clause, imports and build constraints to the
+// generated code.
+func finalize(ctx *genContext, code []byte) []byte {
 	// Add package and imports definition and format code
 	code = append(ctx.header(), code...)
 	code, _ = format.Source(code)
@@ -97,5 +201,5 @@ func (cfg *Config) process() ([]byte, error) {
 	fmt.Fprint(&header, "// Code generated by sszgen. DO NOT EDIT.\n\n")
 	fmt.Fprint(&header, "//go:build !nosszgen\n")
 	fmt.Fprint(&header, "// +build !nosszgen\n\n")
-	return append(header.Bytes(), code...), nil
+	return append(header.Bytes(), code...)
 }
@@ -9,13 +9,16 @@ func parsePackage(pkg *types.Package, names []string) ([]sszType, error) {
 	if len(names) == 0 {
 		names = pkg.Scope().Names()
 	}
-	var types []sszType
+	var (
+		types []sszType
+		cache = newTypeCache()
+	)
 	for _, name := range names {
 		named, err := lookupType(pkg.Scope(), name)
 		if err != nil {
 			return nil, err
 		}
-		typ, err := buildType(nil, named, nil)
+		typ, err := buildType(cache, nil, named, nil)
 		if err != nil {
 			return nil, err
 		}
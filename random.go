@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+)
+
+// isBytes reports whether the type is the plain byte.
+func isBytes(typ sszType) bool {
+	b, ok := typ.(*sszBasic)
+	return ok && b.named == nil && b.basic.Kind() == types.Uint8
+}
+
+func (b *sszBasic) genRandom(ctx *genContext, obj string) string {
+	var v string
+	switch b.basic.Kind() {
+	case types.Bool:
+		v = "r.Intn(2) == 1"
+	case types.Uint64:
+		v = "r.Uint64()"
+	default:
+		v = fmt.Sprintf("%s(r.Uint32())", b.basic.String())
+	}
+	if b.named != nil {
+		v = fmt.Sprintf("%s(%s)", b.named.Obj().Name(), v) // explicit type conversion
+	}
+	return fmt.Sprintf("%s = %s\n", obj, v)
+}
+
+func (v *sszVector) genRandom(ctx *genContext, obj string) string {
+	if isBytes(v.elem) {
+		return fmt.Sprintf("r.Read(%s[:])\n", obj) + genBitvectorMask(v.bits, obj)
+	}
+	var (
+		b   bytes.Buffer
+		cnt = ctx.tmpVar("i")
+	)
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, obj)
+	fmt.Fprint(&b, v.elem.genRandom(ctx, fmt.Sprintf("%s[%s]", obj, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+func (l *sszList) genRandom(ctx *genContext, obj string) string {
+	var (
+		b   bytes.Buffer
+		typ = ctx.typeString(l.slice)
+	)
+	if l.named != nil {
+		typ = ctx.typeString(l.named)
+	}
+	if l.bitlist {
+		v := fmt.Sprintf("%s(r, opts.ListLength(r, %d))", ctx.qualifier(pkgPath, "RandomBitlist"), l.tag.limit)
+		if l.named != nil {
+			v = fmt.Sprintf("%s(%s)", typ, v) // explicit type conversion
+		}
+		fmt.Fprintf(&b, "%s = %s\n", obj, v)
+		return b.String()
+	}
+	if l.tag.size != 0 {
+		fmt.Fprintf(&b, "%s = make(%s, %d)\n", obj, typ, l.tag.size)
+	} else {
+		fmt.Fprintf(&b, "%s = make(%s, opts.ListLength(r, %d))\n", obj, typ, l.tag.limit)
+	}
+	if isBytes(l.elem) {
+		fmt.Fprintf(&b, "r.Read(%s)\n", obj)
+		fmt.Fprint(&b, genBitvectorMask(l.bits, obj))
+		return b.String()
+	}
+	cnt := ctx.tmpVar("i")
+	fmt.Fprintf(&b, "for %s := range %s {\n", cnt, obj)
+	fmt.Fprint(&b, l.elem.genRandom(ctx, fmt.Sprintf("%s[%s]", obj, cnt)))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// genBitvectorMask generates the clearing of the padding bits of the random
+// bitvector, if the bytes are a bitvector.
+func genBitvectorMask(bits int64, obj string) string {
+	if bits%8 == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s[%d] &= %#x\n", obj, (bits-1)/8, byte(1)<<(bits%8)-1)
+}
+
+func (s *sszStruct) genRandom(ctx *genContext, obj string) string {
+	if !ctx.topType {
+		return fmt.Sprintf("%s.GenerateRandomSSZ(r, opts.Nested())\n", obj)
+	}
+	ctx.topType = false
+
+	var b bytes.Buffer
+	fmt.Fprint(&b, s.genCacheReset(obj))
+	for i, field := range s.fields {
+		fmt.Fprint(&b, field.genRandom(ctx, fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
+	}
+	return b.String()
+}
+
+func (p *sszPointer) genRandom(ctx *genContext, obj string) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s = new(%s)\n", obj, p.elem.typeName())
+	if _, ok := p.elem.(*sszStruct); ok {
+		fmt.Fprint(&b, p.elem.genRandom(ctx, obj))
+	} else {
+		fmt.Fprint(&b, p.elem.genRandom(ctx, "*"+obj))
+	}
+	return b.String()
+}
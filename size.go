@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// satAdd returns a+b, saturating at the maximum uint64.
+func satAdd(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// satMul returns a*b, saturating at the maximum uint64.
+func satMul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+// itemSizes returns the minimum and maximum size taken by n items, which is
+// the encoding of the items along with their offsets if they are variable-size.
+func itemSizes(elem sszType, n uint64) (uint64, uint64) {
+	if elem.fixed() {
+		size := satMul(n, uint64(elem.fixedSize()))
+		return size, size
+	}
+	var (
+		min = satMul(n, satAdd(ssz.BytesPerLengthOffset, elem.minSize()))
+		max = satMul(n, satAdd(ssz.BytesPerLengthOffset, elem.maxSize()))
+	)
+	return min, max
+}
+
+func (b *sszBasic) minSize() uint64 {
+	return uint64(b.size)
+}
+
+func (b *sszBasic) maxSize() uint64 {
+	return uint64(b.size)
+}
+
+func (v *sszVector) minSize() uint64 {
+	min, _ := itemSizes(v.elem, uint64(v.len))
+	return min
+}
+
+func (v *sszVector) maxSize() uint64 {
+	_, max := itemSizes(v.elem, uint64(v.len))
+	return max
+}
+
+// minSize returns zero for the variable-length lists without referring to the
+// items, which might be the enclosing struct itself.
+func (l *sszList) minSize() uint64 {
+	switch {
+	case l.bitlist:
+		return 1 // the delimiter bit
+	case l.tag.size != 0:
+		min, _ := itemSizes(l.elem, uint64(l.tag.size))
+		return min
+	default:
+		return 0
+	}
+}
+
+func (l *sszList) maxSize() uint64 {
+	switch {
+	case l.bitlist:
+		return uint64(l.tag.limit)/ssz.BitsPerByte + 1
+	case l.tag.size != 0:
+		_, max := itemSizes(l.elem, uint64(l.tag.size))
+		return max
+	case l.tag.limit == 0:
+		return math.MaxUint64
+	default:
+		_, max := itemSizes(l.elem, uint64(l.tag.limit))
+		return max
+	}
+}
+
+func (s *sszStruct) minSize() uint64 {
+	var size uint64
+	for _, field := range s.fields {
+		if field.fixed() {
+			size = satAdd(size, uint64(field.fixedSize()))
+		} else {
+			size = satAdd(size, satAdd(ssz.BytesPerLengthOffset, field.minSize()))
+		}
+	}
+	return size
+}
+
+// maxSize saturates if the struct refers to itself, which is only possible
+// through the variable-length lists.
+func (s *sszStruct) maxSize() uint64 {
+	if s.sizing {
+		return math.MaxUint64
+	}
+	s.sizing = true
+	defer func() { s.sizing = false }()
+
+	var size uint64
+	for _, field := range s.fields {
+		if field.fixed() {
+			size = satAdd(size, uint64(field.fixedSize()))
+		} else {
+			size = satAdd(size, satAdd(ssz.BytesPerLengthOffset, field.maxSize()))
+		}
+	}
+	return size
+}
+
+func (p *sszPointer) minSize() uint64 {
+	return p.elem.minSize()
+}
+
+func (p *sszPointer) maxSize() uint64 {
+	return p.elem.maxSize()
+}
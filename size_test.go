@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests the size bounds of the types, including the ones saturating on the
+// overflow and through the references to the struct itself.
+func TestSizeBounds(t *testing.T) {
+	tests := []struct {
+		fields   string
+		min, max uint64
+	}{
+		{"A uint64\nB [32]byte", 40, 40},
+		{"A []uint64 `ssz-max:\"4\"`", 4, 4 + 32},
+		{"A []uint64 `ssz-size:\"4\"`", 32, 32},
+		{"A []byte `ssz:\"bitlist\" ssz-max:\"2048\"`", 4 + 1, 4 + 257},
+		{"A [][]byte `ssz-max:\"4,8\"`", 4, 4 + 4*4 + 4*8},
+		{"A [][]byte `ssz-size:\"2,?\" ssz-max:\"?,8\"`", 4 + 2*4, 4 + 2*4 + 2*8},
+		{"A [][]byte `ssz-max:\"1099511627776,1099511627776\"`", 4, math.MaxUint64},
+		{"A []S `ssz-max:\"4\"`\nB uint64", 4 + 8, math.MaxUint64},
+	}
+	for _, tt := range tests {
+		_, built, err := buildSource(t, "package test\ntype S struct {\n"+tt.fields+"\n}\n", "S")
+		if err != nil {
+			t.Errorf("%q: failed to build: %v", tt.fields, err)
+			continue
+		}
+		if min, max := built[0].minSize(), built[0].maxSize(); min != tt.min || max != tt.max {
+			t.Errorf("%q: bounds mismatch: have [%d, %d], want [%d, %d]", tt.fields, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+// Tests the generated size bounds against the sizes defined by the spec, and
+// against the sizes of the random objects.
+func TestSizeBoundsSpec(t *testing.T) {
+	tests := []struct {
+		obj interface {
+			MinSizeSSZ() uint64
+			MaxSizeSSZ() uint64
+		}
+		min, max uint64
+	}{
+		{new(spectests.Checkpoint), 40, 40},
+		{new(spectests.AttestationData), 128, 128},
+		{new(spectests.Validator), 121, 121},
+		{new(spectests.BeaconBlockHeader), 112, 112},
+		{new(spectests.Attestation), 229, 485},
+		{new(spectests.AggregateAndProof), 337, 593},
+		{new(spectests.ExecutionPayload), 508, 1<<50 + 4<<20 + 540},
+	}
+	for _, tt := range tests {
+		if min, max := tt.obj.MinSizeSSZ(), tt.obj.MaxSizeSSZ(); min != tt.min || max != tt.max {
+			t.Errorf("%T: bounds mismatch: have [%d, %d], want [%d, %d]", tt.obj, min, max, tt.min, tt.max)
+		}
+	}
+	if spectests.CheckpointSSZSize != 40 || spectests.ValidatorSSZSize != 121 {
+		t.Errorf("size constants mismatch: checkpoint %d, validator %d", spectests.CheckpointSSZSize, spectests.ValidatorSSZSize)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		for _, obj := range []interface {
+			GenerateRandomSSZ(r *rand.Rand, opts *ssz.RandomOptions)
+			SizeSSZ() int
+			MinSizeSSZ() uint64
+			MaxSizeSSZ() uint64
+		}{
+			new(spectests.Attestation),
+			new(spectests.AggregateAndProof),
+			new(spectests.ExecutionPayloadCapella),
+			new(spectests.BeaconBlockBodyCapella),
+		} {
+			obj.GenerateRandomSSZ(r, nil)
+			if size := uint64(obj.SizeSSZ()); size < obj.MinSizeSSZ() || size > obj.MaxSizeSSZ() {
+				t.Errorf("%T: size %d out of bounds [%d, %d]", obj, size, obj.MinSizeSSZ(), obj.MaxSizeSSZ())
+			}
+		}
+		state := new(spectests.BeaconState)
+		state.GenerateRandomSSZ(r, nil)
+		for _, fork := range []ssz.Fork{ssz.ForkPhase0, ssz.ForkAltair, ssz.ForkBellatrix, ssz.ForkCapella} {
+			size := uint64(state.SizeSSZForFork(fork))
+			if min, max := state.MinSizeSSZForFork(fork), state.MaxSizeSSZForFork(fork); size < min || size > max {
+				t.Errorf("state at %v: size %d out of bounds [%d, %d]", fork, size, min, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,621 @@
+// Code generated by sszgen. DO NOT EDIT.
+
+//go:build !nosszgen
+// +build !nosszgen
+
+package spectests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rjl493456442/sszgen/ssz"
+	"math/rand"
+	"testing"
+)
+
+type sszTestObject interface {
+	SizeSSZ() int
+	MarshalSSZ() ([]byte, error)
+	MarshalSSZTo(w []byte) ([]byte, error)
+	UnmarshalSSZ(s *ssz.Stream) error
+	HashTreeRoot() ([32]byte, error)
+}
+
+var sszTestTypes = []struct {
+	name   string
+	new    func() sszTestObject
+	random func(r *rand.Rand) sszTestObject
+	equal  func(a, b sszTestObject) bool
+}{
+	{
+		name: "AggregateAndProof",
+		new:  func() sszTestObject { return new(AggregateAndProof) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(AggregateAndProof)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*AggregateAndProof).Equal(b.(*AggregateAndProof)) },
+	},
+	{
+		name:   "Attestation",
+		new:    func() sszTestObject { return new(Attestation) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Attestation); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Attestation).Equal(b.(*Attestation)) },
+	},
+	{
+		name: "AttestationData",
+		new:  func() sszTestObject { return new(AttestationData) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(AttestationData)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*AttestationData).Equal(b.(*AttestationData)) },
+	},
+	{
+		name: "AttesterSlashing",
+		new:  func() sszTestObject { return new(AttesterSlashing) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(AttesterSlashing)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*AttesterSlashing).Equal(b.(*AttesterSlashing)) },
+	},
+	{
+		name: "BLSToExecutionChange",
+		new:  func() sszTestObject { return new(BLSToExecutionChange) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BLSToExecutionChange)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BLSToExecutionChange).Equal(b.(*BLSToExecutionChange)) },
+	},
+	{
+		name:   "BeaconBlock",
+		new:    func() sszTestObject { return new(BeaconBlock) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(BeaconBlock); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*BeaconBlock).Equal(b.(*BeaconBlock)) },
+	},
+	{
+		name: "BeaconBlockAltair",
+		new:  func() sszTestObject { return new(BeaconBlockAltair) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockAltair)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockAltair).Equal(b.(*BeaconBlockAltair)) },
+	},
+	{
+		name: "BeaconBlockBellatrix",
+		new:  func() sszTestObject { return new(BeaconBlockBellatrix) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockBellatrix)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockBellatrix).Equal(b.(*BeaconBlockBellatrix)) },
+	},
+	{
+		name: "BeaconBlockBodyAltair",
+		new:  func() sszTestObject { return new(BeaconBlockBodyAltair) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockBodyAltair)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockBodyAltair).Equal(b.(*BeaconBlockBodyAltair)) },
+	},
+	{
+		name: "BeaconBlockBodyBellatrix",
+		new:  func() sszTestObject { return new(BeaconBlockBodyBellatrix) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockBodyBellatrix)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool {
+			return a.(*BeaconBlockBodyBellatrix).Equal(b.(*BeaconBlockBodyBellatrix))
+		},
+	},
+	{
+		name: "BeaconBlockBodyCapella",
+		new:  func() sszTestObject { return new(BeaconBlockBodyCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockBodyCapella)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockBodyCapella).Equal(b.(*BeaconBlockBodyCapella)) },
+	},
+	{
+		name: "BeaconBlockBodyPhase0",
+		new:  func() sszTestObject { return new(BeaconBlockBodyPhase0) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockBodyPhase0)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockBodyPhase0).Equal(b.(*BeaconBlockBodyPhase0)) },
+	},
+	{
+		name: "BeaconBlockCapella",
+		new:  func() sszTestObject { return new(BeaconBlockCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockCapella)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockCapella).Equal(b.(*BeaconBlockCapella)) },
+	},
+	{
+		name: "BeaconBlockHeader",
+		new:  func() sszTestObject { return new(BeaconBlockHeader) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconBlockHeader)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconBlockHeader).Equal(b.(*BeaconBlockHeader)) },
+	},
+	{
+		name: "BeaconState/phase0",
+		new:  func() sszTestObject { return ssz.AtFork(new(BeaconState), ssz.ForkPhase0) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconState)
+			obj.GenerateRandomSSZ(r, nil)
+			return ssz.AtFork(obj, ssz.ForkPhase0)
+		},
+		equal: sszEqualEncoding,
+	},
+	{
+		name: "BeaconState/altair",
+		new:  func() sszTestObject { return ssz.AtFork(new(BeaconState), ssz.ForkAltair) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconState)
+			obj.GenerateRandomSSZ(r, nil)
+			return ssz.AtFork(obj, ssz.ForkAltair)
+		},
+		equal: sszEqualEncoding,
+	},
+	{
+		name: "BeaconState/bellatrix",
+		new:  func() sszTestObject { return ssz.AtFork(new(BeaconState), ssz.ForkBellatrix) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconState)
+			obj.GenerateRandomSSZ(r, nil)
+			return ssz.AtFork(obj, ssz.ForkBellatrix)
+		},
+		equal: sszEqualEncoding,
+	},
+	{
+		name: "BeaconState/capella",
+		new:  func() sszTestObject { return ssz.AtFork(new(BeaconState), ssz.ForkCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconState)
+			obj.GenerateRandomSSZ(r, nil)
+			return ssz.AtFork(obj, ssz.ForkCapella)
+		},
+		equal: sszEqualEncoding,
+	},
+	{
+		name: "BeaconStatePhase0",
+		new:  func() sszTestObject { return new(BeaconStatePhase0) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(BeaconStatePhase0)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*BeaconStatePhase0).Equal(b.(*BeaconStatePhase0)) },
+	},
+	{
+		name:   "Checkpoint",
+		new:    func() sszTestObject { return new(Checkpoint) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Checkpoint); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Checkpoint).Equal(b.(*Checkpoint)) },
+	},
+	{
+		name:   "Deposit",
+		new:    func() sszTestObject { return new(Deposit) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Deposit); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Deposit).Equal(b.(*Deposit)) },
+	},
+	{
+		name:   "DepositData",
+		new:    func() sszTestObject { return new(DepositData) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(DepositData); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*DepositData).Equal(b.(*DepositData)) },
+	},
+	{
+		name: "DepositMessage",
+		new:  func() sszTestObject { return new(DepositMessage) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(DepositMessage)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*DepositMessage).Equal(b.(*DepositMessage)) },
+	},
+	{
+		name:   "ErrorResponse",
+		new:    func() sszTestObject { return new(ErrorResponse) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(ErrorResponse); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*ErrorResponse).Equal(b.(*ErrorResponse)) },
+	},
+	{
+		name:   "Eth1Block",
+		new:    func() sszTestObject { return new(Eth1Block) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Eth1Block); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Eth1Block).Equal(b.(*Eth1Block)) },
+	},
+	{
+		name:   "Eth1Data",
+		new:    func() sszTestObject { return new(Eth1Data) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Eth1Data); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Eth1Data).Equal(b.(*Eth1Data)) },
+	},
+	{
+		name: "ExecutionPayload",
+		new:  func() sszTestObject { return new(ExecutionPayload) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(ExecutionPayload)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*ExecutionPayload).Equal(b.(*ExecutionPayload)) },
+	},
+	{
+		name: "ExecutionPayloadCapella",
+		new:  func() sszTestObject { return new(ExecutionPayloadCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(ExecutionPayloadCapella)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*ExecutionPayloadCapella).Equal(b.(*ExecutionPayloadCapella)) },
+	},
+	{
+		name: "ExecutionPayloadHeader",
+		new:  func() sszTestObject { return new(ExecutionPayloadHeader) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(ExecutionPayloadHeader)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*ExecutionPayloadHeader).Equal(b.(*ExecutionPayloadHeader)) },
+	},
+	{
+		name: "ExecutionPayloadHeaderCapella",
+		new:  func() sszTestObject { return new(ExecutionPayloadHeaderCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(ExecutionPayloadHeaderCapella)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool {
+			return a.(*ExecutionPayloadHeaderCapella).Equal(b.(*ExecutionPayloadHeaderCapella))
+		},
+	},
+	{
+		name:   "Fork",
+		new:    func() sszTestObject { return new(Fork) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Fork); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Fork).Equal(b.(*Fork)) },
+	},
+	{
+		name: "HistoricalBatch",
+		new:  func() sszTestObject { return new(HistoricalBatch) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(HistoricalBatch)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*HistoricalBatch).Equal(b.(*HistoricalBatch)) },
+	},
+	{
+		name: "HistoricalSummary",
+		new:  func() sszTestObject { return new(HistoricalSummary) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(HistoricalSummary)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*HistoricalSummary).Equal(b.(*HistoricalSummary)) },
+	},
+	{
+		name: "IndexedAttestation",
+		new:  func() sszTestObject { return new(IndexedAttestation) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(IndexedAttestation)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*IndexedAttestation).Equal(b.(*IndexedAttestation)) },
+	},
+	{
+		name: "PendingAttestation",
+		new:  func() sszTestObject { return new(PendingAttestation) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(PendingAttestation)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*PendingAttestation).Equal(b.(*PendingAttestation)) },
+	},
+	{
+		name: "ProposerSlashing",
+		new:  func() sszTestObject { return new(ProposerSlashing) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(ProposerSlashing)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*ProposerSlashing).Equal(b.(*ProposerSlashing)) },
+	},
+	{
+		name: "SignedBLSToExecutionChange",
+		new:  func() sszTestObject { return new(SignedBLSToExecutionChange) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBLSToExecutionChange)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool {
+			return a.(*SignedBLSToExecutionChange).Equal(b.(*SignedBLSToExecutionChange))
+		},
+	},
+	{
+		name: "SignedBeaconBlock",
+		new:  func() sszTestObject { return new(SignedBeaconBlock) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBeaconBlock)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*SignedBeaconBlock).Equal(b.(*SignedBeaconBlock)) },
+	},
+	{
+		name: "SignedBeaconBlockAltair",
+		new:  func() sszTestObject { return new(SignedBeaconBlockAltair) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBeaconBlockAltair)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*SignedBeaconBlockAltair).Equal(b.(*SignedBeaconBlockAltair)) },
+	},
+	{
+		name: "SignedBeaconBlockBellatrix",
+		new:  func() sszTestObject { return new(SignedBeaconBlockBellatrix) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBeaconBlockBellatrix)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool {
+			return a.(*SignedBeaconBlockBellatrix).Equal(b.(*SignedBeaconBlockBellatrix))
+		},
+	},
+	{
+		name: "SignedBeaconBlockCapella",
+		new:  func() sszTestObject { return new(SignedBeaconBlockCapella) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBeaconBlockCapella)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool {
+			return a.(*SignedBeaconBlockCapella).Equal(b.(*SignedBeaconBlockCapella))
+		},
+	},
+	{
+		name: "SignedBeaconBlockHeader",
+		new:  func() sszTestObject { return new(SignedBeaconBlockHeader) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedBeaconBlockHeader)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*SignedBeaconBlockHeader).Equal(b.(*SignedBeaconBlockHeader)) },
+	},
+	{
+		name: "SignedVoluntaryExit",
+		new:  func() sszTestObject { return new(SignedVoluntaryExit) },
+		random: func(r *rand.Rand) sszTestObject {
+			obj := new(SignedVoluntaryExit)
+			obj.GenerateRandomSSZ(r, nil)
+			return obj
+		},
+		equal: func(a, b sszTestObject) bool { return a.(*SignedVoluntaryExit).Equal(b.(*SignedVoluntaryExit)) },
+	},
+	{
+		name:   "SigningRoot",
+		new:    func() sszTestObject { return new(SigningRoot) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(SigningRoot); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*SigningRoot).Equal(b.(*SigningRoot)) },
+	},
+	{
+		name:   "SyncAggregate",
+		new:    func() sszTestObject { return new(SyncAggregate) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(SyncAggregate); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*SyncAggregate).Equal(b.(*SyncAggregate)) },
+	},
+	{
+		name:   "SyncCommittee",
+		new:    func() sszTestObject { return new(SyncCommittee) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(SyncCommittee); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*SyncCommittee).Equal(b.(*SyncCommittee)) },
+	},
+	{
+		name:   "Transfer",
+		new:    func() sszTestObject { return new(Transfer) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Transfer); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Transfer).Equal(b.(*Transfer)) },
+	},
+	{
+		name:   "Validator",
+		new:    func() sszTestObject { return new(Validator) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Validator); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Validator).Equal(b.(*Validator)) },
+	},
+	{
+		name:   "VoluntaryExit",
+		new:    func() sszTestObject { return new(VoluntaryExit) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(VoluntaryExit); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*VoluntaryExit).Equal(b.(*VoluntaryExit)) },
+	},
+	{
+		name:   "Withdrawal",
+		new:    func() sszTestObject { return new(Withdrawal) },
+		random: func(r *rand.Rand) sszTestObject { obj := new(Withdrawal); obj.GenerateRandomSSZ(r, nil); return obj },
+		equal:  func(a, b sszTestObject) bool { return a.(*Withdrawal).Equal(b.(*Withdrawal)) },
+	},
+}
+
+func TestSSZRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 16; i++ {
+				obj := tt.random(r)
+				enc, err := obj.MarshalSSZ()
+				if err != nil {
+					t.Fatalf("failed to encode: %v", err)
+				}
+				if len(enc) != obj.SizeSSZ() {
+					t.Fatalf("encoding size mismatch, want: %d, got: %d", obj.SizeSSZ(), len(enc))
+				}
+				dec := tt.new()
+				if err := ssz.Unmarshal(enc, dec); err != nil {
+					t.Fatalf("failed to decode: %v", err)
+				}
+				if !tt.equal(obj, dec) {
+					t.Fatal("decoded object mismatches the original one")
+				}
+				want, err := obj.HashTreeRoot()
+				if err != nil {
+					t.Fatalf("failed to hash: %v", err)
+				}
+				if have, err := dec.HashTreeRoot(); err != nil || have != want {
+					t.Fatalf("decoded object root mismatch, want: %x, got: %x, err: %v", want, have, err)
+				}
+			}
+		})
+	}
+}
+
+func FuzzUnmarshalSSZ(f *testing.F) {
+	r := rand.New(rand.NewSource(1))
+	for i, tt := range sszTestTypes {
+		enc, err := tt.random(r).MarshalSSZ()
+		if err != nil {
+			f.Fatalf("failed to encode %s: %v", tt.name, err)
+		}
+		f.Add(append([]byte{byte(i)}, enc...))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		tt := sszTestTypes[int(data[0])%len(sszTestTypes)]
+		obj := tt.new()
+		if err := ssz.Unmarshal(data[1:], obj); err != nil {
+			return
+		}
+		enc, err := obj.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("failed to encode decoded %s: %v", tt.name, err)
+		}
+		if !bytes.Equal(enc, data[1:]) {
+			t.Fatalf("non-canonical decoding of %s", tt.name)
+		}
+	})
+}
+
+func BenchmarkMarshalSSZ(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		obj := tt.random(r)
+		b.Run(tt.name, func(b *testing.B) {
+			buf := make([]byte, 0, obj.SizeSSZ())
+			b.SetBytes(int64(obj.SizeSSZ()))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := obj.MarshalSSZTo(buf[:0]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalSSZ(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		enc, err := tt.random(r).MarshalSSZ()
+		if err != nil {
+			b.Fatalf("failed to encode %s: %v", tt.name, err)
+		}
+		b.Run(tt.name, func(b *testing.B) {
+			b.SetBytes(int64(len(enc)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := ssz.Unmarshal(enc, tt.new()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHashTreeRoot(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		obj := tt.random(r)
+		b.Run(tt.name, func(b *testing.B) {
+			b.SetBytes(int64(obj.SizeSSZ()))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := obj.HashTreeRoot(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func sszEqualEncoding(a, b sszTestObject) bool {
+	encA, errA := a.MarshalSSZ()
+	encB, errB := b.MarshalSSZ()
+	return errA == nil && errB == nil && bytes.Equal(encA, encB)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 16; i++ {
+				obj := tt.random(r)
+				enc, err := json.Marshal(obj)
+				if err != nil {
+					t.Fatalf("failed to encode: %v", err)
+				}
+				dec := tt.new()
+				if err := json.Unmarshal(enc, dec); err != nil {
+					t.Fatalf("failed to decode: %v", err)
+				}
+				if !tt.equal(obj, dec) {
+					t.Fatal("decoded object mismatches the original one")
+				}
+			}
+		})
+	}
+}
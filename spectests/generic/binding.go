@@ -0,0 +1,840 @@
+// Code generated by sszgen. DO NOT EDIT.
+
+//go:build !nosszgen
+// +build !nosszgen
+
+package generic
+
+import "github.com/rjl493456442/sszgen/ssz"
+
+func (obj *BitsStruct) SizeSSZ() int {
+	s := 11
+	s += len(obj.A)
+	s += len(obj.D)
+	return s
+}
+
+func (obj *BitsStruct) MinSizeSSZ() uint64 {
+	return 13
+}
+
+func (obj *BitsStruct) MaxSizeSSZ() uint64 {
+	return 13
+}
+
+func (obj *BitsStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *BitsStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	_o0 := 11
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += len(obj.A)
+	if _e1 := ssz.ValidateBitvector(obj.B[:], 2); _e1 != nil {
+		return nil, _e1
+	}
+	w = ssz.EncodeBytes(w, obj.B[:])
+	if _e2 := ssz.ValidateBitvector(obj.C[:], 1); _e2 != nil {
+		return nil, _e2
+	}
+	w = ssz.EncodeBytes(w, obj.C[:])
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += len(obj.D)
+	w = ssz.EncodeBytes(w, obj.E[:])
+	if _e3 := ssz.ValidateBitlist(obj.A, 5); _e3 != nil {
+		return nil, _e3
+	}
+	w = ssz.EncodeBytes(w, obj.A)
+	if _e4 := ssz.ValidateBitlist(obj.D, 6); _e4 != nil {
+		return nil, _e4
+	}
+	w = ssz.EncodeBytes(w, obj.D)
+	return w, nil
+}
+
+func (obj *BitsStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	if _e0 := s.DecodeOffset(); _e0 != nil {
+		return _e0
+	}
+	_v1, _e2 := ssz.DecodeBytes(s, 1)
+	if _e2 != nil {
+		return _e2
+	}
+	if _e3 := ssz.ValidateBitvector(_v1, 2); _e3 != nil {
+		return _e3
+	}
+	obj.B = [1]byte(_v1)
+	_v4, _e5 := ssz.DecodeBytes(s, 1)
+	if _e5 != nil {
+		return _e5
+	}
+	if _e6 := ssz.ValidateBitvector(_v4, 1); _e6 != nil {
+		return _e6
+	}
+	obj.C = [1]byte(_v4)
+	if _e7 := s.DecodeOffset(); _e7 != nil {
+		return _e7
+	}
+	_v8, _e9 := ssz.DecodeBytes(s, 1)
+	if _e9 != nil {
+		return _e9
+	}
+	obj.E = [1]byte(_v8)
+	_e10 := s.BlockStart()
+	if _e10 != nil {
+		return _e10
+	}
+	_v11, _e12 := ssz.DecodeBytes(s, 0)
+	if _e12 != nil {
+		return _e12
+	}
+	if _e12 := ssz.ValidateBitlist(_v11, 5); _e12 != nil {
+		return _e12
+	}
+	obj.A = _v11
+	_e10 = s.BlockEnd()
+	if _e10 != nil {
+		return _e10
+	}
+	_e13 := s.BlockStart()
+	if _e13 != nil {
+		return _e13
+	}
+	_v14, _e15 := ssz.DecodeBytes(s, 0)
+	if _e15 != nil {
+		return _e15
+	}
+	if _e15 := ssz.ValidateBitlist(_v14, 6); _e15 != nil {
+		return _e15
+	}
+	obj.D = _v14
+	_e13 = s.BlockEnd()
+	if _e13 != nil {
+		return _e13
+	}
+	return nil
+}
+
+func (obj *BitsStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *BitsStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	if _e1 := ssz.ValidateBitlist(obj.A, 5); _e1 != nil {
+		return _e1
+	}
+	h.PutBitlist(obj.A, 5)
+	h.PutBytes(obj.B[:])
+	h.PutBytes(obj.C[:])
+	if _e2 := ssz.ValidateBitlist(obj.D, 6); _e2 != nil {
+		return _e2
+	}
+	h.PutBitlist(obj.D, 6)
+	h.PutBytes(obj.E[:])
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *BitsStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexBitsStructA = 8
+	GIndexBitsStructB = 9
+	GIndexBitsStructC = 10
+	GIndexBitsStructD = 11
+	GIndexBitsStructE = 12
+)
+
+func (obj *BitsStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 8, ssz.PackedListGIndex(5, 1)
+	case "B":
+		gindex, field = 9, ssz.PackedVectorGIndex(2, 1)
+	case "C":
+		gindex, field = 10, ssz.PackedVectorGIndex(1, 1)
+	case "D":
+		gindex, field = 11, ssz.PackedListGIndex(6, 1)
+	case "E":
+		gindex, field = 12, ssz.PackedVectorGIndex(8, 1)
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
+
+func (obj *ComplexTestStruct) SizeSSZ() int {
+	s := 71
+	s += len(obj.B) * 2
+	s += len(obj.D)
+	s += obj.E.SizeSSZ()
+	for _, _v0 := range obj.G {
+		s += _v0.SizeSSZ()
+	}
+	return s
+}
+
+func (obj *ComplexTestStruct) MinSizeSSZ() uint64 {
+	return 100
+}
+
+func (obj *ComplexTestStruct) MaxSizeSSZ() uint64 {
+	return 6756
+}
+
+func (obj *ComplexTestStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *ComplexTestStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	_o0 := 71
+	w = ssz.EncodeUint16(w, obj.A)
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += len(obj.B) * 2
+	w = ssz.EncodeByte(w, obj.C)
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += len(obj.D)
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += obj.E.SizeSSZ()
+	for _, _v1 := range obj.F {
+		_w2, _e3 := _v1.MarshalSSZTo(w)
+		if _e3 != nil {
+			return nil, _e3
+		}
+		w = _w2
+	}
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	for _, _v4 := range obj.G {
+		_o0 += _v4.SizeSSZ()
+	}
+	if len(obj.B) > 128 {
+		return nil, ssz.ErrListTooBig
+	}
+	w = ssz.EncodeUint16s(w, obj.B)
+	if len(obj.D) > 256 {
+		return nil, ssz.ErrListTooBig
+	}
+	w = ssz.EncodeBytes(w, obj.D)
+	_w5, _e6 := obj.E.MarshalSSZTo(w)
+	if _e6 != nil {
+		return nil, _e6
+	}
+	w = _w5
+	_o7 := len(obj.G) * 4
+	for _, _v8 := range obj.G {
+		w = ssz.EncodeUint32(w, uint32(_o7))
+		_o7 += _v8.SizeSSZ()
+	}
+	for _, _v9 := range obj.G {
+		_w10, _e11 := _v9.MarshalSSZTo(w)
+		if _e11 != nil {
+			return nil, _e11
+		}
+		w = _w10
+	}
+	return w, nil
+}
+
+func (obj *ComplexTestStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	_v0, _e1 := ssz.DecodeUint16(s)
+	if _e1 != nil {
+		return _e1
+	}
+	obj.A = _v0
+	if _e2 := s.DecodeOffset(); _e2 != nil {
+		return _e2
+	}
+	_v3, _e4 := ssz.DecodeByte(s)
+	if _e4 != nil {
+		return _e4
+	}
+	obj.C = _v3
+	if _e5 := s.DecodeOffset(); _e5 != nil {
+		return _e5
+	}
+	if _e6 := s.DecodeOffset(); _e6 != nil {
+		return _e6
+	}
+	for _i7 := 0; _i7 < 4; _i7 += 1 {
+		if err := obj.F[_i7].UnmarshalSSZ(s); err != nil {
+			return err
+		}
+	}
+	if _e8 := s.DecodeOffset(); _e8 != nil {
+		return _e8
+	}
+	_e9 := s.BlockStart()
+	if _e9 != nil {
+		return _e9
+	}
+	_v10, _e11 := ssz.DecodeUint16s(s, 0)
+	if _e11 != nil {
+		return _e11
+	}
+	if len(_v10) > 128 {
+		return ssz.ErrListTooBig
+	}
+	obj.B = _v10
+	_e9 = s.BlockEnd()
+	if _e9 != nil {
+		return _e9
+	}
+	_e12 := s.BlockStart()
+	if _e12 != nil {
+		return _e12
+	}
+	_v13, _e14 := ssz.DecodeBytes(s, 0)
+	if _e14 != nil {
+		return _e14
+	}
+	if len(_v13) > 256 {
+		return ssz.ErrListTooBig
+	}
+	obj.D = _v13
+	_e12 = s.BlockEnd()
+	if _e12 != nil {
+		return _e12
+	}
+	_e15 := s.BlockStart()
+	if _e15 != nil {
+		return _e15
+	}
+	if err := obj.E.UnmarshalSSZ(s); err != nil {
+		return err
+	}
+	_e15 = s.BlockEnd()
+	if _e15 != nil {
+		return _e15
+	}
+	_e16 := s.BlockStart()
+	if _e16 != nil {
+		return _e16
+	}
+	for _i17 := 0; _i17 < 2; _i17 += 1 {
+		if err := s.DecodeOffset(); err != nil {
+			return err
+		}
+	}
+	for _i17 := 0; _i17 < 2; _i17 += 1 {
+		_e18 := s.BlockStart()
+		if _e18 != nil {
+			return _e18
+		}
+		if err := obj.G[_i17].UnmarshalSSZ(s); err != nil {
+			return err
+		}
+		_e18 = s.BlockEnd()
+		if _e18 != nil {
+			return _e18
+		}
+	}
+	_e16 = s.BlockEnd()
+	if _e16 != nil {
+		return _e16
+	}
+	return nil
+}
+
+func (obj *ComplexTestStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *ComplexTestStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	h.PutUint16(obj.A)
+	if len(obj.B) > 128 {
+		return ssz.ErrListTooBig
+	}
+	_i1 := h.Index()
+	for _, _v2 := range obj.B {
+		h.AppendUint16(_v2)
+	}
+	h.FillUpTo32()
+	h.MerkleizeWithMixin(_i1, uint64(len(obj.B)), 8)
+	h.PutUint8(obj.C)
+	if len(obj.D) > 256 {
+		return ssz.ErrListTooBig
+	}
+	_i3 := h.Index()
+	h.AppendBytes32(obj.D)
+	h.MerkleizeWithMixin(_i3, uint64(len(obj.D)), 8)
+	if _e4 := obj.E.HashTreeRootWith(h); _e4 != nil {
+		return _e4
+	}
+	_i5 := h.Index()
+	if _e6 := h.HashItems(len(obj.F), func(h *ssz.Hasher, _from7, _to8 int) error {
+		_s9 := obj.F[_from7:_to8]
+		for _i10 := range _s9 {
+			if _e11 := _s9[_i10].HashTreeRootWith(h); _e11 != nil {
+				return _e11
+			}
+		}
+		return nil
+	}); _e6 != nil {
+		return _e6
+	}
+	h.Merkleize(_i5)
+	_i12 := h.Index()
+	if _e13 := h.HashItems(len(obj.G), func(h *ssz.Hasher, _from14, _to15 int) error {
+		_s16 := obj.G[_from14:_to15]
+		for _i17 := range _s16 {
+			if _e18 := _s16[_i17].HashTreeRootWith(h); _e18 != nil {
+				return _e18
+			}
+		}
+		return nil
+	}); _e13 != nil {
+		return _e13
+	}
+	h.Merkleize(_i12)
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *ComplexTestStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexComplexTestStructA = 8
+	GIndexComplexTestStructB = 9
+	GIndexComplexTestStructC = 10
+	GIndexComplexTestStructD = 11
+	GIndexComplexTestStructE = 12
+	GIndexComplexTestStructF = 13
+	GIndexComplexTestStructG = 14
+)
+
+func (obj *ComplexTestStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 8, ssz.BasicGIndex
+	case "B":
+		gindex, field = 9, ssz.PackedListGIndex(128, 16)
+	case "C":
+		gindex, field = 10, ssz.BasicGIndex
+	case "D":
+		gindex, field = 11, ssz.PackedListGIndex(256, 8)
+	case "E":
+		gindex, field = 12, (*VarTestStruct)(nil).GIndexSSZ
+	case "F":
+		gindex, field = 13, ssz.VectorGIndex(4, (*FixedTestStruct)(nil).GIndexSSZ)
+	case "G":
+		gindex, field = 14, ssz.VectorGIndex(2, (*VarTestStruct)(nil).GIndexSSZ)
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
+
+func (obj *FixedTestStruct) SizeSSZ() int {
+	s := 13
+	return s
+}
+
+const FixedTestStructSSZSize = 13
+
+func (obj *FixedTestStruct) MinSizeSSZ() uint64 {
+	return FixedTestStructSSZSize
+}
+
+func (obj *FixedTestStruct) MaxSizeSSZ() uint64 {
+	return FixedTestStructSSZSize
+}
+
+func (obj *FixedTestStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *FixedTestStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	w = ssz.EncodeByte(w, obj.A)
+	w = ssz.EncodeUint64(w, obj.B)
+	w = ssz.EncodeUint32(w, obj.C)
+	return w, nil
+}
+
+func (obj *FixedTestStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	_v0, _e1 := ssz.DecodeByte(s)
+	if _e1 != nil {
+		return _e1
+	}
+	obj.A = _v0
+	_v2, _e3 := ssz.DecodeUint64(s)
+	if _e3 != nil {
+		return _e3
+	}
+	obj.B = _v2
+	_v4, _e5 := ssz.DecodeUint32(s)
+	if _e5 != nil {
+		return _e5
+	}
+	obj.C = _v4
+	return nil
+}
+
+func (obj *FixedTestStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *FixedTestStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	h.PutUint8(obj.A)
+	h.PutUint64(obj.B)
+	h.PutUint32(obj.C)
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *FixedTestStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexFixedTestStructA = 4
+	GIndexFixedTestStructB = 5
+	GIndexFixedTestStructC = 6
+)
+
+func (obj *FixedTestStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 4, ssz.BasicGIndex
+	case "B":
+		gindex, field = 5, ssz.BasicGIndex
+	case "C":
+		gindex, field = 6, ssz.BasicGIndex
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
+
+func (obj *SingleFieldTestStruct) SizeSSZ() int {
+	s := 1
+	return s
+}
+
+const SingleFieldTestStructSSZSize = 1
+
+func (obj *SingleFieldTestStruct) MinSizeSSZ() uint64 {
+	return SingleFieldTestStructSSZSize
+}
+
+func (obj *SingleFieldTestStruct) MaxSizeSSZ() uint64 {
+	return SingleFieldTestStructSSZSize
+}
+
+func (obj *SingleFieldTestStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *SingleFieldTestStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	w = ssz.EncodeByte(w, obj.A)
+	return w, nil
+}
+
+func (obj *SingleFieldTestStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	_v0, _e1 := ssz.DecodeByte(s)
+	if _e1 != nil {
+		return _e1
+	}
+	obj.A = _v0
+	return nil
+}
+
+func (obj *SingleFieldTestStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *SingleFieldTestStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	h.PutUint8(obj.A)
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *SingleFieldTestStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexSingleFieldTestStructA = 1
+)
+
+func (obj *SingleFieldTestStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 1, ssz.BasicGIndex
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
+
+func (obj *SmallTestStruct) SizeSSZ() int {
+	s := 4
+	return s
+}
+
+const SmallTestStructSSZSize = 4
+
+func (obj *SmallTestStruct) MinSizeSSZ() uint64 {
+	return SmallTestStructSSZSize
+}
+
+func (obj *SmallTestStruct) MaxSizeSSZ() uint64 {
+	return SmallTestStructSSZSize
+}
+
+func (obj *SmallTestStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *SmallTestStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	w = ssz.EncodeUint16(w, obj.A)
+	w = ssz.EncodeUint16(w, obj.B)
+	return w, nil
+}
+
+func (obj *SmallTestStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	_v0, _e1 := ssz.DecodeUint16(s)
+	if _e1 != nil {
+		return _e1
+	}
+	obj.A = _v0
+	_v2, _e3 := ssz.DecodeUint16(s)
+	if _e3 != nil {
+		return _e3
+	}
+	obj.B = _v2
+	return nil
+}
+
+func (obj *SmallTestStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *SmallTestStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	h.PutUint16(obj.A)
+	h.PutUint16(obj.B)
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *SmallTestStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexSmallTestStructA = 2
+	GIndexSmallTestStructB = 3
+)
+
+func (obj *SmallTestStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 2, ssz.BasicGIndex
+	case "B":
+		gindex, field = 3, ssz.BasicGIndex
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
+
+func (obj *VarTestStruct) SizeSSZ() int {
+	s := 7
+	s += len(obj.B) * 2
+	return s
+}
+
+func (obj *VarTestStruct) MinSizeSSZ() uint64 {
+	return 7
+}
+
+func (obj *VarTestStruct) MaxSizeSSZ() uint64 {
+	return 2055
+}
+
+func (obj *VarTestStruct) MarshalSSZ() ([]byte, error) {
+	return obj.MarshalSSZTo(make([]byte, 0, obj.SizeSSZ()))
+}
+
+func (obj *VarTestStruct) MarshalSSZTo(w []byte) ([]byte, error) {
+	_o0 := 7
+	w = ssz.EncodeUint16(w, obj.A)
+	w = ssz.EncodeUint32(w, uint32(_o0))
+	_o0 += len(obj.B) * 2
+	w = ssz.EncodeByte(w, obj.C)
+	if len(obj.B) > 1024 {
+		return nil, ssz.ErrListTooBig
+	}
+	w = ssz.EncodeUint16s(w, obj.B)
+	return w, nil
+}
+
+func (obj *VarTestStruct) UnmarshalSSZ(s *ssz.Stream) error {
+	_v0, _e1 := ssz.DecodeUint16(s)
+	if _e1 != nil {
+		return _e1
+	}
+	obj.A = _v0
+	if _e2 := s.DecodeOffset(); _e2 != nil {
+		return _e2
+	}
+	_v3, _e4 := ssz.DecodeByte(s)
+	if _e4 != nil {
+		return _e4
+	}
+	obj.C = _v3
+	_e5 := s.BlockStart()
+	if _e5 != nil {
+		return _e5
+	}
+	_v6, _e7 := ssz.DecodeUint16s(s, 0)
+	if _e7 != nil {
+		return _e7
+	}
+	if len(_v6) > 1024 {
+		return ssz.ErrListTooBig
+	}
+	obj.B = _v6
+	_e5 = s.BlockEnd()
+	if _e5 != nil {
+		return _e5
+	}
+	return nil
+}
+
+func (obj *VarTestStruct) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	if err := obj.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (obj *VarTestStruct) HashTreeRootWith(h *ssz.Hasher) error {
+	_i0 := h.Index()
+	h.PutUint16(obj.A)
+	if len(obj.B) > 1024 {
+		return ssz.ErrListTooBig
+	}
+	_i1 := h.Index()
+	for _, _v2 := range obj.B {
+		h.AppendUint16(_v2)
+	}
+	h.FillUpTo32()
+	h.MerkleizeWithMixin(_i1, uint64(len(obj.B)), 64)
+	h.PutUint8(obj.C)
+	h.Merkleize(_i0)
+	return nil
+}
+
+func (obj *VarTestStruct) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return ssz.Prove(obj, gindex)
+}
+
+const (
+	GIndexVarTestStructA = 4
+	GIndexVarTestStructB = 5
+	GIndexVarTestStructC = 6
+)
+
+func (obj *VarTestStruct) GIndexSSZ(path []ssz.PathElem) (uint64, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	var field ssz.Resolver
+	var gindex uint64
+	switch path[0].Name {
+	case "A":
+		gindex, field = 4, ssz.BasicGIndex
+	case "B":
+		gindex, field = 5, ssz.PackedListGIndex(1024, 16)
+	case "C":
+		gindex, field = 6, ssz.BasicGIndex
+	default:
+		return 0, ssz.ErrInvalidPath
+	}
+	child, err := field(path[1:])
+	if err != nil {
+		return 0, err
+	}
+	return ssz.ConcatGIndex(gindex, child)
+}
@@ -0,0 +1,41 @@
+// Package generic defines the containers of the ssz_generic spec tests.
+package generic
+
+type SingleFieldTestStruct struct {
+	A byte
+}
+
+type SmallTestStruct struct {
+	A uint16
+	B uint16
+}
+
+type FixedTestStruct struct {
+	A uint8
+	B uint64
+	C uint32
+}
+
+type VarTestStruct struct {
+	A uint16
+	B []uint16 `ssz-max:"1024"`
+	C uint8
+}
+
+type ComplexTestStruct struct {
+	A uint16
+	B []uint16 `ssz-max:"128"`
+	C uint8
+	D []byte `ssz-max:"256"`
+	E VarTestStruct
+	F [4]FixedTestStruct
+	G [2]VarTestStruct
+}
+
+type BitsStruct struct {
+	A []byte  `ssz:"bitlist" ssz-max:"5"`
+	B [1]byte `ssz:"bitvector" ssz-size:"2"`
+	C [1]byte `ssz:"bitvector" ssz-size:"1"`
+	D []byte  `ssz:"bitlist" ssz-max:"6"`
+	E [1]byte `ssz:"bitvector" ssz-size:"8"`
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/spectests/generic"
+	"github.com/rjl493456442/sszgen/ssz"
+	"github.com/rjl493456442/sszgen/ssz/snappy"
+)
+
+// containers are the container types referenced by the ssz_generic cases.
+var containers = map[string]func() object{
+	"SingleFieldTestStruct": func() object { return new(generic.SingleFieldTestStruct) },
+	"SmallTestStruct":       func() object { return new(generic.SmallTestStruct) },
+	"FixedTestStruct":       func() object { return new(generic.FixedTestStruct) },
+	"VarTestStruct":         func() object { return new(generic.VarTestStruct) },
+	"ComplexTestStruct":     func() object { return new(generic.ComplexTestStruct) },
+	"BitsStruct":            func() object { return new(generic.BitsStruct) },
+}
+
+// errZeroLength is returned if the type of the case is a vector with zero
+// length, which is invalid itself and rejects any input.
+var errZeroLength = errors.New("vector with zero length")
+
+// basicObject adapts the decoders and encoders of the ssz package for the
+// values other than the containers into an object.
+type basicObject struct {
+	decode func(s *ssz.Stream) error
+	encode func(w []byte) []byte
+	hash   func(h *ssz.Hasher)
+}
+
+func (o *basicObject) UnmarshalSSZ(s *ssz.Stream) error { return o.decode(s) }
+
+func (o *basicObject) SizeSSZ() int { return len(o.encode(nil)) }
+
+func (o *basicObject) MarshalSSZ() ([]byte, error) { return o.encode(nil), nil }
+
+func (o *basicObject) MarshalSSZTo(buf []byte) ([]byte, error) { return o.encode(buf), nil }
+
+func (o *basicObject) HashTreeRoot() ([32]byte, error) {
+	h := ssz.GetHasher()
+	defer ssz.PutHasher(h)
+	o.hash(h)
+	return h.HashRoot()
+}
+
+func newBool() object {
+	var v bool
+	return &basicObject{
+		decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeBool(s); return err },
+		encode: func(w []byte) []byte { return ssz.EncodeBool(w, v) },
+		hash:   func(h *ssz.Hasher) { h.PutBool(v) },
+	}
+}
+
+// newUint returns the unsigned integer with the given bits, or nil if it's
+// not supported.
+func newUint(bits int) object {
+	switch bits {
+	case 8:
+		var v uint8
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeByte(s); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeByte(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutUint8(v) },
+		}
+	case 16:
+		var v uint16
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint16(s); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint16(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutUint16(v) },
+		}
+	case 32:
+		var v uint32
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint32(s); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint32(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutUint32(v) },
+		}
+	case 64:
+		var v uint64
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint64(s); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint64(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutUint64(v) },
+		}
+	case 128, 256:
+		// The big integers are kept in the little-endian encoding, which is
+		// the same as their chunk.
+		var v []byte
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeBytes(s, bits/8); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeBytes(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutBytes(v) },
+		}
+	}
+	return nil
+}
+
+// newBasicVector returns the vector of n basic items, or nil if the item type
+// is not supported.
+func newBasicVector(elem string, n int) object {
+	switch elem {
+	case "bool":
+		var v []bool
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeBools(s, n); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeBools(w, v) },
+			hash: func(h *ssz.Hasher) {
+				indx := h.Index()
+				for _, b := range v {
+					h.AppendBool(b)
+				}
+				h.FillUpTo32()
+				h.Merkleize(indx)
+			},
+		}
+	case "uint8":
+		var v []byte
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeBytes(s, n); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeBytes(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutBytes(v) },
+		}
+	case "uint16":
+		var v []uint16
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint16s(s, n); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint16s(w, v) },
+			hash: func(h *ssz.Hasher) {
+				indx := h.Index()
+				for _, i := range v {
+					h.AppendUint16(i)
+				}
+				h.FillUpTo32()
+				h.Merkleize(indx)
+			},
+		}
+	case "uint32":
+		var v []uint32
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint32s(s, n); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint32s(w, v) },
+			hash: func(h *ssz.Hasher) {
+				indx := h.Index()
+				for _, i := range v {
+					h.AppendUint32(i)
+				}
+				h.FillUpTo32()
+				h.Merkleize(indx)
+			},
+		}
+	case "uint64":
+		var v []uint64
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeUint64s(s, n); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeUint64s(w, v) },
+			hash: func(h *ssz.Hasher) {
+				indx := h.Index()
+				for _, i := range v {
+					h.AppendUint64(i)
+				}
+				h.FillUpTo32()
+				h.Merkleize(indx)
+			},
+		}
+	case "uint128", "uint256":
+		// The items are packed in the little-endian encoding, so the vector
+		// hashes the same as the bytes.
+		var (
+			v    []byte
+			size = 16
+		)
+		if elem == "uint256" {
+			size = 32
+		}
+		return &basicObject{
+			decode: func(s *ssz.Stream) (err error) { v, err = ssz.DecodeBytes(s, n*size); return err },
+			encode: func(w []byte) []byte { return ssz.EncodeBytes(w, v) },
+			hash:   func(h *ssz.Hasher) { h.PutBytes(v) },
+		}
+	}
+	return nil
+}
+
+func newBitvector(n int) object {
+	var v []byte
+	return &basicObject{
+		decode: func(s *ssz.Stream) (err error) {
+			if v, err = ssz.DecodeBytes(s, (n+7)/8); err != nil {
+				return err
+			}
+			return ssz.ValidateBitvector(v, uint64(n))
+		},
+		encode: func(w []byte) []byte { return ssz.EncodeBytes(w, v) },
+		hash:   func(h *ssz.Hasher) { h.PutBytes(v) },
+	}
+}
+
+func newBitlist(limit int) object {
+	var v []byte
+	return &basicObject{
+		decode: func(s *ssz.Stream) (err error) {
+			if v, err = ssz.DecodeBytes(s, 0); err != nil {
+				return err
+			}
+			return ssz.ValidateBitlist(v, uint64(limit))
+		},
+		encode: func(w []byte) []byte { return ssz.EncodeBytes(w, v) },
+		hash:   func(h *ssz.Hasher) { h.PutBitlist(v, uint64(limit)) },
+	}
+}
+
+// genericType resolves the type of the case from its name, e.g. uint_16_max
+// or vec_uint16_513_random. It returns errZeroLength if the type itself is
+// invalid, or any other error if the type can't be resolved.
+func genericType(handler string, name string) (func() object, error) {
+	parts := strings.Split(name, "_")
+	size := func(i int) (int, error) {
+		if i >= len(parts) {
+			return 0, fmt.Errorf("no size in case %s", name)
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid size in case %s", name)
+		}
+		return n, nil
+	}
+	switch handler {
+	case "boolean":
+		return newBool, nil
+	case "uints":
+		bits, err := size(1)
+		if err != nil {
+			return nil, err
+		}
+		if newUint(bits) == nil {
+			return nil, fmt.Errorf("unsupported uint in case %s", name)
+		}
+		return func() object { return newUint(bits) }, nil
+	case "basic_vector":
+		n, err := size(2)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, errZeroLength
+		}
+		if newBasicVector(parts[1], n) == nil {
+			return nil, fmt.Errorf("unsupported vector in case %s", name)
+		}
+		return func() object { return newBasicVector(parts[1], n) }, nil
+	case "bitvector":
+		n, err := size(1)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, errZeroLength
+		}
+		return func() object { return newBitvector(n) }, nil
+	case "bitlist":
+		// The limit is absent in the cases of malformed bitlists, which are
+		// checked without limit.
+		limit, err := size(1)
+		if err != nil {
+			limit = 0
+		}
+		return func() object { return newBitlist(limit) }, nil
+	case "containers":
+		fn, ok := containers[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown container in case %s", name)
+		}
+		return fn, nil
+	}
+	return nil, fmt.Errorf("unknown handler %s", handler)
+}
+
+// runGeneric runs the ssz_generic cases under the directory, which are located
+// at ssz_generic/<handler>/<valid|invalid>/<case>.
+func runGeneric(args []string) error {
+	dir, verbose := parseFlags("generic", args)
+
+	rep := newReport([]string{"valid", "invalid"})
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != "ssz_generic" {
+			return nil
+		}
+		cases, err := filepath.Glob(filepath.Join(path, "*", "*", "*"))
+		if err != nil {
+			return err
+		}
+		for _, c := range cases {
+			var (
+				kind    = filepath.Base(filepath.Dir(c))
+				handler = filepath.Base(filepath.Dir(filepath.Dir(c)))
+			)
+			fn, err := genericType(handler, filepath.Base(c))
+			switch {
+			case errors.Is(err, errZeroLength) && kind == "invalid":
+				err = nil // the invalid types reject any input
+			case err != nil:
+				err = fmt.Errorf("failed to resolve type: %v", err)
+			case kind == "valid":
+				err = runValidCase(c, fn())
+			case kind == "invalid":
+				err = runInvalidCase(c, fn())
+			default:
+				continue
+			}
+			if err != nil && verbose {
+				fmt.Printf("FAIL %s: %v\n", c, err)
+			}
+			rep.add(handler, kind, err)
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return err
+	}
+	rep.print(os.Stdout)
+	if n := rep.failed(); n != 0 {
+		return fmt.Errorf("%d cases failed", n)
+	}
+	return nil
+}
+
+// runValidCase decodes the serialized value in the case, and checks that it
+// encodes back into the same bytes and hashes into the expected root.
+func runValidCase(dir string, obj object) error {
+	blob, err := readSerialized(dir)
+	if err != nil {
+		return err
+	}
+	want, err := readRoot(filepath.Join(dir, "meta.yaml"))
+	if err != nil {
+		return err
+	}
+	if err := ssz.Unmarshal(blob, obj); err != nil {
+		return fmt.Errorf("failed to decode: %v", err)
+	}
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to encode: %v", err)
+	}
+	if !bytes.Equal(enc, blob) {
+		return errors.New("re-encoding mismatch")
+	}
+	root, err := obj.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("failed to hash: %v", err)
+	}
+	if root != want {
+		return fmt.Errorf("root mismatch, want: %x, got: %x", want, root)
+	}
+	return nil
+}
+
+// runInvalidCase checks that the serialized value in the case is rejected.
+func runInvalidCase(dir string, obj object) error {
+	blob, err := readSerialized(dir)
+	if err != nil {
+		return err
+	}
+	if err := ssz.Unmarshal(blob, obj); err == nil {
+		return errors.New("invalid input accepted")
+	}
+	return nil
+}
+
+// readSerialized reads the snappy compressed value of the case.
+func readSerialized(dir string) ([]byte, error) {
+	compressed, err := os.ReadFile(filepath.Join(dir, "serialized.ssz_snappy"))
+	if err != nil {
+		return nil, err
+	}
+	return snappy.DecompressBlock(compressed, math.MaxUint32)
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 sszgen authors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// The runner checks the generated bindings in spectests against the extracted
+// consensus-spec-tests fixtures.
+//
+//	go run ./spectests/runner static -dir consensus-spec-tests/tests/mainnet
+//	go run ./spectests/runner generic -dir consensus-spec-tests/tests/general
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var commands = map[string]func(args []string) error{
+	"static":  runStatic,
+	"generic": runGeneric,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: runner <static|generic> [flags]")
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the common flags of the commands.
+func parseFlags(name string, args []string) (dir string, verbose bool) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&dir, "dir", ".", "directory of the extracted spec tests")
+	fs.BoolVar(&verbose, "v", false, "print the failed cases")
+	fs.Parse(args)
+	return dir, verbose
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// result is the outcome of the cases in a cell of the report.
+type result struct {
+	total   int
+	failed  int
+	skipped int // number of the cases that can't be run
+}
+
+// report collects the case results in a matrix of rows by columns, e.g. the
+// types by the forks.
+type report struct {
+	cells map[[2]string]*result
+	rows  map[string]bool
+	cols  []string
+	order []string // preferred order of the columns, the others are sorted last
+}
+
+func newReport(order []string) *report {
+	return &report{
+		cells: make(map[[2]string]*result),
+		rows:  make(map[string]bool),
+		order: order,
+	}
+}
+
+// rank returns the position of the column in the preferred order.
+func (r *report) rank(col string) int {
+	for i, c := range r.order {
+		if c == col {
+			return i
+		}
+	}
+	return len(r.order)
+}
+
+func (r *report) cell(row, col string) *result {
+	key := [2]string{row, col}
+	if r.cells[key] == nil {
+		r.cells[key] = new(result)
+		r.rows[row] = true
+
+		var known bool
+		for _, c := range r.cols {
+			known = known || c == col
+		}
+		if !known {
+			r.cols = append(r.cols, col)
+		}
+	}
+	return r.cells[key]
+}
+
+// add records the outcome of a single case.
+func (r *report) add(row, col string, err error) {
+	res := r.cell(row, col)
+	res.total += 1
+	if err != nil {
+		res.failed += 1
+	}
+}
+
+// skip records a case that can't be run.
+func (r *report) skip(row, col string) {
+	r.cell(row, col).skipped += 1
+}
+
+// failed returns the number of failed cases.
+func (r *report) failed() int {
+	var n int
+	for _, res := range r.cells {
+		n += res.failed
+	}
+	return n
+}
+
+// print writes the matrix, with the passed and total number of cases in the
+// cells along with the skipped ones, "skip" for the cells with all the cases
+// skipped and "-" for the absent ones.
+func (r *report) print(w io.Writer) {
+	var rows []string
+	for row := range r.rows {
+		rows = append(rows, row)
+	}
+	sort.Strings(rows)
+	sort.SliceStable(r.cols, func(i, j int) bool {
+		if ri, rj := r.rank(r.cols[i]), r.rank(r.cols[j]); ri != rj {
+			return ri < rj
+		}
+		return r.cols[i] < r.cols[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, col := range r.cols {
+		fmt.Fprintf(tw, "\t%s", col)
+	}
+	fmt.Fprintln(tw)
+	for _, row := range rows {
+		fmt.Fprint(tw, row)
+		for _, col := range r.cols {
+			res := r.cells[[2]string{row, col}]
+			switch {
+			case res == nil:
+				fmt.Fprint(tw, "\t-")
+			case res.total == 0:
+				fmt.Fprint(tw, "\tskip")
+			case res.failed != 0:
+				fmt.Fprintf(tw, "\tFAIL %d/%d", res.total-res.failed, res.total)
+			default:
+				fmt.Fprintf(tw, "\t%d/%d", res.total, res.total)
+			}
+			if res != nil && res.total != 0 && res.skipped != 0 {
+				fmt.Fprintf(tw, " (%d skipped)", res.skipped)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
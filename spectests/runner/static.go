@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// object is the method set of the generated bindings used by the runner.
+type object interface {
+	ssz.Encoder
+	ssz.Decoder
+	HashTreeRoot() ([32]byte, error)
+}
+
+// forks lists the supported forks in order, along with the types that were
+// introduced or changed in the fork.
+var forks = []struct {
+	name  string
+	types map[string]func() object
+}{
+	{"phase0", map[string]func() object{
+		"AggregateAndProof":       func() object { return new(spectests.AggregateAndProof) },
+		"Attestation":             func() object { return new(spectests.Attestation) },
+		"AttestationData":         func() object { return new(spectests.AttestationData) },
+		"AttesterSlashing":        func() object { return new(spectests.AttesterSlashing) },
+		"BeaconBlock":             func() object { return new(spectests.BeaconBlock) },
+		"BeaconBlockBody":         func() object { return new(spectests.BeaconBlockBodyPhase0) },
+		"BeaconBlockHeader":       func() object { return new(spectests.BeaconBlockHeader) },
+		"BeaconState":             func() object { return ssz.AtFork(new(spectests.BeaconState), ssz.ForkPhase0) },
+		"Checkpoint":              func() object { return new(spectests.Checkpoint) },
+		"Deposit":                 func() object { return new(spectests.Deposit) },
+		"DepositData":             func() object { return new(spectests.DepositData) },
+		"DepositMessage":          func() object { return new(spectests.DepositMessage) },
+		"Eth1Block":               func() object { return new(spectests.Eth1Block) },
+		"Eth1Data":                func() object { return new(spectests.Eth1Data) },
+		"Fork":                    func() object { return new(spectests.Fork) },
+		"HistoricalBatch":         func() object { return new(spectests.HistoricalBatch) },
+		"IndexedAttestation":      func() object { return new(spectests.IndexedAttestation) },
+		"PendingAttestation":      func() object { return new(spectests.PendingAttestation) },
+		"ProposerSlashing":        func() object { return new(spectests.ProposerSlashing) },
+		"SignedBeaconBlock":       func() object { return new(spectests.SignedBeaconBlock) },
+		"SignedBeaconBlockHeader": func() object { return new(spectests.SignedBeaconBlockHeader) },
+		"SignedVoluntaryExit":     func() object { return new(spectests.SignedVoluntaryExit) },
+		"Validator":               func() object { return new(spectests.Validator) },
+		"VoluntaryExit":           func() object { return new(spectests.VoluntaryExit) },
+	}},
+	{"altair", map[string]func() object{
+		"BeaconBlock":       func() object { return new(spectests.BeaconBlockAltair) },
+		"BeaconBlockBody":   func() object { return new(spectests.BeaconBlockBodyAltair) },
+		"BeaconState":       func() object { return ssz.AtFork(new(spectests.BeaconState), ssz.ForkAltair) },
+		"SignedBeaconBlock": func() object { return new(spectests.SignedBeaconBlockAltair) },
+		"SyncAggregate":     func() object { return new(spectests.SyncAggregate) },
+		"SyncCommittee":     func() object { return new(spectests.SyncCommittee) },
+	}},
+	{"bellatrix", map[string]func() object{
+		"BeaconBlock":            func() object { return new(spectests.BeaconBlockBellatrix) },
+		"BeaconBlockBody":        func() object { return new(spectests.BeaconBlockBodyBellatrix) },
+		"BeaconState":            func() object { return ssz.AtFork(new(spectests.BeaconState), ssz.ForkBellatrix) },
+		"ExecutionPayload":       func() object { return new(spectests.ExecutionPayload) },
+		"ExecutionPayloadHeader": func() object { return new(spectests.ExecutionPayloadHeader) },
+		"SignedBeaconBlock":      func() object { return new(spectests.SignedBeaconBlockBellatrix) },
+	}},
+	{"capella", map[string]func() object{
+		"BLSToExecutionChange":       func() object { return new(spectests.BLSToExecutionChange) },
+		"BeaconBlock":                func() object { return new(spectests.BeaconBlockCapella) },
+		"BeaconBlockBody":            func() object { return new(spectests.BeaconBlockBodyCapella) },
+		"BeaconState":                func() object { return ssz.AtFork(new(spectests.BeaconState), ssz.ForkCapella) },
+		"ExecutionPayload":           func() object { return new(spectests.ExecutionPayloadCapella) },
+		"ExecutionPayloadHeader":     func() object { return new(spectests.ExecutionPayloadHeaderCapella) },
+		"HistoricalSummary":          func() object { return new(spectests.HistoricalSummary) },
+		"SignedBLSToExecutionChange": func() object { return new(spectests.SignedBLSToExecutionChange) },
+		"SignedBeaconBlock":          func() object { return new(spectests.SignedBeaconBlockCapella) },
+		"Withdrawal":                 func() object { return new(spectests.Withdrawal) },
+	}},
+}
+
+// forkTypes returns the types of the forks, with the ones not changed inherited
+// from the previous fork.
+func forkTypes() map[string]map[string]func() object {
+	var (
+		all  = make(map[string]map[string]func() object)
+		last = make(map[string]func() object)
+	)
+	for _, fork := range forks {
+		types := make(map[string]func() object)
+		for name, fn := range last {
+			types[name] = fn
+		}
+		for name, fn := range fork.types {
+			types[name] = fn
+		}
+		all[fork.name], last = types, types
+	}
+	return all
+}
+
+// runStatic runs the ssz_static cases under the directory, which are located
+// at <fork>/ssz_static/<type>/<suite>/<case>.
+func runStatic(args []string) error {
+	dir, verbose := parseFlags("static", args)
+
+	var (
+		types = forkTypes()
+		order []string
+	)
+	for _, fork := range forks {
+		order = append(order, fork.name)
+	}
+	rep := newReport(order)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != "ssz_static" {
+			return nil
+		}
+		fork := filepath.Base(filepath.Dir(path))
+		cases, err := filepath.Glob(filepath.Join(path, "*", "*", "*"))
+		if err != nil {
+			return err
+		}
+		for _, c := range cases {
+			name := filepath.Base(filepath.Dir(filepath.Dir(c)))
+			fn := types[fork][name]
+			if fn == nil {
+				rep.skip(name, fork)
+				continue
+			}
+			err := runStaticCase(c, fn())
+			if err != nil && verbose {
+				fmt.Printf("FAIL %s: %v\n", c, err)
+			}
+			rep.add(name, fork, err)
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return err
+	}
+	rep.print(os.Stdout)
+	if n := rep.failed(); n != 0 {
+		return fmt.Errorf("%d cases failed", n)
+	}
+	return nil
+}
+
+// runStaticCase decodes the serialized object in the case, and checks that it
+// encodes back into the same bytes and hashes into the expected root.
+func runStaticCase(dir string, obj object) error {
+	blob, err := readSerialized(dir)
+	if err != nil {
+		return err
+	}
+	want, err := readRoot(filepath.Join(dir, "roots.yaml"))
+	if err != nil {
+		return err
+	}
+	if err := ssz.Unmarshal(blob, obj); err != nil {
+		return fmt.Errorf("failed to decode: %v", err)
+	}
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to encode: %v", err)
+	}
+	if !bytes.Equal(enc, blob) {
+		return errors.New("re-encoding mismatch")
+	}
+	root, err := obj.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("failed to hash: %v", err)
+	}
+	if root != want {
+		return fmt.Errorf("root mismatch, want: %x, got: %x", want, root)
+	}
+	return nil
+}
+
+// readRoot reads the root from the roots.yaml, which is in the form of
+// `{root: '0x...'}`.
+func readRoot(path string) ([32]byte, error) {
+	var root [32]byte
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return root, err
+	}
+	text := strings.Trim(strings.TrimSpace(string(content)), "{}")
+	key, value, ok := strings.Cut(text, ":")
+	if !ok || strings.TrimSpace(key) != "root" {
+		return root, fmt.Errorf("invalid roots file %s", path)
+	}
+	value = strings.Trim(strings.TrimSpace(value), "'\"")
+	dec, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(dec) != len(root) {
+		return root, fmt.Errorf("invalid root in %s", path)
+	}
+	copy(root[:], dec)
+	return root, nil
+}
@@ -1,5 +1,13 @@
 package spectests
 
+import "github.com/rjl493456442/sszgen/ssz"
+
+//sszgen:fork BeaconBlockBody phase0=BeaconBlockBodyPhase0 altair=BeaconBlockBodyAltair bellatrix=BeaconBlockBodyBellatrix capella=BeaconBlockBodyCapella
+//sszgen:fork BeaconState phase0 altair bellatrix capella
+//sszgen:gindex BeaconState FinalizedRoot=finalized_checkpoint.root
+//sszgen:each BeaconState Validators Balances RandaoMixes Eth1DataVotes InactivityScores
+//sszgen:each ExecutionPayloadCapella Transactions
+
 type AggregateAndProof struct {
 	Index          uint64       `json:"aggregator_index"`
 	Aggregate      *Attestation `json:"aggregate"`
@@ -145,7 +153,48 @@ type Transfer struct {
 	Signature []byte `json:"signature" ssz-size:"96"`
 }
 
+// BeaconState is the beacon state of the forks up to capella, with the fields
+// gated by the forks introducing or removing them. The trees of the large
+// fields are cached for rehashing the state.
 type BeaconState struct {
+	GenesisTime                         uint64                         `json:"genesis_time"`
+	GenesisValidatorsRoot               []byte                         `json:"genesis_validators_root" ssz-size:"32"`
+	Slot                                uint64                         `json:"slot"`
+	Fork                                *Fork                          `json:"fork"`
+	LatestBlockHeader                   *BeaconBlockHeader             `json:"latest_block_header"`
+	BlockRoots                          [][]byte                       `json:"block_roots" ssz-size:"8192,32"`
+	StateRoots                          [][]byte                       `json:"state_roots" ssz-size:"8192,32"`
+	HistoricalRoots                     [][]byte                       `json:"historical_roots" ssz-max:"16777216" ssz-size:"?,32"`
+	Eth1Data                            *Eth1Data                      `json:"eth1_data"`
+	Eth1DataVotes                       []*Eth1Data                    `json:"eth1_data_votes" ssz-max:"2048"`
+	Eth1DepositIndex                    uint64                         `json:"eth1_deposit_index"`
+	Validators                          []*Validator                   `json:"validators" ssz-max:"1099511627776"`
+	Balances                            []uint64                       `json:"balances" ssz-max:"1099511627776"`
+	RandaoMixes                         [][]byte                       `json:"randao_mixes" ssz-size:"65536,32"`
+	Slashings                           []uint64                       `json:"slashings" ssz-size:"8192"`
+	PreviousEpochAttestations           []*PendingAttestation          `json:"previous_epoch_attestations" ssz-max:"4096" ssz-fork:"<altair"`
+	CurrentEpochAttestations            []*PendingAttestation          `json:"current_epoch_attestations" ssz-max:"4096" ssz-fork:"<altair"`
+	PreviousEpochParticipation          []byte                         `json:"previous_epoch_participation" ssz-max:"1099511627776" ssz-fork:"altair"`
+	CurrentEpochParticipation           []byte                         `json:"current_epoch_participation" ssz-max:"1099511627776" ssz-fork:"altair"`
+	JustificationBits                   []byte                         `json:"justification_bits" ssz:"bitvector" ssz-size:"4"`
+	PreviousJustifiedCheckpoint         *Checkpoint                    `json:"previous_justified_checkpoint"`
+	CurrentJustifiedCheckpoint          *Checkpoint                    `json:"current_justified_checkpoint"`
+	FinalizedCheckpoint                 *Checkpoint                    `json:"finalized_checkpoint"`
+	InactivityScores                    []uint64                       `json:"inactivity_scores" ssz-max:"1099511627776" ssz-fork:"altair"`
+	CurrentSyncCommittee                *SyncCommittee                 `json:"current_sync_committee" ssz-fork:"altair"`
+	NextSyncCommittee                   *SyncCommittee                 `json:"next_sync_committee" ssz-fork:"altair"`
+	LatestExecutionPayloadHeader        *ExecutionPayloadHeader        `json:"latest_execution_payload_header" ssz-fork:">=bellatrix,<capella"`
+	LatestExecutionPayloadHeaderCapella *ExecutionPayloadHeaderCapella `ssz-fork:"capella" ssz-json:"latest_execution_payload_header"`
+	NextWithdrawalIndex                 uint64                         `json:"next_withdrawal_index" ssz-fork:"capella"`
+	NextWithdrawalValidatorIndex        uint64                         `json:"next_withdrawal_validator_index" ssz-fork:"capella"`
+	HistoricalSummaries                 []*HistoricalSummary           `json:"historical_summaries" ssz-max:"16777216" ssz-fork:"capella"`
+
+	cache ssz.HashCache
+}
+
+// BeaconStatePhase0 is the standalone layout of the phase0 beacon state, which
+// the fork-gated BeaconState is checked against.
+type BeaconStatePhase0 struct {
 	GenesisTime                 uint64                `json:"genesis_time"`
 	GenesisValidatorsRoot       []byte                `json:"genesis_validators_root" ssz-size:"32"`
 	Slot                        uint64                `json:"slot"`
@@ -163,7 +212,7 @@ type BeaconState struct {
 	Slashings                   []uint64              `json:"slashings" ssz-size:"8192"`
 	PreviousEpochAttestations   []*PendingAttestation `json:"previous_epoch_attestations" ssz-max:"4096"`
 	CurrentEpochAttestations    []*PendingAttestation `json:"current_epoch_attestations" ssz-max:"4096"`
-	JustificationBits           []byte                `json:"justification_bits" ssz-size:"1"`
+	JustificationBits           []byte                `json:"justification_bits" ssz:"bitvector" ssz-size:"4"`
 	PreviousJustifiedCheckpoint *Checkpoint           `json:"previous_justified_checkpoint"`
 	CurrentJustifiedCheckpoint  *Checkpoint           `json:"current_justified_checkpoint"`
 	FinalizedCheckpoint         *Checkpoint           `json:"finalized_checkpoint"`
@@ -193,63 +242,42 @@ type BeaconBlockBodyAltair struct {
 }
 
 type BeaconBlockBodyBellatrix struct {
-	BeaconBlockBodyAltair `json:",squash"`
-	ExecutionPayload      *ExecutionPayload `json:"execution_payload"`
-}
-
-type BeaconStateAltair struct {
-	GenesisTime                 uint64             `json:"genesis_time"`
-	GenesisValidatorsRoot       []byte             `json:"genesis_validators_root" ssz-size:"32"`
-	Slot                        uint64             `json:"slot"`
-	Fork                        *Fork              `json:"fork"`
-	LatestBlockHeader           *BeaconBlockHeader `json:"latest_block_header"`
-	BlockRoots                  [][]byte           `json:"block_roots" ssz-size:"8192,32"`
-	StateRoots                  [][]byte           `json:"state_roots" ssz-size:"8192,32"`
-	HistoricalRoots             [][]byte           `json:"historical_roots" ssz-max:"16777216" ssz-size:"?,32"`
-	Eth1Data                    *Eth1Data          `json:"eth1_data"`
-	Eth1DataVotes               []*Eth1Data        `json:"eth1_data_votes" ssz-max:"2048"`
-	Eth1DepositIndex            uint64             `json:"eth1_deposit_index"`
-	Validators                  []*Validator       `json:"validators" ssz-max:"1099511627776"`
-	Balances                    []uint64           `json:"balances" ssz-max:"1099511627776"`
-	RandaoMixes                 [][]byte           `json:"randao_mixes" ssz-size:"65536,32"`
-	Slashings                   []uint64           `json:"slashings" ssz-size:"8192"`
-	PreviousEpochParticipation  []byte             `json:"previous_epoch_participation" ssz-max:"1099511627776"`
-	CurrentEpochParticipation   []byte             `json:"current_epoch_participation" ssz-max:"1099511627776"`
-	JustificationBits           []byte             `json:"justification_bits" cast-type:"github.com/prysmaticlabs/go-bitfield.Bitvector4" ssz-size:"1"`
-	PreviousJustifiedCheckpoint *Checkpoint        `json:"previous_justified_checkpoint"`
-	CurrentJustifiedCheckpoint  *Checkpoint        `json:"current_justified_checkpoint"`
-	FinalizedCheckpoint         *Checkpoint        `json:"finalized_checkpoint"`
-	InactivityScores            []uint64           `json:"inactivity_scores" ssz-max:"1099511627776"`
-	CurrentSyncCommittee        *SyncCommittee     `json:"current_sync_committee"`
-	NextSyncCommittee           *SyncCommittee     `json:"next_sync_committee"`
-}
-
-type BeaconStateBellatrix struct {
-	GenesisTime                  uint64                  `json:"genesis_time"`
-	GenesisValidatorsRoot        []byte                  `json:"genesis_validators_root" ssz-size:"32"`
-	Slot                         uint64                  `json:"slot"`
-	Fork                         *Fork                   `json:"fork"`
-	LatestBlockHeader            *BeaconBlockHeader      `json:"latest_block_header"`
-	BlockRoots                   [][]byte                `json:"block_roots" ssz-size:"8192,32"`
-	StateRoots                   [][]byte                `json:"state_roots" ssz-size:"8192,32"`
-	HistoricalRoots              [][]byte                `json:"historical_roots" ssz-max:"16777216" ssz-size:"?,32"`
-	Eth1Data                     *Eth1Data               `json:"eth1_data"`
-	Eth1DataVotes                []*Eth1Data             `json:"eth1_data_votes" ssz-max:"2048"`
-	Eth1DepositIndex             uint64                  `json:"eth1_deposit_index"`
-	Validators                   []*Validator            `json:"validators" ssz-max:"1099511627776"`
-	Balances                     []uint64                `json:"balances" ssz-max:"1099511627776"`
-	RandaoMixes                  [][]byte                `json:"randao_mixes" ssz-size:"65536,32"`
-	Slashings                    []uint64                `json:"slashings" ssz-size:"8192"`
-	PreviousEpochParticipation   []byte                  `json:"previous_epoch_participation" ssz-max:"1099511627776"`
-	CurrentEpochParticipation    []byte                  `json:"current_epoch_participation" ssz-max:"1099511627776"`
-	JustificationBits            []byte                  `json:"justification_bits" cast-type:"github.com/prysmaticlabs/go-bitfield.Bitvector4" ssz-size:"1"`
-	PreviousJustifiedCheckpoint  *Checkpoint             `json:"previous_justified_checkpoint"`
-	CurrentJustifiedCheckpoint   *Checkpoint             `json:"current_justified_checkpoint"`
-	FinalizedCheckpoint          *Checkpoint             `json:"finalized_checkpoint"`
-	InactivityScores             []uint64                `json:"inactivity_scores" ssz-max:"1099511627776"`
-	CurrentSyncCommittee         *SyncCommittee          `json:"current_sync_committee"`
-	NextSyncCommittee            *SyncCommittee          `json:"next_sync_committee"`
-	LatestExecutionPayloadHeader *ExecutionPayloadHeader `json:"latest_execution_payload_header"`
+	RandaoReveal      []byte                 `json:"randao_reveal" ssz-size:"96"`
+	Eth1Data          *Eth1Data              `json:"eth1_data"`
+	Graffiti          [32]byte               `json:"graffiti" ssz-size:"32"`
+	ProposerSlashings []*ProposerSlashing    `json:"proposer_slashings" ssz-max:"16"`
+	AttesterSlashings []*AttesterSlashing    `json:"attester_slashings" ssz-max:"2"`
+	Attestations      []*Attestation         `json:"attestations" ssz-max:"128"`
+	Deposits          []*Deposit             `json:"deposits" ssz-max:"16"`
+	VoluntaryExits    []*SignedVoluntaryExit `json:"voluntary_exits" ssz-max:"16"`
+	SyncAggregate     *SyncAggregate         `json:"sync_aggregate"`
+	ExecutionPayload  *ExecutionPayload      `json:"execution_payload"`
+}
+
+type SignedBeaconBlockAltair struct {
+	Block     *BeaconBlockAltair `json:"message"`
+	Signature []byte             `json:"signature" ssz-size:"96"`
+}
+
+type BeaconBlockAltair struct {
+	Slot          uint64                 `json:"slot"`
+	ProposerIndex uint64                 `json:"proposer_index"`
+	ParentRoot    [32]byte               `json:"parent_root" ssz-size:"32"`
+	StateRoot     [32]byte               `json:"state_root" ssz-size:"32"`
+	Body          *BeaconBlockBodyAltair `json:"body"`
+}
+
+type SignedBeaconBlockBellatrix struct {
+	Block     *BeaconBlockBellatrix `json:"message"`
+	Signature []byte                `json:"signature" ssz-size:"96"`
+}
+
+type BeaconBlockBellatrix struct {
+	Slot          uint64                    `json:"slot"`
+	ProposerIndex uint64                    `json:"proposer_index"`
+	ParentRoot    [32]byte                  `json:"parent_root" ssz-size:"32"`
+	StateRoot     [32]byte                  `json:"state_root" ssz-size:"32"`
+	Body          *BeaconBlockBodyBellatrix `json:"body"`
 }
 
 type SignedBeaconBlockHeader struct {
@@ -275,7 +303,7 @@ type SyncCommittee struct {
 }
 
 type SyncAggregate struct {
-	SyncCommiteeBits      []byte   `json:"sync_committee_bits" ssz-size:"64"`
+	SyncCommiteeBits      []byte   `json:"sync_committee_bits" ssz:"bitvector" ssz-size:"512"`
 	SyncCommiteeSignature [96]byte `json:"sync_committee_signature" ssz-size:"96"`
 }
 
@@ -293,7 +321,7 @@ type ExecutionPayload struct {
 	GasUsed       uint64    `json:"gas_used"`
 	Timestamp     uint64    `json:"timestamp"`
 	ExtraData     []byte    `ssz-max:"32" json:"extra_data"`
-	BaseFeePerGas [32]byte  `ssz-size:"32" json:"base_fee_per_gas"`
+	BaseFeePerGas [32]byte  `ssz:"uint256" ssz-size:"32" json:"base_fee_per_gas"`
 	BlockHash     [32]byte  `ssz-size:"32" json:"block_hash"`
 	Transactions  [][]byte  `ssz-max:"1048576,1073741824" ssz-size:"?,?" json:"transactions"`
 }
@@ -310,7 +338,7 @@ type ExecutionPayloadHeader struct {
 	GasUsed          uint64 `json:"gas_used"`
 	Timestamp        uint64 `json:"timestamp"`
 	ExtraData        []byte `json:"extra_data" ssz-max:"32"`
-	BaseFeePerGas    []byte `json:"base_fee_per_gas" ssz-size:"32"`
+	BaseFeePerGas    []byte `json:"base_fee_per_gas" ssz:"uint256" ssz-size:"32"`
 	BlockHash        []byte `json:"block_hash" ssz-size:"32"`
 	TransactionsRoot []byte `json:"transactions_root" ssz-size:"32"`
 }
@@ -331,7 +359,7 @@ type ExecutionPayloadCapella struct {
 	GasUsed       uint64        `json:"gas_used"`
 	Timestamp     uint64        `json:"timestamp"`
 	ExtraData     []byte        `ssz-max:"32" json:"extra_data"`
-	BaseFeePerGas Uint256       `ssz-size:"32" json:"base_fee_per_gas"`
+	BaseFeePerGas Uint256       `ssz:"uint256" ssz-size:"32" json:"base_fee_per_gas"`
 	BlockHash     [32]byte      `ssz-size:"32" json:"block_hash"`
 	Transactions  [][]byte      `ssz-max:"1048576,1073741824" ssz-size:"?,?" json:"transactions"`
 	Withdrawals   []*Withdrawal `json:"withdrawals" ssz-max:"16"`
@@ -349,7 +377,7 @@ type ExecutionPayloadHeaderCapella struct {
 	GasUsed          uint64    `json:"gas_used"`
 	Timestamp        uint64    `json:"timestamp"`
 	ExtraData        []byte    `json:"extra_data" ssz-max:"32"`
-	BaseFeePerGas    Uint256   `json:"base_fee_per_gas" ssz-size:"32"`
+	BaseFeePerGas    Uint256   `json:"base_fee_per_gas" ssz:"uint256" ssz-size:"32"`
 	BlockHash        [32]byte  `json:"block_hash" ssz-size:"32"`
 	TransactionsRoot [32]byte  `json:"transactions_root" ssz-size:"32"`
 	WithdrawalRoot   [32]byte  `json:"withdrawals_root" ssz-size:"32"`
@@ -378,37 +406,6 @@ type Withdrawal struct {
 	Amount         uint64   `json:"amount"`
 }
 
-type BeaconStateCapella struct {
-	GenesisTime                  uint64                         `json:"genesis_time"`
-	GenesisValidatorsRoot        [32]byte                       `json:"genesis_validators_root" ssz-size:"32"`
-	Slot                         uint64                         `json:"slot"`
-	Fork                         *Fork                          `json:"fork"`
-	LatestBlockHeader            *BeaconBlockHeader             `json:"latest_block_header"`
-	BlockRoots                   [8192][32]byte                 `json:"block_roots" ssz-size:"8192,32"`
-	StateRoots                   [8192][32]byte                 `json:"state_roots" ssz-size:"8192,32"`
-	HistoricalRoots              [][]byte                       `json:"historical_roots" ssz-max:"16777216" ssz-size:"?,32"`
-	Eth1Data                     *Eth1Data                      `json:"eth1_data"`
-	Eth1DataVotes                []*Eth1Data                    `json:"eth1_data_votes" ssz-max:"2048"`
-	Eth1DepositIndex             uint64                         `json:"eth1_deposit_index"`
-	Validators                   []*Validator                   `json:"validators" ssz-max:"1099511627776"`
-	Balances                     []uint64                       `json:"balances" ssz-max:"1099511627776"`
-	RandaoMixes                  [65536][32]byte                `json:"randao_mixes" ssz-size:"65536,32"`
-	Slashings                    []uint64                       `json:"slashings" ssz-size:"8192"`
-	PreviousEpochParticipation   []byte                         `json:"previous_epoch_participation" ssz-max:"1099511627776"`
-	CurrentEpochParticipation    []byte                         `json:"current_epoch_participation" ssz-max:"1099511627776"`
-	JustificationBits            [1]byte                        `json:"justification_bits" ssz-size:"1"`
-	PreviousJustifiedCheckpoint  *Checkpoint                    `json:"previous_justified_checkpoint"`
-	CurrentJustifiedCheckpoint   *Checkpoint                    `json:"current_justified_checkpoint"`
-	FinalizedCheckpoint          *Checkpoint                    `json:"finalized_checkpoint"`
-	InactivityScores             []uint64                       `json:"inactivity_scores" ssz-max:"1099511627776"`
-	CurrentSyncCommittee         *SyncCommittee                 `json:"current_sync_committee"`
-	NextSyncCommittee            *SyncCommittee                 `json:"next_sync_committee"`
-	LatestExecutionPayloadHeader *ExecutionPayloadHeaderCapella `json:"latest_execution_payload_header"`
-	NextWithdrawalIndex          uint64                         `json:"next_withdrawal_index"`
-	NextWithdrawalValidatorIndex uint64                         `json:"next_withdrawal_validator_index"`
-	HistoricalSummaries          []*HistoricalSummary           `json:"historical_summaries" ssz-max:"16777216"`
-}
-
 type SignedBeaconBlockCapella struct {
 	Block     *BeaconBlockCapella `json:"message"`
 	Signature []byte              `json:"signature" ssz-size:"96"`
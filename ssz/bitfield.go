@@ -0,0 +1,50 @@
+package ssz
+
+import (
+	"errors"
+	"math/bits"
+)
+
+var (
+	ErrBitlistNotTerminated = errors.New("ssz: bitlist is not terminated by the delimiter bit")
+	ErrBitlistTooBig        = errors.New("ssz: bitlist exceeds the maximum length")
+	ErrBitvectorPadding     = errors.New("ssz: bitvector has bits set beyond its length")
+)
+
+// BitlistLen returns the number of bits in the bitlist, excluding the trailing
+// delimiter bit. The bitlist is assumed to be well-formed.
+func BitlistLen(b []byte) uint64 {
+	if len(b) == 0 {
+		return 0
+	}
+	last := b[len(b)-1]
+	if last == 0 {
+		return 0
+	}
+	return uint64(len(b)-1)*BitsPerByte + uint64(bits.Len8(last)) - 1
+}
+
+// ValidateBitlist checks that the bitlist is terminated by the delimiter bit in
+// its last byte and holds at most limit bits. Zero limit means no limit.
+func ValidateBitlist(b []byte, limit uint64) error {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		return ErrBitlistNotTerminated
+	}
+	if limit != 0 && BitlistLen(b) > limit {
+		return ErrBitlistTooBig
+	}
+	return nil
+}
+
+// ValidateBitvector checks that the bits of the bitvector beyond its length n
+// are all zero. The byte length is checked by the callers, as the empty ones
+// are regarded as the zero value.
+func ValidateBitvector(b []byte, n uint64) error {
+	if len(b) == 0 || n%BitsPerByte == 0 {
+		return nil
+	}
+	if b[len(b)-1]>>(n%BitsPerByte) != 0 {
+		return ErrBitvectorPadding
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package ssz
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"unsafe"
+)
+
+var ErrUnknownField = errors.New("ssz: unknown field")
+
+// HashCache caches the merkle trees of the large list and vector fields of a
+// container, which opts in by declaring a field of this type. The generated
+// hashing rehashes only the items marked dirty along with the branches above
+// them, the others are served from the cache.
+//
+// The items modified in place must be marked dirty through the generated
+// MarkDirty method, otherwise the cached roots are stale. The lists replaced
+// by another slice are told by the backing array and rehashed in full. The
+// cache is reset by the generated decoding.
+//
+// Hashing the container is safe for concurrent use, the hashing finding the
+// cache in use by another one rehashes the fields in full. Marking the items
+// dirty and modifying the container are not.
+type HashCache struct {
+	fields []*fieldCache // trees of the fields by the field position in the struct
+	busy   int32         // whether the trees are in use by a hashing
+}
+
+// fieldCache is the merkle tree of a field. The layers are stored from the leaf
+// chunks up to the root, with the missing nodes on the right being the zero
+// subtrees.
+type fieldCache struct {
+	layers   [][][32]byte
+	data     unsafe.Pointer // backing array of the list hashed last time
+	depth    int            // depth of the tree, excluding the length mixin
+	perChunk int            // number of items packed into a chunk
+	limit    uint64         // limit of the list in chunks, zero for vectors
+	items    int            // number of items hashed last time
+	all      bool           // whether all the chunks are to be hashed
+	dirty    map[int]struct{}
+}
+
+// ItemsHasher appends the chunk of the items in the range [from, to) into the
+// hasher, which is the root of the item or the packed basic items.
+type ItemsHasher func(h *Hasher, from, to int) error
+
+// Reset drops all the cached trees.
+func (c *HashCache) Reset() {
+	*c = HashCache{}
+}
+
+// SliceData returns the backing array of the slice, which tells the hash cache
+// whether the list field was replaced since the last time.
+func SliceData[T any](s []T) unsafe.Pointer {
+	return unsafe.Pointer(unsafe.SliceData(s))
+}
+
+// MarkDirty marks the item at the index of the field as modified. A negative
+// index marks the whole field, e.g. after the field was replaced. The items
+// appended or removed are tracked by the length of the field.
+func (c *HashCache) MarkDirty(field int, index int) {
+	if field >= len(c.fields) || c.fields[field] == nil {
+		return
+	}
+	f := c.fields[field]
+	if index < 0 {
+		c.fields[field] = nil
+		return
+	}
+	if index < f.items {
+		f.dirty[index/f.perChunk] = struct{}{}
+	}
+}
+
+// HashVector appends the root of the vector field with n items into the
+// hasher, rehashing only the chunks modified since the last time. The data is
+// the backing array of the field given by SliceData, nil for the arrays.
+func (c *HashCache) HashVector(h *Hasher, field int, data unsafe.Pointer, n int, perChunk int, fn ItemsHasher) error {
+	return c.hash(h, field, data, n, perChunk, 0, fn)
+}
+
+// HashList appends the root of the list field with n items into the hasher,
+// with the length mixed in. The limit is counted in chunks.
+func (c *HashCache) HashList(h *Hasher, field int, data unsafe.Pointer, n int, perChunk int, limit uint64, fn ItemsHasher) error {
+	return c.hash(h, field, data, n, perChunk, limit, fn)
+}
+
+func (c *HashCache) hash(h *Hasher, field int, data unsafe.Pointer, n int, perChunk int, limit uint64, fn ItemsHasher) error {
+	chunks := (n + perChunk - 1) / perChunk
+
+	// Hash all the items if the tree is being built, the nodes of the cached
+	// branches are not kept. The same if the trees are in use by a hashing
+	// of the container on another goroutine.
+	if h.tree || !atomic.CompareAndSwapInt32(&c.busy, 0, 1) {
+		indx := h.Index()
+		for i := 0; i < chunks; i++ {
+			from, to := chunkItems(i, perChunk, n)
+			if err := fn(h, from, to); err != nil {
+				return err
+			}
+		}
+		if limit == 0 {
+			h.Merkleize(indx)
+		} else {
+			h.MerkleizeWithMixin(indx, uint64(n), limit)
+		}
+		return nil
+	}
+	defer atomic.StoreInt32(&c.busy, 0)
+
+	for len(c.fields) <= field {
+		c.fields = append(c.fields, nil)
+	}
+	f := c.fields[field]
+	if f == nil || f.data != data || f.perChunk != perChunk || f.limit != limit || (limit == 0 && f.items != n) {
+		f = &fieldCache{data: data, perChunk: perChunk, limit: limit, all: true}
+		if limit != 0 {
+			f.depth = treeDepth(limit)
+		} else {
+			f.depth = treeDepth(uint64(chunks))
+		}
+		f.layers = make([][][32]byte, f.depth+1)
+		f.dirty = make(map[int]struct{})
+		c.fields[field] = f
+	}
+	if err := f.update(h, n, fn); err != nil {
+		c.fields[field] = nil
+		return err
+	}
+	root := zeroHashes[f.depth]
+	if chunks > 0 {
+		root = f.layers[f.depth][0]
+	}
+	if limit == 0 {
+		h.buf = append(h.buf, root[:]...)
+		return nil
+	}
+	var buf [64]byte
+	copy(buf[:32], root[:])
+	binary.LittleEndian.PutUint64(buf[32:], uint64(n))
+	root = hashPair(buf[:])
+	h.buf = append(h.buf, root[:]...)
+	return nil
+}
+
+// update rehashes the dirty chunks and the branches above them, resizing the
+// tree to the n items. All the chunks are rehashed if the tree is new.
+func (f *fieldCache) update(h *Hasher, n int, fn ItemsHasher) error {
+	var (
+		chunks = (n + f.perChunk - 1) / f.perChunk
+		prev   = len(f.layers[0])
+	)
+	if f.limit != 0 && uint64(chunks) > f.limit {
+		return ErrListTooBig
+	}
+	// The last chunk is changed if the items are appended into it or removed
+	// from it, along with the chunks appended. All of them are appended if
+	// the field was empty.
+	if !f.all && n != f.items && chunks > 0 {
+		last := chunks - 1
+		switch {
+		case prev == 0:
+			last = 0
+		case prev < chunks:
+			last = prev - 1
+		}
+		for i := last; i < chunks; i++ {
+			f.dirty[i] = struct{}{}
+		}
+	}
+	f.items = n
+
+	// Rehash the chunks in place of the hasher and copy them out
+	f.layers[0] = resize(f.layers[0], chunks)
+	hashChunk := func(i int) error {
+		indx := h.Index()
+		from, to := chunkItems(i, f.perChunk, n)
+		if err := fn(h, from, to); err != nil {
+			return err
+		}
+		copy(f.layers[0][i][:], h.buf[indx:])
+		h.buf = h.buf[:indx]
+		return nil
+	}
+	if f.all {
+		indx := h.Index()
+		err := h.hashChunks(chunks, func(h *Hasher, first, last int) error {
+			from, _ := chunkItems(first, f.perChunk, n)
+			_, to := chunkItems(last-1, f.perChunk, n)
+			return fn(h, from, to)
+		})
+		if err != nil {
+			return err
+		}
+		for i := range f.layers[0] {
+			copy(f.layers[0][i][:], h.buf[indx+i*BytesPerChunk:])
+		}
+		h.buf = h.buf[:indx]
+	} else {
+		for i := range f.dirty {
+			if i >= chunks {
+				delete(f.dirty, i)
+				continue
+			}
+			if err := hashChunk(i); err != nil {
+				return err
+			}
+		}
+	}
+	// Rehash the parents of the dirty nodes layer by layer
+	for depth := 0; depth < f.depth; depth++ {
+		layer := f.layers[depth]
+		f.layers[depth+1] = resize(f.layers[depth+1], (len(layer)+1)/2)
+		if f.all {
+			parents := f.layers[depth+1]
+			hashParents := func(from, to int) {
+				for p := from; p < to; p++ {
+					parents[p] = parentOf(layer, p, depth)
+				}
+			}
+			if p := parallel(len(parents)); p != nil {
+				p.run(len(parents), hashParents)
+			} else {
+				hashParents(0, len(parents))
+			}
+			continue
+		}
+		parents := make(map[int]struct{}, len(f.dirty))
+		for i := range f.dirty {
+			parents[i/2] = struct{}{}
+		}
+		for p := range parents {
+			f.layers[depth+1][p] = parentOf(layer, p, depth)
+		}
+		f.dirty = parents
+	}
+	f.all, f.dirty = false, make(map[int]struct{})
+	return nil
+}
+
+// chunkItems returns the range of the n items packed into the chunk i.
+func chunkItems(i int, perChunk int, n int) (int, int) {
+	from, to := i*perChunk, (i+1)*perChunk
+	if to > n {
+		to = n
+	}
+	return from, to
+}
+
+// parentOf returns the parent of the nodes 2p and 2p+1 in the layer at the
+// depth, with the missing right node being the zero subtree.
+func parentOf(layer [][32]byte, p int, depth int) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], layer[2*p][:])
+	if 2*p+1 < len(layer) {
+		copy(buf[32:], layer[2*p+1][:])
+	} else {
+		copy(buf[32:], zeroHashes[depth][:])
+	}
+	return hashPair(buf[:])
+}
+
+// resize returns the layer with n nodes, reusing the allocated space.
+func resize(layer [][32]byte, n int) [][32]byte {
+	if n <= cap(layer) {
+		return layer[:n]
+	}
+	return append(layer[:cap(layer)], make([][32]byte, n-cap(layer))...)
+}
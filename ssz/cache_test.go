@@ -0,0 +1,138 @@
+package ssz_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// freshRoot returns the root of the state decoded from its encoding, which has
+// nothing cached.
+func freshRoot(t *testing.T, state *spectests.BeaconState, fork ssz.Fork) [32]byte {
+	t.Helper()
+
+	enc, err := state.MarshalSSZForFork(fork)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	fresh := new(spectests.BeaconState)
+	if err := ssz.Unmarshal(enc, ssz.AtFork(fresh, fork)); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	root, err := fresh.HashTreeRootForFork(fork)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	return root
+}
+
+// Tests that the cached trees of the lists follow the items appended and
+// removed, including the lists growing from and shrinking to empty.
+func TestHashCacheResize(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = nil
+
+	for i, n := range []int{0, 20, 21, 25, 4, 0, 3, 100, 99} {
+		for len(state.Balances) < n {
+			state.Balances = append(state.Balances, r.Uint64())
+		}
+		state.Balances = state.Balances[:n]
+
+		have, err := state.HashTreeRootForFork(ssz.ForkCapella)
+		if err != nil {
+			t.Fatalf("step %d: failed to hash: %v", i, err)
+		}
+		if want := freshRoot(t, state, ssz.ForkCapella); have != want {
+			t.Fatalf("step %d: root mismatch with %d balances: have %x, want %x", i, n, have, want)
+		}
+	}
+}
+
+// Tests that the copies of a state don't share the cached trees, so hashing
+// the modified copy leaves the root of the original intact.
+func TestHashCacheCopy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = []uint64{1, 2, 3, 4, 5}
+
+	want, err := state.HashTreeRootForFork(ssz.ForkCapella)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	cpy := state.Copy()
+	cpy.Balances[2] = 10
+	if err := cpy.MarkDirty("balances", 2); err != nil {
+		t.Fatalf("failed to mark dirty: %v", err)
+	}
+	have, err := cpy.HashTreeRootForFork(ssz.ForkCapella)
+	if err != nil {
+		t.Fatalf("failed to hash the copy: %v", err)
+	}
+	if fresh := freshRoot(t, cpy, ssz.ForkCapella); have != fresh {
+		t.Fatalf("copy root mismatch: have %x, want %x", have, fresh)
+	}
+	if have, _ := state.HashTreeRootForFork(ssz.ForkCapella); have != want {
+		t.Fatalf("original root changed by the copy: have %x, want %x", have, want)
+	}
+}
+
+// Tests that the lists replaced by another slice of the same length are
+// rehashed without being marked dirty.
+func TestHashCacheReplaced(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = []uint64{1, 2, 3, 4, 5}
+
+	if _, err := state.HashTreeRootForFork(ssz.ForkCapella); err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	state.Balances = []uint64{5, 4, 3, 2, 1}
+	state.RandaoMixes = append([][]byte{}, state.RandaoMixes...)
+	state.RandaoMixes[0] = make([]byte, 32)
+
+	have, err := state.HashTreeRootForFork(ssz.ForkCapella)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if want := freshRoot(t, state, ssz.ForkCapella); have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that the state hashed on multiple goroutines at once has the same root
+// on all of them, run with -race to check the cache access.
+func TestHashCacheConcurrent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	want := freshRoot(t, state, ssz.ForkCapella)
+
+	var (
+		wg    sync.WaitGroup
+		roots = make([][32]byte, 8)
+		errs  = make([]error, len(roots))
+	)
+	for i := range roots {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			roots[i], errs[i] = state.HashTreeRootForFork(ssz.ForkCapella)
+		}(i)
+	}
+	wg.Wait()
+	for i, root := range roots {
+		if errs[i] != nil {
+			t.Fatalf("goroutine %d: failed to hash: %v", i, errs[i])
+		}
+		if root != want {
+			t.Fatalf("goroutine %d: root mismatch: have %x, want %x", i, root, want)
+		}
+	}
+}
@@ -1,12 +1,56 @@
 package ssz
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"io"
 )
 
+var ErrInvalidBool = errors.New("ssz: invalid boolean")
+
 type Decoder interface {
-	UnmarshalSSZ(buf []byte) error
+	UnmarshalSSZ(s *Stream) error
+}
+
+// Unmarshal decodes the ssz encoded input into obj. The input must be consumed
+// entirely by the object.
+func Unmarshal(buf []byte, obj Decoder) error {
+	s, err := NewStream(bytes.NewReader(buf), uint32(len(buf)))
+	if err != nil {
+		return err
+	}
+	if err := obj.UnmarshalSSZ(s); err != nil {
+		return err
+	}
+	return s.finish()
+}
+
+// Decode decodes the ssz encoded input with the given size from the reader into
+// obj. The input must be consumed entirely by the object.
+func Decode(r io.Reader, size uint32, obj Decoder) error {
+	s, err := NewStream(r, size)
+	if err != nil {
+		return err
+	}
+	if err := obj.UnmarshalSSZ(s); err != nil {
+		return err
+	}
+	return s.finish()
+}
+
+// DecodeFunc decodes the ssz encoded input with the given size from the reader
+// with fn, e.g. the generated decoder visiting the list items one by one. The
+// input must be consumed entirely by fn.
+func DecodeFunc(r io.Reader, size uint32, fn func(s *Stream) error) error {
+	s, err := NewStream(r, size)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.finish()
 }
 
 func DecodeBool(s *Stream) (bool, error) {
@@ -14,7 +58,14 @@ func DecodeBool(s *Stream) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return b == byte(1), nil
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, ErrInvalidBool
+	}
 }
 
 func DecodeByte(s *Stream) (byte, error) {
@@ -53,23 +104,47 @@ func DecodeBytes(s *Stream, n int) ([]byte, error) {
 	return read(s, n)
 }
 
-func DecodeUint16s(s *Stream, n int) ([]uint16, error) {
+// DecodeBools decodes n booleans, or all the remaining ones in the block if n
+// is zero.
+func DecodeBools(s *Stream, n int) ([]bool, error) {
 	buf, err := read(s, n)
 	if err != nil {
 		return nil, err
 	}
+	ret := make([]bool, len(buf))
+	for i, b := range buf {
+		switch b {
+		case 0:
+		case 1:
+			ret[i] = true
+		default:
+			return nil, ErrInvalidBool
+		}
+	}
+	return ret, nil
+}
+
+// DecodeUint16s decodes n uint16 items, or all the remaining ones in the block
+// if n is zero.
+func DecodeUint16s(s *Stream, n int) ([]uint16, error) {
+	buf, err := read(s, n*2)
+	if err != nil {
+		return nil, err
+	}
 	if len(buf)%2 != 0 {
 		return nil, errors.New("invalid input for decoding uint16s")
 	}
 	ret := make([]uint16, len(buf)/2)
 	for i := 0; i < len(buf)/2; i++ {
-		ret[i] = binary.LittleEndian.Uint16(buf[2*i : 2*i+1])
+		ret[i] = binary.LittleEndian.Uint16(buf[2*i : 2*i+2])
 	}
 	return ret, nil
 }
 
+// DecodeUint32s decodes n uint32 items, or all the remaining ones in the block
+// if n is zero.
 func DecodeUint32s(s *Stream, n int) ([]uint32, error) {
-	buf, err := read(s, n)
+	buf, err := read(s, n*4)
 	if err != nil {
 		return nil, err
 	}
@@ -78,13 +153,15 @@ func DecodeUint32s(s *Stream, n int) ([]uint32, error) {
 	}
 	ret := make([]uint32, len(buf)/4)
 	for i := 0; i < len(buf)/4; i++ {
-		ret[i] = binary.LittleEndian.Uint32(buf[4*i : 4*i+3])
+		ret[i] = binary.LittleEndian.Uint32(buf[4*i : 4*i+4])
 	}
 	return ret, nil
 }
 
+// DecodeUint64s decodes n uint64 items, or all the remaining ones in the block
+// if n is zero.
 func DecodeUint64s(s *Stream, n int) ([]uint64, error) {
-	buf, err := read(s, n)
+	buf, err := read(s, n*8)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +170,7 @@ func DecodeUint64s(s *Stream, n int) ([]uint64, error) {
 	}
 	ret := make([]uint64, len(buf)/8)
 	for i := 0; i < len(buf)/8; i++ {
-		ret[i] = binary.LittleEndian.Uint64(buf[8*i : 8*i+7])
+		ret[i] = binary.LittleEndian.Uint64(buf[8*i : 8*i+8])
 	}
 	return ret, nil
 }
@@ -0,0 +1,180 @@
+package ssz_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// streamOf returns the reader of the encoding hiding its size, so the decoding
+// streams it through the buffer.
+func streamOf(enc []byte) io.Reader {
+	return struct{ io.Reader }{bytes.NewReader(enc)}
+}
+
+// Tests that the items visited while streaming the lists are the same as the
+// ones decoded into the slices, the rest of the object decoded as usual.
+func TestEachItems(t *testing.T) {
+	state, enc := lazyState(t, ssz.ForkCapella)
+
+	var (
+		dec        = new(spectests.BeaconState)
+		validators []*spectests.Validator
+		buffers    = make(map[*spectests.Validator]struct{})
+	)
+	err := ssz.DecodeFunc(streamOf(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+		return dec.DecodeValidatorsEachForFork(s, ssz.ForkCapella, func(i int, v *spectests.Validator) error {
+			if i != len(validators) {
+				t.Errorf("index mismatch: have %d, want %d", i, len(validators))
+			}
+			buffers[v] = struct{}{}
+			validators = append(validators, v.Copy())
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(validators, state.Validators) {
+		t.Fatalf("visited validators mismatch")
+	}
+	if len(buffers) != 1 {
+		t.Fatalf("validators decoded into %d values, want 1", len(buffers))
+	}
+	if dec.Validators != nil || dec.Slot != state.Slot || !reflect.DeepEqual(dec.Balances, state.Balances) {
+		t.Fatalf("rest of the state mismatch")
+	}
+	var balances []uint64
+	err = ssz.DecodeFunc(streamOf(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+		return new(spectests.BeaconState).DecodeBalancesEachForFork(s, ssz.ForkCapella, func(i int, v uint64) error {
+			balances = append(balances, v)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to decode the balances: %v", err)
+	}
+	if !reflect.DeepEqual(balances, state.Balances) {
+		t.Fatalf("visited balances mismatch: have %v, want %v", balances, state.Balances)
+	}
+	// The variable-size items are located by the offsets
+	payload := new(spectests.ExecutionPayloadCapella)
+	payload.GenerateRandomSSZ(rand.New(rand.NewSource(1)), nil)
+	payload.Transactions = [][]byte{{1, 2, 3}, {}, {4}, make([]byte, 5000)}
+	penc, _ := payload.MarshalSSZ()
+
+	var txs [][]byte
+	err = ssz.DecodeFunc(streamOf(penc), uint32(len(penc)), func(s *ssz.Stream) error {
+		return new(spectests.ExecutionPayloadCapella).DecodeTransactionsEach(s, func(i int, v []byte) error {
+			txs = append(txs, append([]byte{}, v...))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to decode the payload: %v", err)
+	}
+	if !reflect.DeepEqual(txs, payload.Transactions) {
+		t.Fatalf("visited transactions mismatch")
+	}
+}
+
+// Tests that the error returned by the visitor stops the decoding.
+func TestEachStop(t *testing.T) {
+	_, enc := lazyState(t, ssz.ForkCapella)
+
+	var (
+		errStop = errors.New("stop")
+		visited int
+	)
+	err := ssz.DecodeFunc(streamOf(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+		return new(spectests.BeaconState).DecodeValidatorsEachForFork(s, ssz.ForkCapella, func(i int, v *spectests.Validator) error {
+			visited++
+			if i == 2 {
+				return errStop
+			}
+			return nil
+		})
+	})
+	if err != errStop {
+		t.Fatalf("error mismatch: have %v, want %v", err, errStop)
+	}
+	if visited != 3 {
+		t.Fatalf("visited %d validators after the error, want 3", visited)
+	}
+}
+
+// Tests that the limits of the lists are enforced while streaming them, and the
+// lists without a limit are bounded by the scope.
+func TestEachLimits(t *testing.T) {
+	// Insert a vote into the state with the votes filled up to the limit, and
+	// shift the offsets of the fields after them
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Eth1DataVotes = make([]*spectests.Eth1Data, 2048)
+	for i := range state.Eth1DataVotes {
+		state.Eth1DataVotes[i] = new(spectests.Eth1Data)
+	}
+	enc, err := state.MarshalSSZForFork(ssz.ForkPhase0)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	const (
+		validators   = 8 + 32 + 8 + 16 + 112 + 2*8192*32 + 4 + 72 + 4 + 8
+		attestations = validators + 4 + 4 + 65536*32 + 8192*8
+	)
+	end := binary.LittleEndian.Uint32(enc[validators:])
+	for _, pos := range []int{validators, validators + 4, attestations, attestations + 4} {
+		binary.LittleEndian.PutUint32(enc[pos:], binary.LittleEndian.Uint32(enc[pos:])+72)
+	}
+	enc = append(enc[:end], append(make([]byte, 72), enc[end:]...)...)
+
+	err = ssz.DecodeFunc(streamOf(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+		return new(spectests.BeaconState).DecodeEth1DataVotesEachForFork(s, ssz.ForkPhase0, func(i int, v *spectests.Eth1Data) error {
+			return nil
+		})
+	})
+	if err != ssz.ErrListTooBig {
+		t.Fatalf("votes beyond the limit: error mismatch: have %v, want %v", err, ssz.ErrListTooBig)
+	}
+	// The offsets beyond the limit are rejected before reading them
+	offsets := []byte{12, 0, 0, 0, 12, 0, 0, 0, 12, 0, 0, 0}
+	tests := []struct {
+		name string
+		size uint32
+		max  int
+		fail bool
+	}{
+		{"within the limit", 12, 3, false},
+		{"beyond the limit", 12, 2, true},
+		{"bounded by the scope", 12, 0, false},
+		{"unbounded", 0, 0, true},
+	}
+	for _, tt := range tests {
+		err := ssz.DecodeFunc(streamOf(offsets), tt.size, func(s *ssz.Stream) error {
+			n, err := s.DecodeOffsets(tt.max)
+			if err == nil && n != 3 {
+				t.Errorf("%s: items mismatch: have %d, want 3", tt.name, n)
+			}
+			return err
+		})
+		if (err != nil) != tt.fail {
+			t.Errorf("%s: error mismatch: have %v, want failure %v", tt.name, err, tt.fail)
+		}
+	}
+	// The first offset points beyond the scope
+	err = ssz.DecodeFunc(streamOf([]byte{0, 0, 0, 1}), 4, func(s *ssz.Stream) error {
+		_, err := s.DecodeOffsets(0)
+		return err
+	})
+	if err != ssz.ErrInvalidOffset {
+		t.Fatalf("offsets beyond the scope: error mismatch: have %v, want %v", err, ssz.ErrInvalidOffset)
+	}
+}
@@ -2,11 +2,19 @@ package ssz
 
 import (
 	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrNilPointer   = errors.New("ssz: nil pointer is not allowed")
+	ErrListTooBig   = errors.New("ssz: list exceeds the maximum length")
+	ErrSizeMismatch = errors.New("ssz: length mismatches the fixed size")
 )
 
 type Encoder interface {
+	SizeSSZ() int
 	MarshalSSZ() ([]byte, error)
-	MarshalSSZTo(buf []byte) error
+	MarshalSSZTo(buf []byte) ([]byte, error)
 }
 
 func EncodeBool(dst []byte, b bool) []byte {
@@ -36,11 +44,13 @@ func EncodeUint64(dst []byte, i uint64) []byte {
 }
 
 func EncodeBools(dst []byte, input []bool) []byte {
+	dst = grow(dst, len(input))
 	for _, b := range input {
 		if b {
 			dst = append(dst, byte(1))
+		} else {
+			dst = append(dst, byte(0))
 		}
-		dst = append(dst, byte(0))
 	}
 	return dst
 }
@@ -73,9 +83,20 @@ func EncodeUint64s(dst []byte, input []uint64) []byte {
 	return dst
 }
 
+// EncodeZeros appends n zero bytes, which is the encoding of the zero value
+// for any fixed-size type.
+func EncodeZeros(dst []byte, n int) []byte {
+	dst = grow(dst, n)
+	return append(dst, make([]byte, n)...)
+}
+
 func grow(buf []byte, n int) []byte {
 	if cap(buf)-len(buf) < n {
-		nbuf := make([]byte, len(buf), 2*cap(buf))
+		size := 2 * cap(buf)
+		if size < len(buf)+n {
+			size = len(buf) + n
+		}
+		nbuf := make([]byte, len(buf), size)
 		copy(nbuf, buf)
 		buf = nbuf
 	}
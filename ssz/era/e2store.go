@@ -0,0 +1,146 @@
+package era
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// HeaderSize is the size of the header of the e2store records, which consists
+// of the 2-byte type, the 4-byte little-endian length of the value and 2
+// reserved zero bytes.
+const HeaderSize = 8
+
+// Types of the records.
+var (
+	TypeVersion                     = [2]byte{0x65, 0x32}
+	TypeEmpty                       = [2]byte{0x00, 0x00}
+	TypeCompressedSignedBeaconBlock = [2]byte{0x01, 0x00}
+	TypeCompressedBeaconState       = [2]byte{0x02, 0x00}
+	TypeSlotIndex                   = [2]byte{0x69, 0x32}
+)
+
+var (
+	ErrReservedBytes = errors.New("e2store: reserved bytes are not zero")
+	ErrValueTooLarge = errors.New("e2store: value exceeds the maximum length")
+)
+
+// Record is an e2store record.
+type Record struct {
+	Type  [2]byte
+	Value []byte
+}
+
+// Writer writes the e2store records sequentially.
+type Writer struct {
+	w   io.Writer
+	pos int64 // number of bytes written so far
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Pos returns the position where the next record starts.
+func (w *Writer) Pos() int64 {
+	return w.pos
+}
+
+// Write writes the record, returning the position where it starts.
+func (w *Writer) Write(typ [2]byte, value []byte) (int64, error) {
+	if uint64(len(value)) > 1<<32-1 {
+		return 0, ErrValueTooLarge
+	}
+	var header [HeaderSize]byte
+	copy(header[:2], typ[:])
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(value)))
+
+	pos := w.pos
+	if _, err := w.w.Write(header[:]); err != nil {
+		return 0, err
+	}
+	if _, err := w.w.Write(value); err != nil {
+		return 0, err
+	}
+	w.pos += HeaderSize + int64(len(value))
+	return pos, nil
+}
+
+// Reader reads the e2store records, either sequentially or at the positions
+// specified.
+type Reader struct {
+	r    io.ReaderAt
+	size int64 // size of the underlying data, bounding the record lengths
+	pos  int64 // position of the next record for the sequential reads
+}
+
+// NewReader creates the reader of the e2store data with the given size. The
+// values are only allocated if they fit in the data.
+func NewReader(r io.ReaderAt, size int64) *Reader {
+	return &Reader{r: r, size: size}
+}
+
+// Next reads the next record, returning io.EOF if there are no more records.
+func (r *Reader) Next() (*Record, error) {
+	rec, err := r.ReadAt(r.pos)
+	if err != nil {
+		return nil, err
+	}
+	r.pos += HeaderSize + int64(len(rec.Value))
+	return rec, nil
+}
+
+// ReadAt reads the record starting at the position.
+func (r *Reader) ReadAt(pos int64) (*Record, error) {
+	typ, length, err := r.ReadHeader(pos)
+	if err != nil {
+		return nil, err
+	}
+	if int64(length) > r.size-pos-HeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	value := make([]byte, length)
+	if n, err := r.r.ReadAt(value, pos+HeaderSize); n < len(value) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &Record{Type: typ, Value: value}, nil
+}
+
+// ReadHeader reads the type and the value length of the record starting at
+// the position, returning io.EOF if the position is at the end.
+func (r *Reader) ReadHeader(pos int64) ([2]byte, uint32, error) {
+	var (
+		typ    [2]byte
+		header [HeaderSize]byte
+	)
+	n, err := r.r.ReadAt(header[:], pos)
+	switch {
+	case n == 0 && err == io.EOF:
+		return typ, 0, io.EOF
+	case n < HeaderSize && err == io.EOF:
+		return typ, 0, io.ErrUnexpectedEOF
+	case n < HeaderSize:
+		return typ, 0, err
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return typ, 0, ErrReservedBytes
+	}
+	copy(typ[:], header[:2])
+	return typ, binary.LittleEndian.Uint32(header[2:6]), nil
+}
+
+// readTyped reads the record of the expected type starting at the position.
+func (r *Reader) readTyped(pos int64, typ [2]byte) ([]byte, error) {
+	rec, err := r.ReadAt(pos)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Type != typ {
+		return nil, fmt.Errorf("e2store: unexpected record type %x at %d, want %x", rec.Type, pos, typ)
+	}
+	return rec.Value, nil
+}
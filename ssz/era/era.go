@@ -0,0 +1,298 @@
+// Package era implements the .era archives of the historical beacon chain data,
+// which are e2store files holding the snappy compressed blocks of an era along
+// with the state at its end, indexed by the slots.
+//
+//	era := Version | block* | era-state | other-entries* | slot-index(block)? | slot-index(state)
+package era
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rjl493456442/sszgen/ssz"
+	"github.com/rjl493456442/sszgen/ssz/snappy"
+)
+
+// SlotsPerHistoricalRoot is the number of slots in an era of mainnet.
+const SlotsPerHistoricalRoot = 8192
+
+var (
+	ErrInvalidIndex = errors.New("era: invalid slot index")
+	ErrSlotRange    = errors.New("era: slot out of the range of the era")
+)
+
+// Object is the method set of the generated bindings for decoding the records.
+type Object interface {
+	ssz.Decoder
+	MaxSizeSSZ() uint64
+}
+
+// SlotIndex is the index of the records by slot. The offsets are relative to
+// the start of the index record, with zero for the slots without record.
+type SlotIndex struct {
+	StartSlot uint64
+	Offsets   []int64
+}
+
+// encode encodes the index as starting-slot | offset * count | count, all in
+// little-endian 64 bits.
+func (idx *SlotIndex) encode() []byte {
+	b := make([]byte, 0, 16+8*len(idx.Offsets))
+	b = binary.LittleEndian.AppendUint64(b, idx.StartSlot)
+	for _, offset := range idx.Offsets {
+		b = binary.LittleEndian.AppendUint64(b, uint64(offset))
+	}
+	return binary.LittleEndian.AppendUint64(b, uint64(len(idx.Offsets)))
+}
+
+func decodeSlotIndex(b []byte) (*SlotIndex, error) {
+	if len(b) < 16 || len(b)%8 != 0 {
+		return nil, ErrInvalidIndex
+	}
+	count := binary.LittleEndian.Uint64(b[len(b)-8:])
+	if count != uint64(len(b)-16)/8 {
+		return nil, ErrInvalidIndex
+	}
+	idx := &SlotIndex{
+		StartSlot: binary.LittleEndian.Uint64(b),
+		Offsets:   make([]int64, count),
+	}
+	for i := range idx.Offsets {
+		idx.Offsets[i] = int64(binary.LittleEndian.Uint64(b[8+8*i:]))
+	}
+	return idx, nil
+}
+
+// Builder writes an era file. The blocks are added in the order of the slots,
+// followed by the state finishing the era.
+type Builder struct {
+	w      *Writer
+	era    uint64
+	blocks []int64 // positions of the block records by slot, zero if absent
+	last   uint64  // slot of the last block added
+	added  bool    // whether any block is added
+}
+
+// NewBuilder creates the builder of the era, which holds the blocks of the
+// slots in [(era-1)*SlotsPerHistoricalRoot, era*SlotsPerHistoricalRoot) and
+// the state at era*SlotsPerHistoricalRoot. The era zero holds the genesis
+// state only.
+func NewBuilder(w io.Writer, era uint64) (*Builder, error) {
+	b := &Builder{w: NewWriter(w), era: era}
+	if era > 0 {
+		b.blocks = make([]int64, SlotsPerHistoricalRoot)
+	}
+	if _, err := b.w.Write(TypeVersion, nil); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// AddBlock adds the compressed block of the slot.
+func (b *Builder) AddBlock(slot uint64, block ssz.Encoder) error {
+	start := (b.era - 1) * SlotsPerHistoricalRoot
+	if b.era == 0 || slot < start || slot >= start+SlotsPerHistoricalRoot {
+		return ErrSlotRange
+	}
+	if b.added && slot <= b.last {
+		return fmt.Errorf("era: block of slot %d added after slot %d", slot, b.last)
+	}
+	value, err := snappy.EncodeFramed(block)
+	if err != nil {
+		return err
+	}
+	pos, err := b.w.Write(TypeCompressedSignedBeaconBlock, value)
+	if err != nil {
+		return err
+	}
+	b.blocks[slot-start], b.last, b.added = pos, slot, true
+	return nil
+}
+
+// Finalize writes the compressed state at the end of the era, followed by the
+// slot indices of the blocks and the state.
+func (b *Builder) Finalize(state ssz.Encoder) error {
+	value, err := snappy.EncodeFramed(state)
+	if err != nil {
+		return err
+	}
+	statePos, err := b.w.Write(TypeCompressedBeaconState, value)
+	if err != nil {
+		return err
+	}
+	if b.era > 0 {
+		idx := &SlotIndex{
+			StartSlot: (b.era - 1) * SlotsPerHistoricalRoot,
+			Offsets:   make([]int64, len(b.blocks)),
+		}
+		pos := b.w.Pos()
+		for i, blockPos := range b.blocks {
+			if blockPos != 0 {
+				idx.Offsets[i] = blockPos - pos
+			}
+		}
+		if _, err := b.w.Write(TypeSlotIndex, idx.encode()); err != nil {
+			return err
+		}
+	}
+	idx := &SlotIndex{
+		StartSlot: b.era * SlotsPerHistoricalRoot,
+		Offsets:   []int64{statePos - b.w.Pos()},
+	}
+	_, err = b.w.Write(TypeSlotIndex, idx.encode())
+	return err
+}
+
+// Era is an opened era file, accessing the records through the slot indices.
+type Era struct {
+	r          *Reader
+	blockIndex *SlotIndex // nil in the era zero
+	blockPos   int64      // position of the block index record
+	stateIndex *SlotIndex
+	statePos   int64 // position of the state index record
+}
+
+// Open opens the era file of the given size, reading the slot indices at the
+// end of it.
+func Open(r io.ReaderAt, size int64) (*Era, error) {
+	e := &Era{r: NewReader(r, size)}
+
+	var err error
+	if e.stateIndex, e.statePos, err = e.readIndexBefore(size); err != nil {
+		return nil, err
+	}
+	if len(e.stateIndex.Offsets) != 1 {
+		return nil, ErrInvalidIndex
+	}
+	if e.stateIndex.StartSlot == 0 {
+		return e, nil // genesis era without blocks
+	}
+	if e.blockIndex, e.blockPos, err = e.readIndexBefore(e.statePos); err != nil {
+		return nil, err
+	}
+	if e.blockIndex.StartSlot+uint64(len(e.blockIndex.Offsets)) != e.stateIndex.StartSlot {
+		return nil, ErrInvalidIndex
+	}
+	return e, nil
+}
+
+// readIndexBefore reads the slot index record ending at the position, which is
+// located by the count at the end of it.
+func (e *Era) readIndexBefore(end int64) (*SlotIndex, int64, error) {
+	var buf [8]byte
+	if end < HeaderSize+16 {
+		return nil, 0, ErrInvalidIndex
+	}
+	if _, err := e.r.r.ReadAt(buf[:], end-8); err != nil {
+		return nil, 0, err
+	}
+	count := binary.LittleEndian.Uint64(buf[:])
+	if count > uint64(end-HeaderSize-16)/8 {
+		return nil, 0, ErrInvalidIndex
+	}
+	pos := end - HeaderSize - 16 - int64(count)*8
+	value, err := e.r.readTyped(pos, TypeSlotIndex)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx, err := decodeSlotIndex(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return idx, pos, nil
+}
+
+// StartSlot returns the first slot of the blocks in the era.
+func (e *Era) StartSlot() uint64 {
+	if e.blockIndex == nil {
+		return e.stateIndex.StartSlot
+	}
+	return e.blockIndex.StartSlot
+}
+
+// StateSlot returns the slot of the state at the end of the era.
+func (e *Era) StateSlot() uint64 {
+	return e.stateIndex.StartSlot
+}
+
+// State decodes the state at the end of the era into obj.
+func (e *Era) State(obj Object) error {
+	return e.decode(e.statePos+e.stateIndex.Offsets[0], TypeCompressedBeaconState, obj)
+}
+
+// Block decodes the block of the slot into obj. It returns false if there is
+// no block in the slot.
+func (e *Era) Block(slot uint64, obj Object) (bool, error) {
+	if e.blockIndex == nil || slot < e.blockIndex.StartSlot || slot-e.blockIndex.StartSlot >= uint64(len(e.blockIndex.Offsets)) {
+		return false, ErrSlotRange
+	}
+	offset := e.blockIndex.Offsets[slot-e.blockIndex.StartSlot]
+	if offset == 0 {
+		return false, nil
+	}
+	return true, e.decode(e.blockPos+offset, TypeCompressedSignedBeaconBlock, obj)
+}
+
+func (e *Era) decode(pos int64, typ [2]byte, obj Object) error {
+	if pos < 0 {
+		return ErrInvalidIndex
+	}
+	value, err := e.r.readTyped(pos, typ)
+	if err != nil {
+		return err
+	}
+	return snappy.DecodeFramed(value, obj, obj.MaxSizeSSZ())
+}
+
+// Blocks returns the iterator over the blocks of the era in the order of the
+// slots, skipping the empty ones.
+func (e *Era) Blocks() *BlockIterator {
+	return &BlockIterator{era: e}
+}
+
+// BlockIterator iterates the blocks of an era.
+type BlockIterator struct {
+	era  *Era
+	next int    // index of the next slot to check
+	slot uint64 // slot of the current block
+	err  error
+}
+
+// Next moves to the next block, returning false if there are no more blocks
+// or the iteration has failed.
+func (it *BlockIterator) Next() bool {
+	idx := it.era.blockIndex
+	if idx == nil || it.err != nil {
+		return false
+	}
+	for it.next < len(idx.Offsets) {
+		i := it.next
+		it.next += 1
+		if idx.Offsets[i] != 0 {
+			it.slot = idx.StartSlot + uint64(i)
+			return true
+		}
+	}
+	return false
+}
+
+// Slot returns the slot of the current block.
+func (it *BlockIterator) Slot() uint64 {
+	return it.slot
+}
+
+// Decode decodes the current block into obj.
+func (it *BlockIterator) Decode(obj Object) error {
+	_, err := it.era.Block(it.slot, obj)
+	if err != nil {
+		it.err = err
+	}
+	return err
+}
+
+// Err returns the error that failed the iteration.
+func (it *BlockIterator) Err() error {
+	return it.err
+}
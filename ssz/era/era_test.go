@@ -0,0 +1,120 @@
+package era_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+	"github.com/rjl493456442/sszgen/ssz/era"
+)
+
+// Tests that the blocks and the state written by the builder are read back
+// from the opened era, both by slot and by iterating the blocks.
+func TestEraRoundTrip(t *testing.T) {
+	var (
+		r      = rand.New(rand.NewSource(1))
+		start  = uint64(4 * era.SlotsPerHistoricalRoot)
+		slots  = []uint64{start, start + 3, start + 100, start + era.SlotsPerHistoricalRoot - 1}
+		blocks = make(map[uint64]*spectests.SignedBeaconBlock)
+		buf    bytes.Buffer
+	)
+	b, err := era.NewBuilder(&buf, 5)
+	if err != nil {
+		t.Fatalf("failed to create the builder: %v", err)
+	}
+	for _, slot := range slots {
+		blk := new(spectests.SignedBeaconBlock)
+		blk.GenerateRandomSSZ(r, nil)
+		if err := b.AddBlock(slot, blk); err != nil {
+			t.Fatalf("failed to add the block of slot %d: %v", slot, err)
+		}
+		blocks[slot] = blk
+	}
+	if err := b.AddBlock(start+5, blocks[start]); err == nil {
+		t.Fatalf("block added out of order")
+	}
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	if err := b.Finalize(ssz.AtFork(state, ssz.ForkCapella)); err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+
+	e, err := era.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if e.StartSlot() != start || e.StateSlot() != start+era.SlotsPerHistoricalRoot {
+		t.Fatalf("slot range mismatch: have [%d, %d]", e.StartSlot(), e.StateSlot())
+	}
+	have := new(spectests.BeaconState)
+	if err := e.State(ssz.AtFork(have, ssz.ForkCapella)); err != nil {
+		t.Fatalf("failed to decode the state: %v", err)
+	}
+	haveRoot, _ := have.HashTreeRootForFork(ssz.ForkCapella)
+	wantRoot, _ := state.HashTreeRootForFork(ssz.ForkCapella)
+	if haveRoot != wantRoot {
+		t.Fatalf("state root mismatch: have %x, want %x", haveRoot, wantRoot)
+	}
+	for _, slot := range slots {
+		blk := new(spectests.SignedBeaconBlock)
+		if ok, err := e.Block(slot, blk); !ok || err != nil {
+			t.Fatalf("failed to decode the block of slot %d: %v %v", slot, ok, err)
+		}
+		if !blk.Equal(blocks[slot]) {
+			t.Fatalf("block of slot %d mismatch", slot)
+		}
+	}
+	if ok, err := e.Block(start+1, new(spectests.SignedBeaconBlock)); ok || err != nil {
+		t.Fatalf("empty slot: have %v %v, want no block", ok, err)
+	}
+	if _, err := e.Block(start-1, new(spectests.SignedBeaconBlock)); err != era.ErrSlotRange {
+		t.Fatalf("slot out of range: have %v, want %v", err, era.ErrSlotRange)
+	}
+	var (
+		it   = e.Blocks()
+		iter []uint64
+	)
+	for it.Next() {
+		blk := new(spectests.SignedBeaconBlock)
+		if err := it.Decode(blk); err != nil {
+			t.Fatalf("failed to decode the block of slot %d: %v", it.Slot(), err)
+		}
+		if !blk.Equal(blocks[it.Slot()]) {
+			t.Fatalf("iterated block of slot %d mismatch", it.Slot())
+		}
+		iter = append(iter, it.Slot())
+	}
+	if it.Err() != nil {
+		t.Fatalf("iteration failed: %v", it.Err())
+	}
+	if len(iter) != len(slots) {
+		t.Fatalf("iterated slots mismatch: have %v, want %v", iter, slots)
+	}
+}
+
+// Tests that the record lengths beyond the data are rejected before the value
+// is allocated.
+func TestRecordLengthBound(t *testing.T) {
+	var buf bytes.Buffer
+	w := era.NewWriter(&buf)
+	if _, err := w.Write(era.TypeVersion, nil); err != nil {
+		t.Fatalf("failed to write the record: %v", err)
+	}
+	if _, err := w.Write(era.TypeEmpty, make([]byte, 16)); err != nil {
+		t.Fatalf("failed to write the record: %v", err)
+	}
+	blob := buf.Bytes()
+	binary.LittleEndian.PutUint32(blob[era.HeaderSize+2:], 1<<32-1)
+
+	r := era.NewReader(bytes.NewReader(blob), int64(len(blob)))
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("failed to read the version: %v", err)
+	}
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("oversized record: have %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
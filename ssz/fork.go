@@ -0,0 +1,176 @@
+package ssz
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownFork = errors.New("ssz: unknown fork")
+	ErrNoJSON      = errors.New("ssz: container has no json methods")
+)
+
+// Fork is a consensus fork, in the order of activation.
+type Fork int
+
+const (
+	ForkPhase0 Fork = iota
+	ForkAltair
+	ForkBellatrix
+	ForkCapella
+	ForkDeneb
+	ForkElectra
+	ForkFulu
+
+	LatestFork = ForkFulu
+)
+
+var forkNames = [...]string{
+	ForkPhase0:    "phase0",
+	ForkAltair:    "altair",
+	ForkBellatrix: "bellatrix",
+	ForkCapella:   "capella",
+	ForkDeneb:     "deneb",
+	ForkElectra:   "electra",
+	ForkFulu:      "fulu",
+}
+
+func (f Fork) String() string {
+	if f < 0 || int(f) >= len(forkNames) {
+		return fmt.Sprintf("fork(%d)", int(f))
+	}
+	return forkNames[f]
+}
+
+// ForkByName returns the fork with the given name, e.g. "capella".
+func ForkByName(name string) (Fork, bool) {
+	for f, n := range forkNames {
+		if n == name {
+			return Fork(f), true
+		}
+	}
+	return 0, false
+}
+
+// ForkedObject is implemented by the containers with fields gated by the forks,
+// whose encoding and root depend on the fork.
+type ForkedObject interface {
+	SizeSSZForFork(fork Fork) int
+	MarshalSSZToForFork(buf []byte, fork Fork) ([]byte, error)
+	UnmarshalSSZForFork(s *Stream, fork Fork) error
+	HashTreeRootWithForFork(h *Hasher, fork Fork) error
+	MinSizeSSZForFork(fork Fork) uint64
+	MaxSizeSSZForFork(fork Fork) uint64
+	GIndexSSZForFork(path []PathElem, fork Fork) (uint64, error)
+}
+
+// ForkedJSON is implemented by the fork-gated containers generated with the
+// json methods, whose json representation depends on the fork.
+type ForkedJSON interface {
+	MarshalJSONForFork(fork Fork) ([]byte, error)
+	UnmarshalJSONForFork(input []byte, fork Fork) error
+}
+
+// ForkBound is the fork-gated container bound to a fork, which implements the
+// fork independent methods of the containers, e.g. Encoder and Decoder.
+type ForkBound struct {
+	Object ForkedObject
+	Fork   Fork
+}
+
+// AtFork binds the fork-gated container to the fork.
+func AtFork(obj ForkedObject, fork Fork) *ForkBound {
+	return &ForkBound{Object: obj, Fork: fork}
+}
+
+func (b *ForkBound) SizeSSZ() int {
+	return b.Object.SizeSSZForFork(b.Fork)
+}
+
+func (b *ForkBound) MarshalSSZ() ([]byte, error) {
+	return b.MarshalSSZTo(make([]byte, 0, b.SizeSSZ()))
+}
+
+func (b *ForkBound) MarshalSSZTo(buf []byte) ([]byte, error) {
+	return b.Object.MarshalSSZToForFork(buf, b.Fork)
+}
+
+func (b *ForkBound) UnmarshalSSZ(s *Stream) error {
+	return b.Object.UnmarshalSSZForFork(s, b.Fork)
+}
+
+func (b *ForkBound) HashTreeRoot() ([32]byte, error) {
+	h := GetHasher()
+	defer PutHasher(h)
+	if err := b.HashTreeRootWith(h); err != nil {
+		return [32]byte{}, err
+	}
+	return h.HashRoot()
+}
+
+func (b *ForkBound) HashTreeRootWith(h *Hasher) error {
+	return b.Object.HashTreeRootWithForFork(h, b.Fork)
+}
+
+func (b *ForkBound) ProveSSZ(gindex uint64) ([][32]byte, error) {
+	return Prove(b, gindex)
+}
+
+func (b *ForkBound) GIndexSSZ(path []PathElem) (uint64, error) {
+	return b.Object.GIndexSSZForFork(path, b.Fork)
+}
+
+func (b *ForkBound) MinSizeSSZ() uint64 {
+	return b.Object.MinSizeSSZForFork(b.Fork)
+}
+
+func (b *ForkBound) MaxSizeSSZ() uint64 {
+	return b.Object.MaxSizeSSZForFork(b.Fork)
+}
+
+// MarshalJSON converts the container to json at the fork. It fails with
+// ErrNoJSON if the container has no json methods generated.
+func (b *ForkBound) MarshalJSON() ([]byte, error) {
+	obj, ok := b.Object.(ForkedJSON)
+	if !ok {
+		return nil, ErrNoJSON
+	}
+	return obj.MarshalJSONForFork(b.Fork)
+}
+
+// UnmarshalJSON converts the container from json at the fork. It fails with
+// ErrNoJSON if the container has no json methods generated.
+func (b *ForkBound) UnmarshalJSON(input []byte) error {
+	obj, ok := b.Object.(ForkedJSON)
+	if !ok {
+		return ErrNoJSON
+	}
+	return obj.UnmarshalJSONForFork(input, b.Fork)
+}
+
+// ForkDigests maps the fork digests of a network to the forks.
+type ForkDigests map[[4]byte]Fork
+
+// NewForkDigests computes the digests of the fork versions on the network with
+// the genesis validators root.
+func NewForkDigests(versions map[Fork][4]byte, genesisValidatorsRoot [32]byte) ForkDigests {
+	digests := make(ForkDigests)
+	for fork, version := range versions {
+		digests[ComputeForkDigest(version, genesisValidatorsRoot)] = fork
+	}
+	return digests
+}
+
+// ComputeForkDigest returns the first 4 bytes of the root of the ForkData with
+// the fork version and the genesis validators root.
+func ComputeForkDigest(version [4]byte, genesisValidatorsRoot [32]byte) [4]byte {
+	var (
+		chunks [2 * BytesPerChunk]byte
+		digest [4]byte
+	)
+	copy(chunks[:], version[:])
+	copy(chunks[BytesPerChunk:], genesisValidatorsRoot[:])
+	root := hashPair(chunks[:])
+	copy(digest[:], root[:])
+	return digest
+}
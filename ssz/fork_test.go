@@ -0,0 +1,214 @@
+package ssz_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// mainnetDigests are the fork digests of the mainnet, by the genesis validators
+// root and the fork versions.
+func mainnetDigests(t *testing.T) ssz.ForkDigests {
+	t.Helper()
+
+	var root [32]byte
+	if _, err := hex.Decode(root[:], []byte("4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95")); err != nil {
+		t.Fatal(err)
+	}
+	return ssz.NewForkDigests(map[ssz.Fork][4]byte{
+		ssz.ForkPhase0:    {0x00, 0x00, 0x00, 0x00},
+		ssz.ForkAltair:    {0x01, 0x00, 0x00, 0x00},
+		ssz.ForkBellatrix: {0x02, 0x00, 0x00, 0x00},
+		ssz.ForkCapella:   {0x03, 0x00, 0x00, 0x00},
+	}, root)
+}
+
+// Tests the fork digests against the ones of the mainnet.
+func TestForkDigests(t *testing.T) {
+	want := ssz.ForkDigests{
+		{0xb5, 0x30, 0x3f, 0x2a}: ssz.ForkPhase0,
+		{0xaf, 0xca, 0xab, 0xa0}: ssz.ForkAltair,
+		{0x4a, 0x26, 0xc5, 0x8b}: ssz.ForkBellatrix,
+		{0xbb, 0xa4, 0xda, 0x96}: ssz.ForkCapella,
+	}
+	if have := mainnetDigests(t); !reflect.DeepEqual(have, want) {
+		t.Fatalf("digests mismatch: have %x, want %x", have, want)
+	}
+}
+
+// digestOf returns the digest of the fork.
+func digestOf(digests ssz.ForkDigests, fork ssz.Fork) [4]byte {
+	for digest, f := range digests {
+		if f == fork {
+			return digest
+		}
+	}
+	panic("no digest")
+}
+
+// Tests that the block bodies encoded at each fork are decoded by the digest
+// into the body type of the fork, and the unknown digests and forks rejected.
+func TestVersionedBlockBody(t *testing.T) {
+	var (
+		r       = rand.New(rand.NewSource(1))
+		digests = mainnetDigests(t)
+	)
+	bodies := map[ssz.Fork]interface {
+		spectests.AnyBeaconBlockBody
+		GenerateRandomSSZ(r *rand.Rand, opts *ssz.RandomOptions)
+	}{
+		ssz.ForkPhase0:    new(spectests.BeaconBlockBodyPhase0),
+		ssz.ForkAltair:    new(spectests.BeaconBlockBodyAltair),
+		ssz.ForkBellatrix: new(spectests.BeaconBlockBodyBellatrix),
+		ssz.ForkCapella:   new(spectests.BeaconBlockBodyCapella),
+	}
+	for fork, body := range bodies {
+		body.GenerateRandomSSZ(r, nil)
+		enc, err := body.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("%v: failed to encode: %v", fork, err)
+		}
+		var v spectests.VersionedBeaconBlockBody
+		err = ssz.DecodeFunc(bytes.NewReader(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+			return v.DecodeForDigest(digests, digestOf(digests, fork), s)
+		})
+		if err != nil {
+			t.Fatalf("%v: failed to decode: %v", fork, err)
+		}
+		if v.Fork != fork {
+			t.Fatalf("%v: fork mismatch: have %v", fork, v.Fork)
+		}
+		if reflect.TypeOf(v.AnyBeaconBlockBody) != reflect.TypeOf(body) {
+			t.Fatalf("%v: body type mismatch: have %T, want %T", fork, v.AnyBeaconBlockBody, body)
+		}
+		if !reflect.DeepEqual(v.AnyBeaconBlockBody, spectests.AnyBeaconBlockBody(body)) {
+			t.Fatalf("%v: decoded body mismatch", fork)
+		}
+		if v.GetGraffiti() != body.GetGraffiti() || len(v.GetAttestations()) != len(body.GetAttestations()) {
+			t.Fatalf("%v: accessors mismatch", fork)
+		}
+	}
+	var v spectests.VersionedBeaconBlockBody
+	enc, _ := bodies[ssz.ForkPhase0].MarshalSSZ()
+	for _, fn := range []func(s *ssz.Stream) error{
+		func(s *ssz.Stream) error { return v.DecodeForDigest(digests, [4]byte{1, 2, 3, 4}, s) },
+		func(s *ssz.Stream) error { return v.DecodeForFork(ssz.ForkDeneb, s) },
+	} {
+		if err := ssz.DecodeFunc(bytes.NewReader(enc), uint32(len(enc)), fn); err != ssz.ErrUnknownFork {
+			t.Fatalf("error mismatch: have %v, want %v", err, ssz.ErrUnknownFork)
+		}
+	}
+	if v.AnyBeaconBlockBody != nil {
+		t.Fatalf("body set by the rejected decoding")
+	}
+}
+
+// Tests that the state encoded at each fork of the gated family is decoded by
+// the digest at the fork, and the forks out of the family rejected.
+func TestVersionedState(t *testing.T) {
+	var (
+		r       = rand.New(rand.NewSource(1))
+		digests = mainnetDigests(t)
+		state   = new(spectests.BeaconState)
+	)
+	state.GenerateRandomSSZ(r, nil)
+
+	for _, fork := range []ssz.Fork{ssz.ForkPhase0, ssz.ForkAltair, ssz.ForkBellatrix, ssz.ForkCapella} {
+		enc, err := state.MarshalSSZForFork(fork)
+		if err != nil {
+			t.Fatalf("%v: failed to encode: %v", fork, err)
+		}
+		var v spectests.VersionedBeaconState
+		err = ssz.DecodeFunc(bytes.NewReader(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+			return v.DecodeForDigest(digests, digestOf(digests, fork), s)
+		})
+		if err != nil {
+			t.Fatalf("%v: failed to decode: %v", fork, err)
+		}
+		if v.Fork != fork {
+			t.Fatalf("%v: fork mismatch: have %v", fork, v.Fork)
+		}
+		reenc, err := v.Bound().MarshalSSZ()
+		if err != nil {
+			t.Fatalf("%v: failed to re-encode: %v", fork, err)
+		}
+		if !bytes.Equal(reenc, enc) {
+			t.Fatalf("%v: re-encoding mismatch", fork)
+		}
+		have, err := v.Bound().HashTreeRoot()
+		if err != nil {
+			t.Fatalf("%v: failed to hash: %v", fork, err)
+		}
+		want, _ := state.HashTreeRootForFork(fork)
+		if have != want {
+			t.Fatalf("%v: root mismatch: have %x, want %x", fork, have, want)
+		}
+	}
+	enc, _ := state.MarshalSSZForFork(ssz.ForkCapella)
+	err := ssz.DecodeFunc(bytes.NewReader(enc), uint32(len(enc)), func(s *ssz.Stream) error {
+		return new(spectests.VersionedBeaconState).DecodeForFork(ssz.ForkDeneb, s)
+	})
+	if err != ssz.ErrUnknownFork {
+		t.Fatalf("error mismatch: have %v, want %v", err, ssz.ErrUnknownFork)
+	}
+}
+
+// Tests that the state at phase0 has the same encoding and root as the
+// standalone phase0 layout, leaving out the fields gated to the later forks.
+func TestForkGatedPhase0(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		state := new(spectests.BeaconState)
+		state.GenerateRandomSSZ(r, nil)
+
+		enc, err := state.MarshalSSZForFork(ssz.ForkPhase0)
+		if err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		phase0 := new(spectests.BeaconStatePhase0)
+		if err := ssz.Unmarshal(enc, phase0); err != nil {
+			t.Fatalf("failed to decode the phase0 layout: %v", err)
+		}
+		reenc, err := phase0.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("failed to encode the phase0 layout: %v", err)
+		}
+		if !bytes.Equal(reenc, enc) {
+			t.Fatalf("encoding mismatch with the phase0 layout")
+		}
+		have, err := state.HashTreeRootForFork(ssz.ForkPhase0)
+		if err != nil {
+			t.Fatalf("failed to hash: %v", err)
+		}
+		want, err := phase0.HashTreeRoot()
+		if err != nil {
+			t.Fatalf("failed to hash the phase0 layout: %v", err)
+		}
+		if have != want {
+			t.Fatalf("root mismatch with the phase0 layout: have %x, want %x", have, want)
+		}
+		// The fields of the later forks are neither encoded nor hashed
+		state.InactivityScores = append(state.InactivityScores, 1)
+		state.NextWithdrawalIndex++
+		state.CurrentEpochParticipation = append(state.CurrentEpochParticipation, 1)
+		if enc2, _ := state.MarshalSSZForFork(ssz.ForkPhase0); !bytes.Equal(enc2, enc) {
+			t.Fatalf("encoding changed by the fields of the later forks")
+		}
+		if root, _ := state.HashTreeRootForFork(ssz.ForkPhase0); root != want {
+			t.Fatalf("root changed by the fields of the later forks")
+		}
+		// And they are left empty decoding the phase0 encoding
+		dec := new(spectests.BeaconState)
+		if err := ssz.Unmarshal(enc, ssz.AtFork(dec, ssz.ForkPhase0)); err != nil {
+			t.Fatalf("failed to decode at phase0: %v", err)
+		}
+		if dec.InactivityScores != nil || dec.CurrentSyncCommittee != nil || dec.LatestExecutionPayloadHeaderCapella != nil || dec.NextWithdrawalIndex != 0 {
+			t.Fatalf("fields of the later forks set decoding at phase0")
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package ssz
+
+import (
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath    = errors.New("ssz: invalid path")
+	ErrGIndexOverflow = errors.New("ssz: generalized index overflows uint64")
+)
+
+// LengthName is the path element referring to the length of a list, which is
+// mixed in the root of the list.
+const LengthName = "__len__"
+
+// PathElem is a step of the path into a value, which is either a field name of
+// the container or an index into the vector or list.
+type PathElem struct {
+	Name  string // name of the field, empty for an index
+	Index uint64
+}
+
+// ParsePath parses the path in the form of "body.execution_payload.transactions[2]",
+// where the fields are separated by dots and the indexes are in brackets.
+func ParsePath(path string) ([]PathElem, error) {
+	var elems []PathElem
+	if path == "" {
+		return nil, nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		name, rest, indexed := strings.Cut(part, "[")
+		switch {
+		case name != "":
+			elems = append(elems, PathElem{Name: name})
+		case !indexed:
+			return nil, ErrInvalidPath // empty field name
+		}
+		for indexed {
+			index, remain, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, ErrInvalidPath
+			}
+			n, err := strconv.ParseUint(index, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidPath
+			}
+			elems = append(elems, PathElem{Index: n})
+			if remain == "" {
+				break
+			}
+			if !strings.HasPrefix(remain, "[") {
+				return nil, ErrInvalidPath
+			}
+			rest = remain[1:]
+		}
+	}
+	return elems, nil
+}
+
+// GIndexer is implemented by the containers resolving the paths into them.
+type GIndexer interface {
+	GIndexSSZ(path []PathElem) (uint64, error)
+}
+
+// GIndex returns the generalized index of the path into the container.
+func GIndex(obj GIndexer, path string) (uint64, error) {
+	elems, err := ParsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	return obj.GIndexSSZ(elems)
+}
+
+// ConcatGIndex returns the generalized index of the node at the index child in
+// the subtree rooted at the index parent.
+func ConcatGIndex(parent, child uint64) (uint64, error) {
+	if parent == 0 || child == 0 {
+		return 0, ErrInvalidPath
+	}
+	depth := bits.Len64(child) - 1
+	if bits.Len64(parent)+depth > 64 {
+		return 0, ErrGIndexOverflow
+	}
+	return parent<<depth | child&^(1<<depth), nil
+}
+
+// Resolver resolves the path into a value to the generalized index relative to
+// the root of the value.
+type Resolver func(path []PathElem) (uint64, error)
+
+// BasicGIndex resolves the paths into the basic values, which have no path
+// other than the empty one.
+func BasicGIndex(path []PathElem) (uint64, error) {
+	if len(path) != 0 {
+		return 0, ErrInvalidPath
+	}
+	return 1, nil
+}
+
+// treeDepth returns the depth of the tree with the given number of leaves.
+func treeDepth(leaves uint64) int {
+	if leaves <= 1 {
+		return 0
+	}
+	return bits.Len64(leaves - 1)
+}
+
+// itemGIndex resolves the path into the item with the given chunk in the tree
+// with the given number of chunks.
+func itemGIndex(path []PathElem, chunks uint64, chunk uint64, item Resolver) (uint64, error) {
+	if chunk >= chunks {
+		return 0, ErrInvalidPath
+	}
+	child, err := item(path)
+	if err != nil {
+		return 0, err
+	}
+	depth := treeDepth(chunks)
+	if depth >= 64 {
+		return 0, ErrGIndexOverflow
+	}
+	return ConcatGIndex(1<<depth|chunk, child)
+}
+
+// PackedVectorGIndex returns the resolver of the vector with n basic items of
+// the given bits, which are packed into the chunks.
+func PackedVectorGIndex(n uint64, bits uint64) Resolver {
+	chunks := (n*bits + 255) / 256
+	return func(path []PathElem) (uint64, error) {
+		if len(path) == 0 {
+			return 1, nil
+		}
+		if path[0].Name != "" || path[0].Index >= n {
+			return 0, ErrInvalidPath
+		}
+		return itemGIndex(path[1:], chunks, path[0].Index*bits/256, BasicGIndex)
+	}
+}
+
+// VectorGIndex returns the resolver of the vector with n composite items.
+func VectorGIndex(n uint64, elem Resolver) Resolver {
+	return func(path []PathElem) (uint64, error) {
+		if len(path) == 0 {
+			return 1, nil
+		}
+		if path[0].Name != "" || path[0].Index >= n {
+			return 0, ErrInvalidPath
+		}
+		return itemGIndex(path[1:], n, path[0].Index, elem)
+	}
+}
+
+// PackedListGIndex returns the resolver of the list with limit basic items of
+// the given bits, which are packed into the chunks. The items are under the
+// left child of the root, and the length is the right child.
+func PackedListGIndex(limit uint64, bits uint64) Resolver {
+	chunks := (limit*bits + 255) / 256
+	return listGIndex(func(path []PathElem) (uint64, error) {
+		if path[0].Name != "" || path[0].Index >= limit {
+			return 0, ErrInvalidPath
+		}
+		return itemGIndex(path[1:], chunks, path[0].Index*bits/256, BasicGIndex)
+	})
+}
+
+// ListGIndex returns the resolver of the list with limit composite items.
+func ListGIndex(limit uint64, elem Resolver) Resolver {
+	return listGIndex(func(path []PathElem) (uint64, error) {
+		if path[0].Name != "" || path[0].Index >= limit {
+			return 0, ErrInvalidPath
+		}
+		return itemGIndex(path[1:], limit, path[0].Index, elem)
+	})
+}
+
+// listGIndex resolves the length of the list, or the path into the items with
+// the given resolver of the tree of the items.
+func listGIndex(items Resolver) Resolver {
+	return func(path []PathElem) (uint64, error) {
+		switch {
+		case len(path) == 0:
+			return 1, nil
+		case path[0].Name == LengthName:
+			if len(path) != 1 {
+				return 0, ErrInvalidPath
+			}
+			return 3, nil
+		}
+		gindex, err := items(path)
+		if err != nil {
+			return 0, err
+		}
+		return ConcatGIndex(2, gindex)
+	}
+}
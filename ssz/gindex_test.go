@@ -0,0 +1,97 @@
+package ssz_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests the generalized index constants against the ones defined by the spec.
+func TestGIndexConstants(t *testing.T) {
+	tests := []struct {
+		name       string
+		have, want uint64
+	}{
+		{"FINALIZED_ROOT_GINDEX", spectests.GIndexBeaconStateFinalizedRoot, 105},
+		{"CURRENT_SYNC_COMMITTEE_GINDEX", spectests.GIndexBeaconStateCurrentSyncCommittee, 54},
+		{"NEXT_SYNC_COMMITTEE_GINDEX", spectests.GIndexBeaconStateNextSyncCommittee, 55},
+		{"EXECUTION_PAYLOAD_GINDEX", spectests.GIndexBeaconBlockBodyCapellaExecutionPayload, 25},
+	}
+	for _, tt := range tests {
+		if tt.have != tt.want {
+			t.Errorf("%s mismatch: have %d, want %d", tt.name, tt.have, tt.want)
+		}
+	}
+}
+
+// Tests the resolution of the paths into the state at the forks, and the proofs
+// of the nodes at the resolved indexes.
+func TestGIndexPaths(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = []uint64{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		path  string
+		forks []ssz.Fork
+		want  uint64
+	}{
+		{"finalized_checkpoint.root", []ssz.Fork{ssz.ForkPhase0, ssz.ForkAltair, ssz.ForkBellatrix, ssz.ForkCapella}, 105},
+		{"FinalizedCheckpoint.Root", []ssz.Fork{ssz.ForkAltair}, 105},
+		{"finalized_checkpoint", []ssz.Fork{ssz.ForkAltair}, 52},
+		{"next_sync_committee", []ssz.Fork{ssz.ForkAltair, ssz.ForkCapella}, 55},
+		{"current_epoch_attestations", []ssz.Fork{ssz.ForkPhase0}, 48},
+		{"current_epoch_participation", []ssz.Fork{ssz.ForkAltair}, 48},
+		{"balances.__len__", []ssz.Fork{ssz.ForkAltair}, 89},
+		{"balances[0]", []ssz.Fork{ssz.ForkAltair}, 88 << 38},
+		{"balances[5]", []ssz.Fork{ssz.ForkAltair}, 88<<38 | 1},
+		{"next_withdrawal_index", []ssz.Fork{ssz.ForkCapella}, 57},
+	}
+	trees := make(map[ssz.Fork]*ssz.Node)
+	for _, tt := range tests {
+		for _, fork := range tt.forks {
+			obj := ssz.AtFork(state, fork)
+			gindex, err := ssz.GIndex(obj, tt.path)
+			if err != nil {
+				t.Errorf("%s at %v: failed to resolve: %v", tt.path, fork, err)
+				continue
+			}
+			if gindex != tt.want {
+				t.Errorf("%s at %v: gindex mismatch: have %d, want %d", tt.path, fork, gindex, tt.want)
+			}
+			tree, ok := trees[fork]
+			if !ok {
+				if tree, err = ssz.BuildTree(obj); err != nil {
+					t.Fatalf("failed to build the tree: %v", err)
+				}
+				trees[fork] = tree
+			}
+			node, err := tree.Get(gindex)
+			if err != nil {
+				t.Errorf("%s at %v: failed to get the node: %v", tt.path, fork, err)
+				continue
+			}
+			branch, err := tree.Prove(gindex)
+			if err != nil {
+				t.Errorf("%s at %v: failed to prove: %v", tt.path, fork, err)
+				continue
+			}
+			if !ssz.VerifyProof(tree.Root(), node.Root(), branch, gindex) {
+				t.Errorf("%s at %v: failed to verify", tt.path, fork)
+			}
+		}
+	}
+	// The leaf of the finalized root is the root in the state
+	if node, _ := trees[ssz.ForkAltair].Get(spectests.GIndexBeaconStateFinalizedRoot); node.Root() != [32]byte(state.FinalizedCheckpoint.Root) {
+		t.Errorf("finalized root leaf mismatch: have %x, want %x", node.Root(), state.FinalizedCheckpoint.Root)
+	}
+	// The paths absent at the fork are rejected
+	for _, path := range []string{"next_sync_committee", "current_epoch_participation", "finalized_checkpoint.bogus", "bogus"} {
+		if _, err := ssz.GIndex(ssz.AtFork(state, ssz.ForkPhase0), path); err == nil {
+			t.Errorf("%s at phase0: resolved", path)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+var ErrHasherState = errors.New("ssz: hasher holds no single root")
+
+// zeroHashes[i] is the root of the all-zero subtree with depth i.
+var zeroHashes [65][32]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = sha256.Sum256(append(zeroHashes[i-1][:], zeroHashes[i-1][:]...))
+	}
+}
+
+// Hasher computes the hash tree roots. The chunks of the containers being
+// hashed are accumulated in a single buffer, and every merkleization collapses
+// the chunks from an index into their root, layer by layer in place.
+//
+// The hasher building the tree keeps the nodes of the chunks along with them,
+// see BuildTree.
+type Hasher struct {
+	buf   []byte
+	err   error
+	tree  bool    // whether the tree nodes are kept
+	nodes []*Node // nodes of the chunks in the buffer, synced lazily
+}
+
+var hasherPool = sync.Pool{
+	New: func() interface{} { return new(Hasher) },
+}
+
+// GetHasher retrieves a reset hasher from the pool.
+func GetHasher() *Hasher {
+	return hasherPool.Get().(*Hasher)
+}
+
+// PutHasher resets the hasher and returns it into the pool.
+func PutHasher(h *Hasher) {
+	h.buf, h.err = h.buf[:0], nil
+	h.tree, h.nodes = false, h.nodes[:0]
+	hasherPool.Put(h)
+}
+
+// Index returns the position where the chunks of the next value start.
+func (h *Hasher) Index() int {
+	return len(h.buf)
+}
+
+// HashRoot returns the root of the hashed value.
+func (h *Hasher) HashRoot() ([32]byte, error) {
+	var root [32]byte
+	if h.err != nil {
+		return root, h.err
+	}
+	if len(h.buf) != 32 {
+		return root, ErrHasherState
+	}
+	copy(root[:], h.buf)
+	return root, nil
+}
+
+// PutBool appends the boolean as a chunk.
+func (h *Hasher) PutBool(b bool) {
+	h.AppendBool(b)
+	h.FillUpTo32()
+}
+
+// PutUint8 appends the integer as a chunk.
+func (h *Hasher) PutUint8(i uint8) {
+	h.AppendUint8(i)
+	h.FillUpTo32()
+}
+
+// PutUint16 appends the integer as a chunk.
+func (h *Hasher) PutUint16(i uint16) {
+	h.AppendUint16(i)
+	h.FillUpTo32()
+}
+
+// PutUint32 appends the integer as a chunk.
+func (h *Hasher) PutUint32(i uint32) {
+	h.AppendUint32(i)
+	h.FillUpTo32()
+}
+
+// PutUint64 appends the integer as a chunk.
+func (h *Hasher) PutUint64(i uint64) {
+	h.AppendUint64(i)
+	h.FillUpTo32()
+}
+
+// PutBytes appends the root of the byte vector, which is the vector itself if
+// it fits in a chunk.
+func (h *Hasher) PutBytes(b []byte) {
+	if len(b) <= BytesPerChunk {
+		h.AppendBytes32(b)
+		return
+	}
+	indx := h.Index()
+	h.AppendBytes32(b)
+	h.Merkleize(indx)
+}
+
+// PutBytesN appends the root of the byte vector with length n. The vector is
+// padded with zeros if it's shorter, which is the case for the empty ones
+// regarded as the zero value.
+func (h *Hasher) PutBytesN(b []byte, n int) {
+	indx := h.Index()
+	h.buf = append(h.buf, b...)
+	h.buf = append(h.buf, make([]byte, n-len(b))...)
+	h.FillUpTo32()
+	if n > BytesPerChunk {
+		h.Merkleize(indx)
+	}
+}
+
+// PutBitlist appends the root of the bitlist, whose limit is counted in bits.
+// The bitlist is assumed to be well-formed.
+func (h *Hasher) PutBitlist(b []byte, limit uint64) {
+	size := BitlistLen(b)
+
+	// Append the bits without the delimiter, trimming the byte holding only
+	// the delimiter
+	indx := h.Index()
+	h.buf = append(h.buf, b[:(size+7)/BitsPerByte]...)
+	if size%BitsPerByte != 0 {
+		h.buf[len(h.buf)-1] &^= byte(1) << (size % BitsPerByte)
+	}
+	h.FillUpTo32()
+	h.MerkleizeWithMixin(indx, size, (limit+255)/256)
+}
+
+// AppendBool appends the packed boolean.
+func (h *Hasher) AppendBool(b bool) {
+	if b {
+		h.buf = append(h.buf, 1)
+	} else {
+		h.buf = append(h.buf, 0)
+	}
+}
+
+// AppendUint8 appends the packed integer.
+func (h *Hasher) AppendUint8(i uint8) {
+	h.buf = append(h.buf, i)
+}
+
+// AppendUint16 appends the packed integer.
+func (h *Hasher) AppendUint16(i uint16) {
+	h.buf = binary.LittleEndian.AppendUint16(h.buf, i)
+}
+
+// AppendUint32 appends the packed integer.
+func (h *Hasher) AppendUint32(i uint32) {
+	h.buf = binary.LittleEndian.AppendUint32(h.buf, i)
+}
+
+// AppendUint64 appends the packed integer.
+func (h *Hasher) AppendUint64(i uint64) {
+	h.buf = binary.LittleEndian.AppendUint64(h.buf, i)
+}
+
+// AppendBytes32 appends the bytes padded to the chunk boundary.
+func (h *Hasher) AppendBytes32(b []byte) {
+	h.buf = append(h.buf, b...)
+	h.FillUpTo32()
+}
+
+// HashItems appends the roots of the n composite items hashed by fn, which is
+// called back with the ranges of the items. The large ranges are split and
+// hashed in parallel, see SetParallelism.
+func (h *Hasher) HashItems(n int, fn ItemsHasher) error {
+	return h.hashChunks(n, fn)
+}
+
+// FillUpTo32 pads the packed values to the chunk boundary.
+func (h *Hasher) FillUpTo32() {
+	if rest := len(h.buf) % BytesPerChunk; rest != 0 {
+		h.buf = append(h.buf, zeroHashes[0][:BytesPerChunk-rest]...)
+	}
+}
+
+// Merkleize collapses the chunks since indx into their root.
+func (h *Hasher) Merkleize(indx int) {
+	h.merkleize(indx, uint64(len(h.buf)-indx)/BytesPerChunk)
+}
+
+// MerkleizeWithMixin collapses the chunks of the list since indx into their
+// root, with the list length mixed in. The limit is counted in chunks, zero
+// means the list is unbounded and the tree is not padded.
+func (h *Hasher) MerkleizeWithMixin(indx int, num uint64, limit uint64) {
+	h.merkleize(indx, limit)
+
+	var mixin [32]byte
+	binary.LittleEndian.PutUint64(mixin[:], num)
+	h.buf = append(h.buf, mixin[:]...)
+	root := hashPair(h.buf[indx:])
+	h.buf = append(h.buf[:indx], root[:]...)
+
+	if h.tree {
+		n := indx / BytesPerChunk
+		h.nodes = append(h.nodes[:n], &Node{root: root, left: h.nodes[n], right: &Node{root: mixin}})
+	}
+}
+
+// syncNodes adds the leaf nodes of the chunks appended since the last sync. The
+// chunks collapsed from the sub-trees have their nodes added already.
+func (h *Hasher) syncNodes() {
+	for i := len(h.nodes); i < len(h.buf)/BytesPerChunk; i++ {
+		var root [32]byte
+		copy(root[:], h.buf[i*BytesPerChunk:])
+		h.nodes = append(h.nodes, &Node{root: root})
+	}
+}
+
+// merkleize collapses the chunks since indx into the root of the tree with the
+// given number of leaves, padding the missing ones with zero.
+func (h *Hasher) merkleize(indx int, limit uint64) {
+	count := uint64(len(h.buf)-indx) / BytesPerChunk
+	if limit == 0 {
+		limit = count // unbounded list, no padding
+	}
+	if count > limit {
+		if h.err == nil {
+			h.err = ErrListTooBig
+		}
+		limit = count
+	}
+	var depth int
+	if limit > 1 {
+		depth = bits.Len64(limit - 1)
+	}
+	if h.tree {
+		h.syncNodes()
+	}
+	first := indx / BytesPerChunk // position of the first node
+	if count == 0 {
+		h.buf = append(h.buf[:indx], zeroHashes[depth][:]...)
+		if h.tree {
+			h.nodes = append(h.nodes[:first], zeroNodes[depth])
+		}
+		return
+	}
+	for i := 0; i < depth; i++ {
+		if count%2 == 1 {
+			h.buf = append(h.buf, zeroHashes[i][:]...)
+			if h.tree {
+				h.nodes = append(h.nodes, zeroNodes[i])
+			}
+			count += 1
+		}
+		layer := h.buf[indx:]
+		if h.tree {
+			for j := uint64(0); j < count/2; j++ {
+				root := hashPair(layer[j*64 : j*64+64])
+				copy(layer[j*32:], root[:])
+
+				nodes := h.nodes[first:]
+				nodes[j] = &Node{root: root, left: nodes[2*j], right: nodes[2*j+1]}
+			}
+		} else {
+			hashLayer(layer, int(count/2))
+		}
+		count /= 2
+		h.buf = h.buf[:indx+int(count)*BytesPerChunk]
+		if h.tree {
+			h.nodes = h.nodes[:first+int(count)]
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ssz
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+var (
+	ErrUintOverflow   = errors.New("ssz: integer overflows the type")
+	ErrMissingPrefix  = errors.New("ssz: hex string without 0x prefix")
+	ErrInvalidUint256 = errors.New("ssz: invalid uint256 decimal")
+)
+
+// JSONUint is an unsigned integer in the consensus JSON form, which is the
+// quoted decimal string.
+type JSONUint uint64
+
+func (u JSONUint) MarshalText() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(u), 10), nil
+}
+
+func (u *JSONUint) UnmarshalText(input []byte) error {
+	v, err := strconv.ParseUint(string(input), 10, 64)
+	if err != nil {
+		return err
+	}
+	*u = JSONUint(v)
+	return nil
+}
+
+// JSONBytes is a byte sequence in the consensus JSON form, which is the 0x
+// prefixed hex string. Bitlists are represented in the same form, including
+// the delimiter bit.
+type JSONBytes []byte
+
+func (b JSONBytes) MarshalText() ([]byte, error) {
+	enc := make([]byte, len(b)*2+2)
+	copy(enc, "0x")
+	hex.Encode(enc[2:], b)
+	return enc, nil
+}
+
+func (b *JSONBytes) UnmarshalText(input []byte) error {
+	if len(input) < 2 || input[0] != '0' || (input[1] != 'x' && input[1] != 'X') {
+		return ErrMissingPrefix
+	}
+	dec := make([]byte, hex.DecodedLen(len(input)-2))
+	if _, err := hex.Decode(dec, input[2:]); err != nil {
+		return err
+	}
+	*b = dec
+	return nil
+}
+
+// JSONUint256 is a 256-bit unsigned integer in the consensus JSON form, which
+// is the quoted decimal string. The value is held in the ssz form, which is 32
+// bytes in little-endian order.
+type JSONUint256 []byte
+
+func (u JSONUint256) MarshalText() ([]byte, error) {
+	be := make([]byte, len(u))
+	for i, b := range u {
+		be[len(u)-1-i] = b
+	}
+	return new(big.Int).SetBytes(be).Append(nil, 10), nil
+}
+
+func (u *JSONUint256) UnmarshalText(input []byte) error {
+	// Only the plain decimal digits are accepted, big.Int would take the
+	// sign too.
+	if len(input) == 0 {
+		return ErrInvalidUint256
+	}
+	for _, c := range input {
+		if c < '0' || c > '9' {
+			return ErrInvalidUint256
+		}
+	}
+	v, ok := new(big.Int).SetString(string(input), 10)
+	if !ok {
+		return ErrInvalidUint256
+	}
+	if v.BitLen() > 256 {
+		return ErrUintOverflow
+	}
+	le := v.FillBytes(make([]byte, 32))
+	for i, j := 0, len(le)-1; i < j; i, j = i+1, j-1 {
+		le[i], le[j] = le[j], le[i]
+	}
+	*u = le
+	return nil
+}
@@ -0,0 +1,99 @@
+package ssz_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests that the uint256 decimals are parsed with the plain digits only.
+func TestJSONUint256(t *testing.T) {
+	tests := []struct {
+		input string
+		want  byte // lowest byte of the value
+		err   error
+	}{
+		{"0", 0, nil},
+		{"258", 2, nil},
+		{"007", 7, nil},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 0xff, nil},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639936", 0, ssz.ErrUintOverflow},
+		{"", 0, ssz.ErrInvalidUint256},
+		{"+1", 0, ssz.ErrInvalidUint256},
+		{"-1", 0, ssz.ErrInvalidUint256},
+		{"1_000", 0, ssz.ErrInvalidUint256},
+		{" 1", 0, ssz.ErrInvalidUint256},
+		{"0x10", 0, ssz.ErrInvalidUint256},
+	}
+	for _, tt := range tests {
+		var u ssz.JSONUint256
+		err := u.UnmarshalText([]byte(tt.input))
+		if err != tt.err {
+			t.Errorf("%q: error mismatch: have %v, want %v", tt.input, err, tt.err)
+			continue
+		}
+		if err == nil && (len(u) != 32 || u[0] != tt.want) {
+			t.Errorf("%q: value mismatch: have %x", tt.input, []byte(u))
+		}
+	}
+}
+
+// Tests the json of the containers against the beacon-API samples, with the
+// uints as quoted decimals, the bytes as 0x prefixed hex and the uint256 as a
+// quoted decimal.
+func TestJSONGolden(t *testing.T) {
+	checkpoint := &spectests.Checkpoint{Epoch: 12345, Root: make([]byte, 32)}
+	checkpoint.Root[0], checkpoint.Root[31] = 0xab, 0xcd
+	checkJSON(t, checkpoint, new(spectests.Checkpoint),
+		`{"epoch":"12345","root":"0xab000000000000000000000000000000000000000000000000000000000000cd"}`)
+
+	payload := &spectests.ExecutionPayload{
+		BlockNumber:  1,
+		GasLimit:     30000000,
+		GasUsed:      21000,
+		Timestamp:    1681338455,
+		ExtraData:    []byte{0xde, 0xad},
+		Transactions: [][]byte{{0x01, 0x02}, {}},
+	}
+	payload.ParentHash[0] = 0x11
+	payload.FeeRecipient[19] = 0x22
+	payload.BaseFeePerGas[0], payload.BaseFeePerGas[1] = 0x00, 0x01 // 256 in little-endian
+	checkJSON(t, payload, new(spectests.ExecutionPayload),
+		`{"parent_hash":"0x1100000000000000000000000000000000000000000000000000000000000000",`+
+			`"fee_recipient":"0x0000000000000000000000000000000000000022",`+
+			`"state_root":"0x0000000000000000000000000000000000000000000000000000000000000000",`+
+			`"receipts_root":"0x0000000000000000000000000000000000000000000000000000000000000000",`+
+			`"logs_bloom":"0x`+strings.Repeat("00", 256)+`",`+
+			`"prev_randao":"0x0000000000000000000000000000000000000000000000000000000000000000",`+
+			`"block_number":"1","gas_limit":"30000000","gas_used":"21000","timestamp":"1681338455",`+
+			`"extra_data":"0xdead","base_fee_per_gas":"256",`+
+			`"block_hash":"0x0000000000000000000000000000000000000000000000000000000000000000",`+
+			`"transactions":["0x0102","0x"]}`)
+}
+
+// checkJSON checks the json of the object against the sample, and decoding the
+// sample back into the other object.
+func checkJSON[T interface {
+	json.Marshaler
+	json.Unmarshaler
+	Equal(T) bool
+}](t *testing.T, obj T, dec T, want string) {
+	t.Helper()
+
+	have, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if string(have) != want {
+		t.Fatalf("json mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if err := json.Unmarshal([]byte(want), dec); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if !dec.Equal(obj) {
+		t.Fatalf("decoded object mismatch")
+	}
+}
@@ -0,0 +1,260 @@
+package ssz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Container is the encoding of a container in a reader, for decoding the fields
+// one by one without the rest, e.g. a few fields of a large state in a file.
+type Container struct {
+	r    io.ReaderAt
+	buf  []byte // encoding in memory, if the container is created from bytes
+	size int64
+}
+
+// Field is the layout of a field in the encoding of a container.
+type Field struct {
+	Offset int  // position of the field in the fixed part, or its offset if variable-size
+	Size   int  // size of the field if fixed-size, zero if variable-size
+	First  bool // whether the field is the first variable-size one
+	Prev   int  // position of the offset of the previous variable-size field, if not the first
+	Next   int  // position of the offset of the next variable-size field, zero if none
+	Fixed  int  // size of the fixed part of the container, zero if the field is absent
+}
+
+// Items is the layout of the items in the encoding of a list or vector field.
+type Items struct {
+	Size int    // size of the items if fixed-size, zero if variable-size
+	Max  uint64 // length of the vector or limit of the list, zero if unbounded
+	List bool   // whether the number of items is up to the limit
+}
+
+// NewContainer creates the container of the encoding with the given size in the
+// reader.
+func NewContainer(r io.ReaderAt, size int64) *Container {
+	return &Container{r: r, size: size}
+}
+
+// NewContainerBytes creates the container of the encoding in memory, the bytes
+// of which are returned as the views into it without copying.
+func NewContainerBytes(buf []byte) *Container {
+	return &Container{r: bytes.NewReader(buf), buf: buf, size: int64(len(buf))}
+}
+
+// Section returns the position and the size of the encoding of the field. The
+// offset of the variable-size field is checked against the ones around it, the
+// first one pointing right past the fixed part and the others in order.
+func (c *Container) Section(f Field) (int64, int64, error) {
+	if f.Fixed == 0 {
+		return 0, 0, ErrUnknownField
+	}
+	if c.size < int64(f.Fixed) {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if f.Size != 0 {
+		return int64(f.Offset), int64(f.Size), nil
+	}
+	start, err := c.offset(int64(f.Offset))
+	if err != nil {
+		return 0, 0, err
+	}
+	if f.First && start != int64(f.Fixed) {
+		return 0, 0, ErrInvalidOffset
+	}
+	if !f.First {
+		prev, err := c.offset(int64(f.Prev))
+		if err != nil {
+			return 0, 0, err
+		}
+		if start < prev {
+			return 0, 0, ErrInvalidOffset
+		}
+	}
+	end := c.size
+	if f.Next != 0 {
+		if end, err = c.offset(int64(f.Next)); err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < int64(f.Fixed) || start > end || end > c.size {
+		return 0, 0, ErrInvalidOffset
+	}
+	return start, end - start, nil
+}
+
+// offset reads the offset at the position.
+func (c *Container) offset(pos int64) (int64, error) {
+	var buf [BytesPerLengthOffset]byte
+	if err := readAt(c.r, buf[:], pos); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(buf[:])), nil
+}
+
+// Bytes returns the encoding of the field.
+func (c *Container) Bytes(f Field) ([]byte, error) {
+	start, size, err := c.Section(f)
+	if err != nil {
+		return nil, err
+	}
+	return c.read(start, size)
+}
+
+// read returns the bytes of the section, which is the view into the encoding in
+// memory or the copy read from the reader.
+func (c *Container) read(start int64, size int64) ([]byte, error) {
+	if c.buf != nil {
+		return c.buf[start : start+size : start+size], nil
+	}
+	buf := make([]byte, size)
+	if err := readAt(c.r, buf, start); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readAt fills buf from the position of the reader, which may report EOF along
+// with the bytes read to the end.
+func readAt(r io.ReaderAt, buf []byte, pos int64) error {
+	n, err := r.ReadAt(buf, pos)
+	if n == len(buf) {
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decode decodes the field with fn from the stream of its encoding, which must
+// be consumed entirely.
+func (c *Container) Decode(f Field, fn func(s *Stream) error) error {
+	start, size, err := c.Section(f)
+	if err != nil {
+		return err
+	}
+	return c.decode(start, size, fn)
+}
+
+// decode decodes the section with fn, which must consume it entirely.
+func (c *Container) decode(start int64, size int64, fn func(s *Stream) error) error {
+	s := sectionStream(c.r, start, size)
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.finish()
+}
+
+// Len returns the number of items in the list or vector field, which is derived
+// from the size of the field for the fixed-size items, or the first offset for
+// the variable-size ones.
+func (c *Container) Len(f Field, items Items) (int, error) {
+	start, size, err := c.Section(f)
+	if err != nil {
+		return 0, err
+	}
+	return c.count(start, size, items)
+}
+
+func (c *Container) count(start int64, size int64, items Items) (int, error) {
+	var n int64
+	if items.Size != 0 {
+		if size%int64(items.Size) != 0 {
+			return 0, ErrSizeMismatch
+		}
+		n = size / int64(items.Size)
+	} else if size != 0 {
+		first, err := c.offset(start)
+		if err != nil {
+			return 0, err
+		}
+		if first == 0 || first%BytesPerLengthOffset != 0 || first > size {
+			return 0, ErrInvalidOffset
+		}
+		n = first / BytesPerLengthOffset
+	}
+	if !items.List && uint64(n) != items.Max {
+		return 0, ErrSizeMismatch
+	}
+	if items.Max != 0 && uint64(n) > items.Max {
+		return 0, ErrListTooBig
+	}
+	return int(n), nil
+}
+
+// ItemSection returns the position and the size of the encoding of the item i
+// in the list or vector field. The fixed-size items are located by the stride,
+// and the variable-size ones by the offsets.
+func (c *Container) ItemSection(f Field, items Items, i int) (int64, int64, error) {
+	start, size, err := c.Section(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := c.count(start, size, items)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i < 0 || i >= n {
+		return 0, 0, ErrIndexOutOfRange
+	}
+	if items.Size != 0 {
+		return start + int64(i*items.Size), int64(items.Size), nil
+	}
+	from, err := c.offset(start + int64(i*BytesPerLengthOffset))
+	if err != nil {
+		return 0, 0, err
+	}
+	if i > 0 {
+		prev, err := c.offset(start + int64((i-1)*BytesPerLengthOffset))
+		if err != nil {
+			return 0, 0, err
+		}
+		if from < prev {
+			return 0, 0, ErrInvalidOffset
+		}
+	}
+	to := size
+	if i+1 < n {
+		if to, err = c.offset(start + int64((i+1)*BytesPerLengthOffset)); err != nil {
+			return 0, 0, err
+		}
+	}
+	if from < int64(n*BytesPerLengthOffset) || from > to || to > size {
+		return 0, 0, ErrInvalidOffset
+	}
+	return start + from, to - from, nil
+}
+
+// ItemBytes returns the encoding of the item i in the list or vector field.
+func (c *Container) ItemBytes(f Field, items Items, i int) ([]byte, error) {
+	start, size, err := c.ItemSection(f, items, i)
+	if err != nil {
+		return nil, err
+	}
+	return c.read(start, size)
+}
+
+// DecodeItem decodes the item i in the list or vector field with fn from the
+// stream of its encoding, which must be consumed entirely.
+func (c *Container) DecodeItem(f Field, items Items, i int, fn func(s *Stream) error) error {
+	start, size, err := c.ItemSection(f, items, i)
+	if err != nil {
+		return err
+	}
+	return c.decode(start, size, fn)
+}
+
+// sectionStream creates the stream bounded to the section of the reader.
+func sectionStream(r io.ReaderAt, start int64, size int64) *Stream {
+	buffer := 4096
+	if size < int64(buffer) {
+		buffer = int(size)
+	}
+	return &Stream{
+		reader: bufio.NewReaderSize(io.NewSectionReader(r, start, size), buffer),
+		frames: []frame{{end: uint32(size), bounded: true}},
+	}
+}
@@ -0,0 +1,268 @@
+package ssz_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// lazyState returns a random state with a few items in each of the lists, along
+// with its encoding at the fork.
+func lazyState(t *testing.T, fork ssz.Fork) (*spectests.BeaconState, []byte) {
+	t.Helper()
+
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	for {
+		state.GenerateRandomSSZ(r, nil)
+		if len(state.PreviousEpochAttestations) >= 3 && len(state.Eth1DataVotes) > 0 && len(state.HistoricalSummaries) > 0 {
+			break
+		}
+	}
+	state.Validators = make([]*spectests.Validator, 10)
+	for i := range state.Validators {
+		state.Validators[i] = new(spectests.Validator)
+		state.Validators[i].GenerateRandomSSZ(r, nil)
+	}
+	enc, err := state.MarshalSSZForFork(fork)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	dec := new(spectests.BeaconState)
+	if err := ssz.Unmarshal(enc, ssz.AtFork(dec, fork)); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	return dec, enc
+}
+
+// Tests that the fields read lazily from the encoding are the same as the ones
+// of the state decoded in full, from the bytes and from the reader alike.
+func TestLazyFields(t *testing.T) {
+	type field struct {
+		name string
+		read func() (any, error)
+		want any
+	}
+	for _, fork := range []ssz.Fork{ssz.ForkPhase0, ssz.ForkCapella} {
+		state, enc := lazyState(t, fork)
+		readers := map[string]*spectests.BeaconStateReader{
+			"bytes":  spectests.NewBeaconStateReaderBytes(enc, fork),
+			"reader": spectests.NewBeaconStateReader(bytes.NewReader(enc), int64(len(enc)), fork),
+		}
+		for name, reader := range readers {
+			fields := []field{
+				{"slot", func() (any, error) { return reader.Slot() }, state.Slot},
+				{"fork", func() (any, error) { return reader.Fork() }, state.Fork},
+				{"block roots", func() (any, error) { return reader.BlockRoots() }, state.BlockRoots},
+				{"historical roots", func() (any, error) { return reader.HistoricalRoots() }, state.HistoricalRoots},
+				{"eth1 data votes", func() (any, error) { return reader.Eth1DataVotes() }, state.Eth1DataVotes},
+				{"validators", func() (any, error) { return reader.Validators() }, state.Validators},
+				{"balances", func() (any, error) { return reader.Balances() }, state.Balances},
+				{"finalized checkpoint", func() (any, error) { return reader.FinalizedCheckpoint() }, state.FinalizedCheckpoint},
+			}
+			if fork == ssz.ForkPhase0 {
+				fields = append(fields, field{"previous epoch attestations", func() (any, error) { return reader.PreviousEpochAttestations() }, state.PreviousEpochAttestations})
+			} else {
+				fields = append(fields, []field{
+					{"current epoch participation", func() (any, error) { return reader.CurrentEpochParticipation() }, state.CurrentEpochParticipation},
+					{"inactivity scores", func() (any, error) { return reader.InactivityScores() }, state.InactivityScores},
+					{"payload header", func() (any, error) { return reader.LatestExecutionPayloadHeaderCapella() }, state.LatestExecutionPayloadHeaderCapella},
+					{"historical summaries", func() (any, error) { return reader.HistoricalSummaries() }, state.HistoricalSummaries},
+				}...)
+			}
+			for _, field := range fields {
+				have, err := field.read()
+				if err != nil {
+					t.Errorf("%v %s: %s: failed to read: %v", fork, name, field.name, err)
+					continue
+				}
+				if !reflect.DeepEqual(have, field.want) {
+					t.Errorf("%v %s: %s mismatch: have %v, want %v", fork, name, field.name, have, field.want)
+				}
+			}
+		}
+	}
+	// The fields absent at the fork are rejected
+	_, enc := lazyState(t, ssz.ForkPhase0)
+	if _, err := spectests.NewBeaconStateReaderBytes(enc, ssz.ForkPhase0).InactivityScores(); err != ssz.ErrUnknownField {
+		t.Errorf("absent field: error mismatch: have %v, want %v", err, ssz.ErrUnknownField)
+	}
+}
+
+// Tests that the malformed offsets of the fields and the items are rejected by
+// the lazy reads, same as by the full decoding.
+func TestLazyMalformedOffsets(t *testing.T) {
+	_, enc := lazyState(t, ssz.ForkPhase0)
+
+	// The offsets of the first variable-size fields at phase0: the historical
+	// roots after the vectors of the roots, and the eth1 data votes after the
+	// eth1 data.
+	const (
+		historicalRoots = 8 + 32 + 8 + 16 + 112 + 2*8192*32
+		eth1DataVotes   = historicalRoots + 4 + 72
+		validators      = eth1DataVotes + 4 + 8
+		attestations    = validators + 4 + 4 + 65536*32 + 8192*8
+	)
+	var (
+		offset = func(enc []byte, pos int) uint32 {
+			return binary.LittleEndian.Uint32(enc[pos:])
+		}
+		modify = func(pos int, value uint32) []byte {
+			cpy := append([]byte{}, enc...)
+			binary.LittleEndian.PutUint32(cpy[pos:], value)
+			return cpy
+		}
+		table = int(offset(enc, attestations)) // offsets of the attestations
+	)
+	tests := []struct {
+		name string
+		enc  []byte
+		read func(r *spectests.BeaconStateReader) error
+	}{
+		{
+			"first offset past the fixed part",
+			modify(historicalRoots, offset(enc, historicalRoots)+4),
+			func(r *spectests.BeaconStateReader) error { _, err := r.HistoricalRoots(); return err },
+		},
+		{
+			"first offset in the fixed part",
+			modify(historicalRoots, offset(enc, historicalRoots)-4),
+			func(r *spectests.BeaconStateReader) error { _, err := r.HistoricalRoots(); return err },
+		},
+		{
+			"offset before the previous one",
+			modify(validators, offset(enc, eth1DataVotes)-1),
+			func(r *spectests.BeaconStateReader) error { _, err := r.Validators(); return err },
+		},
+		{
+			"offset after the next one",
+			modify(eth1DataVotes, offset(enc, validators)+1),
+			func(r *spectests.BeaconStateReader) error { _, err := r.Eth1DataVotes(); return err },
+		},
+		{
+			"offset beyond the end",
+			modify(validators, uint32(len(enc)+1)),
+			func(r *spectests.BeaconStateReader) error { _, err := r.Validators(); return err },
+		},
+		{
+			"item offset before the previous one",
+			modify(table+8, offset(enc, table+4)-1),
+			func(r *spectests.BeaconStateReader) error { _, err := r.PreviousEpochAttestationsAt(2); return err },
+		},
+		{
+			"item offset after the next one",
+			modify(table+4, offset(enc, table+8)+1),
+			func(r *spectests.BeaconStateReader) error { _, err := r.PreviousEpochAttestationsAt(1); return err },
+		},
+		{
+			"item offset in the offsets",
+			modify(table, offset(enc, table)-1),
+			func(r *spectests.BeaconStateReader) error { _, err := r.LenPreviousEpochAttestations(); return err },
+		},
+	}
+	for _, tt := range tests {
+		if err := tt.read(spectests.NewBeaconStateReaderBytes(tt.enc, ssz.ForkPhase0)); !errors.Is(err, ssz.ErrInvalidOffset) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, ssz.ErrInvalidOffset)
+		}
+		// The full decoding rejects them too
+		if err := ssz.Unmarshal(tt.enc, ssz.AtFork(new(spectests.BeaconState), ssz.ForkPhase0)); err == nil {
+			t.Errorf("%s: decoded in full", tt.name)
+		}
+	}
+}
+
+// Tests that the items of the lists and vectors read one by one are the same as
+// the ones of the decoded state, with the indexes out of range rejected.
+func TestLazyItems(t *testing.T) {
+	state, enc := lazyState(t, ssz.ForkCapella)
+	reader := spectests.NewBeaconStateReader(bytes.NewReader(enc), int64(len(enc)), ssz.ForkCapella)
+
+	if n, err := reader.LenValidators(); err != nil || n != len(state.Validators) {
+		t.Fatalf("validators length mismatch: have %d (%v), want %d", n, err, len(state.Validators))
+	}
+	for i, want := range state.Validators {
+		have, err := reader.ValidatorsAt(i)
+		if err != nil {
+			t.Fatalf("validator %d: failed to read: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("validator %d: mismatch: have %v, want %v", i, have, want)
+		}
+		blob, err := reader.ValidatorsBytesAt(i)
+		if err != nil {
+			t.Fatalf("validator %d: failed to read the bytes: %v", i, err)
+		}
+		if wantBlob, _ := want.MarshalSSZ(); !bytes.Equal(blob, wantBlob) {
+			t.Fatalf("validator %d: bytes mismatch: have %x, want %x", i, blob, wantBlob)
+		}
+	}
+	if n, err := reader.LenBlockRoots(); err != nil || n != 8192 {
+		t.Fatalf("block roots length mismatch: have %d (%v), want 8192", n, err)
+	}
+	if have, err := reader.BlockRootsAt(8191); err != nil || !bytes.Equal(have, state.BlockRoots[8191]) {
+		t.Fatalf("block root mismatch: have %x (%v), want %x", have, err, state.BlockRoots[8191])
+	}
+	if have, err := reader.BalancesAt(len(state.Balances) - 1); err != nil || have != state.Balances[len(state.Balances)-1] {
+		t.Fatalf("balance mismatch: have %d (%v), want %d", have, err, state.Balances[len(state.Balances)-1])
+	}
+	// The indexes out of range are rejected
+	for name, read := range map[string]func() error{
+		"negative validator": func() error { _, err := reader.ValidatorsAt(-1); return err },
+		"validator":          func() error { _, err := reader.ValidatorsAt(len(state.Validators)); return err },
+		"validator bytes":    func() error { _, err := reader.ValidatorsBytesAt(len(state.Validators)); return err },
+		"balance":            func() error { _, err := reader.BalancesAt(len(state.Balances)); return err },
+		"block root":         func() error { _, err := reader.BlockRootsAt(8192); return err },
+	} {
+		if err := read(); err != ssz.ErrIndexOutOfRange {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, ssz.ErrIndexOutOfRange)
+		}
+	}
+}
+
+// Tests that the variable-size items are located by the offset table of the
+// list, each item read from the position its offset points to.
+func TestLazyVariableItems(t *testing.T) {
+	state, enc := lazyState(t, ssz.ForkPhase0)
+	reader := spectests.NewBeaconStateReaderBytes(enc, ssz.ForkPhase0)
+
+	// The offset of the previous epoch attestations at phase0, following the
+	// randao mixes and the slashings.
+	const attestations = 8 + 32 + 8 + 16 + 112 + 2*8192*32 + 4 + 72 + 4 + 8 + 4 + 4 + 65536*32 + 8192*8
+	var (
+		items = state.PreviousEpochAttestations
+		table = int(binary.LittleEndian.Uint32(enc[attestations:]))
+	)
+	if n, err := reader.LenPreviousEpochAttestations(); err != nil || n != len(items) {
+		t.Fatalf("attestations length mismatch: have %d (%v), want %d", n, err, len(items))
+	}
+	for i, want := range items {
+		have, err := reader.PreviousEpochAttestationsAt(i)
+		if err != nil {
+			t.Fatalf("attestation %d: failed to read: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("attestation %d: mismatch", i)
+		}
+		blob, err := reader.PreviousEpochAttestationsBytesAt(i)
+		if err != nil {
+			t.Fatalf("attestation %d: failed to read the bytes: %v", i, err)
+		}
+		wantBlob, _ := want.MarshalSSZ()
+		if !bytes.Equal(blob, wantBlob) {
+			t.Fatalf("attestation %d: bytes mismatch: have %x, want %x", i, blob, wantBlob)
+		}
+		pos := table + int(binary.LittleEndian.Uint32(enc[table+4*i:]))
+		if !bytes.Equal(enc[pos:pos+len(wantBlob)], wantBlob) {
+			t.Fatalf("attestation %d: not at its offset", i)
+		}
+	}
+	if _, err := reader.PreviousEpochAttestationsAt(len(items)); err != ssz.ErrIndexOutOfRange {
+		t.Fatalf("error mismatch: have %v, want %v", err, ssz.ErrIndexOutOfRange)
+	}
+}
@@ -0,0 +1,156 @@
+package ssz
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallelism configures the hashing of the large trees across goroutines.
+// The roots are identical to the ones hashed serially.
+type Parallelism struct {
+	Threshold int // minimum number of chunks hashed in parallel, zero disables it
+	Workers   int // maximum number of goroutines hashing at the same time
+}
+
+// DefaultParallelism hashes the trees with 4096 chunks or more over all cores.
+var DefaultParallelism = Parallelism{
+	Threshold: 4096,
+	Workers:   runtime.GOMAXPROCS(0),
+}
+
+// workerPool is the configured parallelism along with the slots of the
+// goroutines running.
+type workerPool struct {
+	Parallelism
+	slots chan struct{}
+}
+
+var pool atomic.Pointer[workerPool]
+
+func init() {
+	SetParallelism(DefaultParallelism)
+}
+
+// SetParallelism replaces the parallelism of the hashing. The goroutines in
+// flight are still counted in the old budget until they finish.
+func SetParallelism(p Parallelism) {
+	if p.Workers < 1 {
+		p.Workers = 1
+	}
+	pool.Store(&workerPool{Parallelism: p, slots: make(chan struct{}, p.Workers-1)})
+}
+
+// parallel returns the worker pool if the number of chunks is large enough to
+// be hashed in parallel, or nil otherwise.
+func parallel(chunks int) *workerPool {
+	p := pool.Load()
+	if p.Threshold == 0 || p.Workers == 1 || chunks < p.Threshold {
+		return nil
+	}
+	return p
+}
+
+// run splits the range [0, n) into the segments and runs fn on them, in the
+// goroutines as long as the budget allows and in the calling one otherwise.
+func (p *workerPool) run(n int, fn func(from, to int)) {
+	var (
+		wg   sync.WaitGroup
+		size = (n + p.Workers - 1) / p.Workers
+	)
+	for from := 0; from < n; from += size {
+		to := from + size
+		if to > n {
+			to = n
+		}
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func(from, to int) {
+				defer func() {
+					<-p.slots
+					wg.Done()
+				}()
+				fn(from, to)
+			}(from, to)
+		default:
+			fn(from, to)
+		}
+	}
+	wg.Wait()
+}
+
+// hashChunks appends the n chunks produced by fn into the hasher. The ranges
+// of the chunks are hashed by the sub-hashers in parallel if n is large enough,
+// each of which must produce a chunk per position.
+func (h *Hasher) hashChunks(n int, fn ItemsHasher) error {
+	p := parallel(n)
+	if p == nil || h.tree {
+		return fn(h, 0, n)
+	}
+	var (
+		indx = len(h.buf)
+		lock sync.Mutex
+		fail error
+	)
+	h.buf = append(h.buf, make([]byte, n*BytesPerChunk)...)
+	p.run(n, func(from, to int) {
+		sub := GetHasher()
+		defer PutHasher(sub)
+
+		err := fn(sub, from, to)
+		if err == nil {
+			err = sub.err
+		}
+		if err == nil && len(sub.buf) != (to-from)*BytesPerChunk {
+			err = ErrHasherState
+		}
+		if err != nil {
+			lock.Lock()
+			if fail == nil {
+				fail = err
+			}
+			lock.Unlock()
+			return
+		}
+		copy(h.buf[indx+from*BytesPerChunk:], sub.buf)
+	})
+	if fail != nil {
+		h.buf = h.buf[:indx]
+		return fail
+	}
+	return nil
+}
+
+// pairsPerBatch is the number of pairs handed to the pair hasher at once while
+// hashing a layer serially.
+const pairsPerBatch = 64
+
+// hashLayer hashes the pairs of the chunks in the layer into the parents, in
+// parallel if the layer is large enough. The parents are written from the
+// start of the layer.
+func hashLayer(layer []byte, parents int) {
+	p := parallel(parents)
+	if p == nil {
+		// Hash the pairs batch by batch aside, as the pair hashers take no
+		// overlapping buffers. The parents of a batch land on the pairs of
+		// the batches hashed already.
+		var out [pairsPerBatch * BytesPerChunk]byte
+		for from := 0; from < parents; from += pairsPerBatch {
+			to := from + pairsPerBatch
+			if to > parents {
+				to = parents
+			}
+			hashPairs(out[:(to-from)*32], layer[from*64:to*64])
+			copy(layer[from*32:], out[:(to-from)*32])
+		}
+		return
+	}
+	// The parents overlap the pairs of the others being hashed, collect them
+	// aside before writing back
+	out := make([]byte, parents*BytesPerChunk)
+	p.run(parents, func(from, to int) {
+		hashPairs(out[from*32:to*32], layer[from*64:to*64])
+	})
+	copy(layer, out)
+}
@@ -0,0 +1,78 @@
+package ssz_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests that the roots of a large state hashed in parallel are the same as the
+// ones hashed serially, over a range of worker counts and thresholds. Run with
+// -race to check the sub-hashers don't share any state.
+func TestParallelHashing(t *testing.T) {
+	t.Cleanup(func() { ssz.SetParallelism(ssz.DefaultParallelism) })
+
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+
+	state.Validators = make([]*spectests.Validator, 30000)
+	for i := range state.Validators {
+		state.Validators[i] = new(spectests.Validator)
+		state.Validators[i].GenerateRandomSSZ(r, nil)
+	}
+	state.Balances = make([]uint64, 50000)
+	for i := range state.Balances {
+		state.Balances[i] = r.Uint64()
+	}
+	state.InactivityScores = state.Balances[:30000]
+
+	// The phase0 layout has no hash cache, so its lists are hashed along with
+	// the tree layers by the hasher itself
+	enc, err := state.MarshalSSZForFork(ssz.ForkPhase0)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	phase0 := new(spectests.BeaconStatePhase0)
+	if err := ssz.Unmarshal(enc, phase0); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	roots := func() ([32]byte, [32]byte) {
+		t.Helper()
+
+		have, err := state.Copy().HashTreeRootForFork(ssz.ForkCapella)
+		if err != nil {
+			t.Fatalf("failed to hash the state: %v", err)
+		}
+		have0, err := phase0.HashTreeRoot()
+		if err != nil {
+			t.Fatalf("failed to hash the phase0 state: %v", err)
+		}
+		return have, have0
+	}
+	ssz.SetParallelism(ssz.Parallelism{})
+	want, want0 := roots()
+
+	for _, p := range []ssz.Parallelism{
+		{Threshold: 1, Workers: 2},
+		{Threshold: 64, Workers: 3},
+		{Threshold: 1024, Workers: 4},
+		{Threshold: 4096, Workers: 8},
+		{Threshold: 1 << 20, Workers: 8},
+		{Threshold: 16, Workers: 64},
+	} {
+		t.Run(fmt.Sprintf("threshold=%d,workers=%d", p.Threshold, p.Workers), func(t *testing.T) {
+			ssz.SetParallelism(p)
+			have, have0 := roots()
+			if have != want {
+				t.Errorf("state root mismatch: have %x, want %x", have, want)
+			}
+			if have0 != want0 {
+				t.Errorf("phase0 state root mismatch: have %x, want %x", have0, want0)
+			}
+		})
+	}
+}
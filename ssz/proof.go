@@ -0,0 +1,308 @@
+package ssz
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+var ErrInvalidGindex = errors.New("ssz: invalid generalized index")
+
+// zeroNodes[i] is the all-zero subtree with depth i, shared by the trees.
+var zeroNodes [65]*Node
+
+func init() {
+	zeroNodes[0] = new(Node)
+	for i := 1; i < len(zeroNodes); i++ {
+		zeroNodes[i] = &Node{root: zeroHashes[i], left: zeroNodes[i-1], right: zeroNodes[i-1]}
+	}
+}
+
+// Hashable is implemented by the values which can be hashed into a hasher.
+type Hashable interface {
+	HashTreeRootWith(h *Hasher) error
+}
+
+// Node is a node of the merkle tree of a value. The leaves are the chunks of
+// the value, which have no children.
+//
+// The nodes are immutable once built, so the trees modified through Set share
+// the untouched subtrees with the original ones. The roots of the branches
+// created are hashed lazily on the first Root, which is not safe for calling
+// concurrently on the trees sharing such branches.
+type Node struct {
+	root        [32]byte
+	left, right *Node
+	dirty       bool // whether the root is yet to be hashed from the children
+}
+
+// NewLeaf creates the leaf node of the chunk.
+func NewLeaf(chunk [32]byte) *Node {
+	return &Node{root: chunk}
+}
+
+// NewBranch creates the node of the two subtrees, the root of which is hashed
+// lazily.
+func NewBranch(left, right *Node) *Node {
+	return &Node{left: left, right: right, dirty: true}
+}
+
+// BuildTree builds the merkle tree of the value, from the same merkleization
+// as the hash tree root.
+func BuildTree(obj Hashable) (*Node, error) {
+	return TreeOf(obj.HashTreeRootWith)
+}
+
+// TreeOf builds the merkle tree of the single value hashed by fn, e.g. a field
+// of a container.
+func TreeOf(fn func(h *Hasher) error) (*Node, error) {
+	h := &Hasher{tree: true}
+	if err := fn(h); err != nil {
+		return nil, err
+	}
+	if _, err := h.HashRoot(); err != nil {
+		return nil, err
+	}
+	h.syncNodes()
+	return h.nodes[0], nil
+}
+
+// Prove builds the merkle tree of the value and returns the branch proving the
+// node at the generalized index.
+func Prove(obj Hashable, gindex uint64) ([][32]byte, error) {
+	tree, err := BuildTree(obj)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Prove(gindex)
+}
+
+// Root returns the root of the subtree.
+func (n *Node) Root() [32]byte {
+	if n.dirty {
+		var (
+			buf   [64]byte
+			left  = n.left.Root()
+			right = n.right.Root()
+		)
+		copy(buf[:32], left[:])
+		copy(buf[32:], right[:])
+		n.root, n.dirty = hashPair(buf[:]), false
+	}
+	return n.root
+}
+
+// IsLeaf returns whether the node is a chunk of the value.
+func (n *Node) IsLeaf() bool {
+	return n.left == nil
+}
+
+// Get returns the node at the generalized index relative to this node.
+func (n *Node) Get(gindex uint64) (*Node, error) {
+	if gindex == 0 {
+		return nil, ErrInvalidGindex
+	}
+	node := n
+	for i := bits.Len64(gindex) - 2; i >= 0; i-- {
+		if node.IsLeaf() {
+			return nil, ErrInvalidGindex
+		}
+		if gindex&(1<<i) == 0 {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	return node, nil
+}
+
+// Set returns the tree with the node at the generalized index relative to this
+// node replaced, leaving this tree untouched. Only the branches along the path
+// are created, the others are shared.
+func (n *Node) Set(gindex uint64, node *Node) (*Node, error) {
+	if gindex == 0 {
+		return nil, ErrInvalidGindex
+	}
+	var (
+		depth = bits.Len64(gindex) - 1
+		path  = make([]*Node, depth) // parents by the bit of the gindex
+		cur   = n
+	)
+	for i := depth - 1; i >= 0; i-- {
+		if cur.IsLeaf() {
+			return nil, ErrInvalidGindex
+		}
+		path[i] = cur
+		if gindex&(1<<i) == 0 {
+			cur = cur.left
+		} else {
+			cur = cur.right
+		}
+	}
+	for i := 0; i < depth; i++ {
+		if gindex&(1<<i) == 0 {
+			node = NewBranch(node, path[i].right)
+		} else {
+			node = NewBranch(path[i].left, node)
+		}
+	}
+	return node, nil
+}
+
+// Prove returns the branch proving the node at the generalized index, which is
+// the roots of the siblings along the path from the node up to this node.
+func (n *Node) Prove(gindex uint64) ([][32]byte, error) {
+	if gindex == 0 {
+		return nil, ErrInvalidGindex
+	}
+	var (
+		depth  = bits.Len64(gindex) - 1
+		branch = make([][32]byte, depth)
+		node   = n
+	)
+	for i := depth - 1; i >= 0; i-- {
+		if node.IsLeaf() {
+			return nil, ErrInvalidGindex
+		}
+		if gindex&(1<<i) == 0 {
+			branch[i], node = node.right.Root(), node.left
+		} else {
+			branch[i], node = node.left.Root(), node.right
+		}
+	}
+	return branch, nil
+}
+
+// VerifyProof checks the branch proves the leaf at the generalized index under
+// the root.
+func VerifyProof(root [32]byte, leaf [32]byte, branch [][32]byte, gindex uint64) bool {
+	if gindex == 0 || len(branch) != bits.Len64(gindex)-1 {
+		return false
+	}
+	var buf [64]byte
+	for _, sibling := range branch {
+		if gindex&1 == 0 {
+			copy(buf[:32], leaf[:])
+			copy(buf[32:], sibling[:])
+		} else {
+			copy(buf[:32], sibling[:])
+			copy(buf[32:], leaf[:])
+		}
+		leaf = hashPair(buf[:])
+		gindex >>= 1
+	}
+	return leaf == root
+}
+
+// HelperIndices returns the generalized indexes of the nodes needed along with
+// the nodes at the given indexes to compute the root, in decreasing order.
+func HelperIndices(gindexes []uint64) []uint64 {
+	var (
+		siblings = make(map[uint64]bool)
+		paths    = make(map[uint64]bool)
+	)
+	for _, gindex := range gindexes {
+		for g := gindex; g > 1; g /= 2 {
+			siblings[g^1] = true
+			paths[g] = true
+		}
+	}
+	var helpers []uint64
+	for g := range siblings {
+		if !paths[g] {
+			helpers = append(helpers, g)
+		}
+	}
+	sort.Slice(helpers, func(i, j int) bool { return helpers[i] > helpers[j] })
+	return helpers
+}
+
+// ProveMulti builds the merkle tree of the value and returns the multiproof of
+// the nodes at the generalized indexes.
+func ProveMulti(obj Hashable, gindexes []uint64) ([][32]byte, error) {
+	tree, err := BuildTree(obj)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ProveMulti(gindexes)
+}
+
+// ProveMulti returns the multiproof of the nodes at the generalized indexes,
+// which is the roots of the nodes at the helper indexes in the same order.
+func (n *Node) ProveMulti(gindexes []uint64) ([][32]byte, error) {
+	for _, gindex := range gindexes {
+		if _, err := n.Get(gindex); err != nil {
+			return nil, err
+		}
+	}
+	helpers := HelperIndices(gindexes)
+	proof := make([][32]byte, len(helpers))
+	for i, gindex := range helpers {
+		node, err := n.Get(gindex)
+		if err != nil {
+			return nil, err
+		}
+		proof[i] = node.Root()
+	}
+	return proof, nil
+}
+
+// VerifyMultiproof checks the multiproof proves the leaves at the generalized
+// indexes under the root.
+func VerifyMultiproof(root [32]byte, leaves [][32]byte, proof [][32]byte, gindexes []uint64) bool {
+	if len(leaves) != len(gindexes) || len(gindexes) == 0 {
+		return false
+	}
+	helpers := HelperIndices(gindexes)
+	if len(proof) != len(helpers) {
+		return false
+	}
+	var (
+		nodes = make(map[uint64][32]byte, len(leaves)+len(proof))
+		keys  = make([]uint64, 0, len(leaves)+len(proof))
+	)
+	for i, gindex := range gindexes {
+		if gindex == 0 {
+			return false
+		}
+		if _, ok := nodes[gindex]; ok {
+			return false // duplicated leaf
+		}
+		nodes[gindex], keys = leaves[i], append(keys, gindex)
+	}
+	// Reject the leaves under the other ones, which would be left unchecked
+	// as the roots above them are known already.
+	for _, gindex := range gindexes {
+		for g := gindex / 2; g >= 1; g /= 2 {
+			if _, ok := nodes[g]; ok {
+				return false
+			}
+		}
+	}
+	for i, gindex := range helpers {
+		nodes[gindex] = proof[i]
+		keys = append(keys, gindex)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] > keys[j] })
+
+	// Hash up the pairs of the siblings known, with the parents appended to
+	// the keys to be hashed up in turn.
+	var buf [64]byte
+	for pos := 0; pos < len(keys); pos++ {
+		k := keys[pos]
+		if k <= 1 {
+			continue
+		}
+		left, lok := nodes[k&^1]
+		right, rok := nodes[k|1]
+		if _, done := nodes[k/2]; !lok || !rok || done {
+			continue
+		}
+		copy(buf[:32], left[:])
+		copy(buf[32:], right[:])
+		nodes[k/2] = hashPair(buf[:])
+		keys = append(keys, k/2)
+	}
+	computed, ok := nodes[1]
+	return ok && computed == root
+}
@@ -0,0 +1,141 @@
+package ssz_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests that the helper indices are the siblings along the paths which are not
+// on any of the paths themselves, in decreasing order.
+func TestHelperIndices(t *testing.T) {
+	tests := []struct {
+		gindexes []uint64
+		want     []uint64
+	}{
+		{[]uint64{1}, nil},
+		{[]uint64{2}, []uint64{3}},
+		{[]uint64{8}, []uint64{9, 5, 3}},
+		{[]uint64{8, 9}, []uint64{5, 3}},
+		{[]uint64{8, 9, 14}, []uint64{15, 6, 5}},
+		{[]uint64{2, 3}, nil},
+	}
+	for _, tt := range tests {
+		if have := ssz.HelperIndices(tt.gindexes); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("helpers of %v: have %v, want %v", tt.gindexes, have, tt.want)
+		}
+	}
+}
+
+// Tests the multiproofs of random subsets of the balances in the state, along
+// with the forged leaves and proofs which must fail the verification.
+func TestMultiproofBalances(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = make([]uint64, 100)
+	for i := range state.Balances {
+		state.Balances[i] = r.Uint64()
+	}
+	obj := ssz.AtFork(state, ssz.ForkAltair)
+	tree, err := ssz.BuildTree(obj)
+	if err != nil {
+		t.Fatalf("failed to build the tree: %v", err)
+	}
+	root, err := obj.HashTreeRoot()
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if tree.Root() != root {
+		t.Fatalf("tree root mismatch: have %x, want %x", tree.Root(), root)
+	}
+	gindexOf := func(path string) uint64 {
+		gindex, err := ssz.GIndex(obj, path)
+		if err != nil {
+			t.Fatalf("failed to resolve %s: %v", path, err)
+		}
+		return gindex
+	}
+	for i := 0; i < 100; i++ {
+		var (
+			seen     = make(map[uint64]bool)
+			gindexes []uint64
+		)
+		for j := r.Intn(8); j >= 0; j-- {
+			gindex := gindexOf(fmt.Sprintf("balances[%d]", r.Intn(len(state.Balances))))
+			if !seen[gindex] {
+				seen[gindex] = true
+				gindexes = append(gindexes, gindex)
+			}
+		}
+		if r.Intn(4) == 0 {
+			gindexes = append(gindexes, gindexOf("balances.__len__"))
+		}
+		leaves := make([][32]byte, len(gindexes))
+		for j, gindex := range gindexes {
+			node, err := tree.Get(gindex)
+			if err != nil {
+				t.Fatalf("failed to get the node %d: %v", gindex, err)
+			}
+			leaves[j] = node.Root()
+		}
+		proof, err := tree.ProveMulti(gindexes)
+		if err != nil {
+			t.Fatalf("failed to prove %v: %v", gindexes, err)
+		}
+		if !ssz.VerifyMultiproof(root, leaves, proof, gindexes) {
+			t.Fatalf("failed to verify %v", gindexes)
+		}
+		forged := append([][32]byte{}, leaves...)
+		forged[r.Intn(len(forged))][r.Intn(32)] ^= 1
+		if ssz.VerifyMultiproof(root, forged, proof, gindexes) {
+			t.Fatalf("forged leaf of %v verified", gindexes)
+		}
+		if len(proof) > 0 {
+			forged := append([][32]byte{}, proof...)
+			forged[r.Intn(len(forged))][r.Intn(32)] ^= 1
+			if ssz.VerifyMultiproof(root, leaves, forged, gindexes) {
+				t.Fatalf("forged proof of %v verified", gindexes)
+			}
+			if ssz.VerifyMultiproof(root, leaves, proof[1:], gindexes) {
+				t.Fatalf("short proof of %v verified", gindexes)
+			}
+		}
+	}
+	// The multiproof of a single leaf is its branch
+	gindex := gindexOf("balances[3]")
+	multi, err := ssz.ProveMulti(obj, []uint64{gindex})
+	if err != nil {
+		t.Fatalf("failed to prove: %v", err)
+	}
+	branch, err := tree.Prove(gindex)
+	if err != nil {
+		t.Fatalf("failed to prove: %v", err)
+	}
+	if !reflect.DeepEqual(multi, branch) {
+		t.Fatalf("single leaf multiproof differs from the branch")
+	}
+	// The duplicated leaves and the leaves under the others are rejected, as
+	// the nested ones would be left unchecked
+	leaf, _ := tree.Get(gindex)
+	if ssz.VerifyMultiproof(root, [][32]byte{leaf.Root(), leaf.Root()}, multi, []uint64{gindex, gindex}) {
+		t.Fatalf("duplicated leaves verified")
+	}
+	parent, _ := tree.Get(gindex / 2)
+	forged := leaf.Root()
+	forged[0] ^= 1
+	nested, err := tree.ProveMulti([]uint64{gindex, gindex / 2})
+	if err != nil {
+		t.Fatalf("failed to prove: %v", err)
+	}
+	if ssz.VerifyMultiproof(root, [][32]byte{forged, parent.Root()}, nested, []uint64{gindex, gindex / 2}) {
+		t.Fatalf("nested leaves verified")
+	}
+	if _, err := tree.ProveMulti([]uint64{gindex << 40}); err == nil {
+		t.Fatalf("proof of the gindex beyond the tree built")
+	}
+}
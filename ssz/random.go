@@ -0,0 +1,109 @@
+package ssz
+
+import (
+	"math/rand"
+)
+
+// The defaults of the random options left unspecified.
+const (
+	defaultMaxLength = 8
+	defaultMaxDepth  = 4
+)
+
+// RandomOptions configures the generation of random instances by the generated
+// GenerateRandomSSZ methods. A nil options is equivalent to the default one, and
+// so are the zero fields to the default values.
+type RandomOptions struct {
+	// MaxLength caps the length of the variable-length lists, in addition to
+	// their ssz-max limits which are usually way too large to be useful. For
+	// bitlists it's counted in bits.
+	MaxLength int
+
+	// MaxDepth is the nesting depth of structs beyond which the variable-length
+	// lists are left empty. It terminates the generation of recursive types.
+	MaxDepth int
+
+	// Length picks the length of a list from [0, max]. UniformLength is used
+	// if it's not specified.
+	Length func(r *rand.Rand, max int) int
+
+	depth int // nesting depth of the struct being generated
+}
+
+// DefaultRandomOptions returns the options used if none is specified.
+func DefaultRandomOptions() *RandomOptions {
+	return &RandomOptions{
+		MaxLength: defaultMaxLength,
+		MaxDepth:  defaultMaxDepth,
+	}
+}
+
+// UniformLength picks the list length uniformly from [0, max].
+func UniformLength(r *rand.Rand, max int) int {
+	return r.Intn(max + 1)
+}
+
+// EdgeLength picks the list length favoring the edge cases, i.e. empty lists,
+// single item lists and the full ones, over the others.
+func EdgeLength(r *rand.Rand, max int) int {
+	switch r.Intn(4) {
+	case 0:
+		return 0
+	case 1:
+		if max > 0 {
+			return 1
+		}
+		return 0
+	case 2:
+		return max
+	default:
+		return r.Intn(max + 1)
+	}
+}
+
+// Nested returns the options for generating a struct nested one level deeper.
+func (o *RandomOptions) Nested() *RandomOptions {
+	if o == nil {
+		o = DefaultRandomOptions()
+	}
+	cpy := *o
+	cpy.depth += 1
+	return &cpy
+}
+
+// ListLength picks the length of a variable-length list with the given limit,
+// zero limit means the list is unbounded.
+func (o *RandomOptions) ListLength(r *rand.Rand, limit uint64) int {
+	if o == nil {
+		o = DefaultRandomOptions()
+	}
+	max, depth := o.MaxLength, o.MaxDepth
+	if max <= 0 {
+		max = defaultMaxLength
+	}
+	if depth <= 0 {
+		depth = defaultMaxDepth
+	}
+	if o.depth >= depth {
+		return 0
+	}
+	if limit != 0 && limit < uint64(max) {
+		max = int(limit)
+	}
+	if o.Length == nil {
+		return UniformLength(r, max)
+	}
+	return o.Length(r, max)
+}
+
+// RandomBitlist returns a well-formed bitlist holding n random bits.
+func RandomBitlist(r *rand.Rand, n int) []byte {
+	b := make([]byte, n/BitsPerByte+1)
+	r.Read(b)
+
+	// Clear the bits beyond the delimiter and set the delimiter itself
+	last := n % BitsPerByte
+	b[len(b)-1] &= byte(1)<<last - 1
+	b[len(b)-1] |= byte(1) << last
+	return b
+}
@@ -0,0 +1,36 @@
+package ssz
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Tests that the options with only some of the knobs set fall back to the
+// defaults for the others, instead of leaving all the lists empty.
+func TestRandomOptionsDefaults(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, opts := range []*RandomOptions{nil, {}, {MaxLength: 40}, {MaxDepth: 2}} {
+		var (
+			top    int
+			nested = opts.Nested().Nested()
+		)
+		for i := 0; i < 100; i++ {
+			top += opts.ListLength(r, 0)
+		}
+		if top == 0 {
+			t.Errorf("options %+v: top-level lists are always empty", opts)
+		}
+		if opts != nil && opts.MaxLength != 0 {
+			for i := 0; i < 100; i++ {
+				if n := opts.ListLength(r, 0); n > opts.MaxLength {
+					t.Errorf("options %+v: length %d beyond the max", opts, n)
+				}
+			}
+		}
+		if opts != nil && opts.MaxDepth == 2 {
+			if n := nested.ListLength(r, 0); n != 0 {
+				t.Errorf("options %+v: length %d beyond the max depth", opts, n)
+			}
+		}
+	}
+}
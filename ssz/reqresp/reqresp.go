@@ -0,0 +1,187 @@
+// Package reqresp implements the chunk codec of the eth2 req/resp protocols.
+// The requests are encoded as the uncompressed length in unsigned varint,
+// followed by the snappy framed ssz encoding. The responses are sequences of
+// chunks, each of them leading with the result byte and the context bytes of
+// the successful ones, e.g. the fork digest, followed by an encoded payload.
+package reqresp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rjl493456442/sszgen/ssz"
+	"github.com/rjl493456442/sszgen/ssz/snappy"
+)
+
+// Result codes of the response chunks.
+const (
+	ResultSuccess             byte = 0
+	ResultInvalidRequest      byte = 1
+	ResultServerError         byte = 2
+	ResultResourceUnavailable byte = 3
+)
+
+// MaxErrorMessage is the maximum length of the error message in the chunks
+// with failure results.
+const MaxErrorMessage = 256
+
+var (
+	ErrSizeOutOfBounds = errors.New("reqresp: payload size out of the bounds of the type")
+	ErrNoChunk         = errors.New("reqresp: no chunk header read")
+)
+
+// Message is the method set of the generated bindings for decoding a payload.
+// The bounds of the encoded size are checked before the payload is read.
+type Message interface {
+	ssz.Decoder
+	MinSizeSSZ() uint64
+	MaxSizeSSZ() uint64
+}
+
+// ResponseError is the failure result of a response chunk.
+type ResponseError struct {
+	Code    byte
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("reqresp: response error %d: %s", e.Code, e.Message)
+}
+
+// errorMessage is the payload of the chunks with failure results, which is
+// a byte list limited to MaxErrorMessage.
+type errorMessage []byte
+
+func (m *errorMessage) SizeSSZ() int { return len(*m) }
+
+func (m *errorMessage) MinSizeSSZ() uint64 { return 0 }
+
+func (m *errorMessage) MaxSizeSSZ() uint64 { return MaxErrorMessage }
+
+func (m *errorMessage) MarshalSSZ() ([]byte, error) {
+	return m.MarshalSSZTo(make([]byte, 0, m.SizeSSZ()))
+}
+
+func (m *errorMessage) MarshalSSZTo(w []byte) ([]byte, error) {
+	if len(*m) > MaxErrorMessage {
+		return nil, ssz.ErrListTooBig
+	}
+	return ssz.EncodeBytes(w, *m), nil
+}
+
+func (m *errorMessage) UnmarshalSSZ(s *ssz.Stream) error {
+	v, err := ssz.DecodeBytes(s, 0)
+	if err != nil {
+		return err
+	}
+	if len(v) > MaxErrorMessage {
+		return ssz.ErrListTooBig
+	}
+	*m = v
+	return nil
+}
+
+// WriteRequest writes the encoded request.
+func WriteRequest(w io.Writer, obj ssz.Encoder) error {
+	return snappy.WritePrefixed(w, obj)
+}
+
+// ReadRequest reads the encoded request into obj.
+func ReadRequest(r io.Reader, obj Message) error {
+	return readPayload(r, obj)
+}
+
+// WriteChunk writes a successful response chunk with the context bytes, which
+// are left out if empty.
+func WriteChunk(w io.Writer, context []byte, obj ssz.Encoder) error {
+	if _, err := w.Write(append([]byte{ResultSuccess}, context...)); err != nil {
+		return err
+	}
+	return snappy.WritePrefixed(w, obj)
+}
+
+// WriteError writes a response chunk with the failure result. The message is
+// truncated to MaxErrorMessage bytes.
+func WriteError(w io.Writer, code byte, message string) error {
+	if code == ResultSuccess {
+		return errors.New("reqresp: error chunk with success result")
+	}
+	if len(message) > MaxErrorMessage {
+		message = message[:MaxErrorMessage]
+	}
+	if _, err := w.Write([]byte{code}); err != nil {
+		return err
+	}
+	msg := errorMessage(message)
+	return snappy.WritePrefixed(w, &msg)
+}
+
+// readPayload reads the length prefix, checks it against the bounds of the
+// type before reading any of the payload, and decodes the payload into obj.
+func readPayload(r io.Reader, obj Message) error {
+	size, err := snappy.ReadLength(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF // the payload is mandatory
+	}
+	if err != nil {
+		return err
+	}
+	if size < obj.MinSizeSSZ() || size > obj.MaxSizeSSZ() {
+		return ErrSizeOutOfBounds
+	}
+	return snappy.ReadFramed(r, size, obj)
+}
+
+// ChunkReader iterates the chunks of a response. The chunks are read without
+// any buffering beyond them, so that the reader can be shared with the other
+// protocol messages.
+type ChunkReader struct {
+	r          io.Reader
+	contextLen int  // length of the context bytes in the successful chunks
+	pending    bool // whether a chunk header is read without the payload
+}
+
+// NewChunkReader creates the reader for the response chunks with the context
+// bytes of the given length, which is zero if the protocol has none.
+func NewChunkReader(r io.Reader, contextLen int) *ChunkReader {
+	return &ChunkReader{r: r, contextLen: contextLen}
+}
+
+// Next reads the header of the next chunk, returning its context bytes which
+// determine the type of the payload to decode. It returns io.EOF at the end of
+// the response, or a *ResponseError if the chunk holds a failure result.
+func (cr *ChunkReader) Next() ([]byte, error) {
+	if cr.pending {
+		return nil, errors.New("reqresp: payload of the last chunk is not read")
+	}
+	var result [1]byte
+	if _, err := io.ReadFull(cr.r, result[:]); err != nil {
+		return nil, err // io.EOF if the response ends cleanly
+	}
+	if result[0] != ResultSuccess {
+		var msg errorMessage
+		if err := readPayload(cr.r, &msg); err != nil {
+			return nil, err
+		}
+		return nil, &ResponseError{Code: result[0], Message: string(msg)}
+	}
+	context := make([]byte, cr.contextLen)
+	if _, err := io.ReadFull(cr.r, context); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	cr.pending = true
+	return context, nil
+}
+
+// Decode reads the payload of the chunk whose header is read by Next into obj.
+func (cr *ChunkReader) Decode(obj Message) error {
+	if !cr.pending {
+		return ErrNoChunk
+	}
+	cr.pending = false
+	return readPayload(cr.r, obj)
+}
@@ -0,0 +1,197 @@
+package reqresp_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+	"github.com/rjl493456442/sszgen/ssz/reqresp"
+)
+
+// serve runs the writer of the response on one end of a pipe, returning the
+// other end to read from. The writing end is closed once the writer returns,
+// and the reading end once the test is done, which aborts the unread writes.
+func serve(t *testing.T, write func(w io.Writer) error) io.Reader {
+	t.Helper()
+
+	r, w := net.Pipe()
+	t.Cleanup(func() { r.Close() })
+	go func() {
+		defer w.Close()
+		if err := write(w); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("failed to write the response: %v", err)
+		}
+	}()
+	return r
+}
+
+func newAttestation(bits int) *spectests.Attestation {
+	agg := make([]byte, bits/8+1)
+	agg[len(agg)-1] |= 1 << (bits % 8)
+	return &spectests.Attestation{
+		AggregationBits: agg,
+		Data: &spectests.AttestationData{
+			Slot:   spectests.Slot(bits),
+			Source: &spectests.Checkpoint{Epoch: 1, Root: bytes.Repeat([]byte{1}, 32)},
+			Target: &spectests.Checkpoint{Epoch: 2, Root: bytes.Repeat([]byte{2}, 32)},
+		},
+	}
+}
+
+func encode(t *testing.T, obj ssz.Encoder) []byte {
+	t.Helper()
+
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return enc
+}
+
+// Tests that the chunks of a response are read back along with their context
+// bytes, which select the type of the payload.
+func TestChunksRoundTrip(t *testing.T) {
+	var (
+		attDigest = []byte{0xaa, 0xbb, 0xcc, 0xdd}
+		cpDigest  = []byte{0x11, 0x22, 0x33, 0x44}
+		chunks    = []ssz.Encoder{
+			newAttestation(0),
+			&spectests.Checkpoint{Epoch: 3, Root: bytes.Repeat([]byte{3}, 32)},
+			newAttestation(100),
+			newAttestation(2047),
+		}
+	)
+	r := serve(t, func(w io.Writer) error {
+		for _, obj := range chunks {
+			digest := attDigest
+			if _, ok := obj.(*spectests.Checkpoint); ok {
+				digest = cpDigest
+			}
+			if err := reqresp.WriteChunk(w, digest, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	cr := reqresp.NewChunkReader(r, len(attDigest))
+	for i, want := range chunks {
+		digest, err := cr.Next()
+		if err != nil {
+			t.Fatalf("chunk %d: failed to read the header: %v", i, err)
+		}
+		var obj reqresp.Message
+		switch {
+		case bytes.Equal(digest, attDigest):
+			obj = new(spectests.Attestation)
+		case bytes.Equal(digest, cpDigest):
+			obj = new(spectests.Checkpoint)
+		default:
+			t.Fatalf("chunk %d: unknown context bytes %x", i, digest)
+		}
+		if err := cr.Decode(obj); err != nil {
+			t.Fatalf("chunk %d: failed to decode: %v", i, err)
+		}
+		if !bytes.Equal(encode(t, obj.(ssz.Encoder)), encode(t, want)) {
+			t.Fatalf("chunk %d: payload mismatch", i)
+		}
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Fatalf("end of response: have %v, want %v", err, io.EOF)
+	}
+}
+
+// Tests that an error chunk in the middle of a response is surfaced with its
+// code and message, and the chunks after it are still readable.
+func TestErrorChunk(t *testing.T) {
+	want := &spectests.Checkpoint{Epoch: 7, Root: make([]byte, 32)}
+	r := serve(t, func(w io.Writer) error {
+		if err := reqresp.WriteChunk(w, nil, want); err != nil {
+			return err
+		}
+		if err := reqresp.WriteError(w, reqresp.ResultResourceUnavailable, "pruned"); err != nil {
+			return err
+		}
+		return reqresp.WriteChunk(w, nil, want)
+	})
+	cr := reqresp.NewChunkReader(r, 0)
+	if _, err := cr.Next(); err != nil {
+		t.Fatalf("failed to read the first header: %v", err)
+	}
+	if err := cr.Decode(new(spectests.Checkpoint)); err != nil {
+		t.Fatalf("failed to decode the first chunk: %v", err)
+	}
+	_, err := cr.Next()
+	var rerr *reqresp.ResponseError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("error chunk: have %v, want response error", err)
+	}
+	if rerr.Code != reqresp.ResultResourceUnavailable || rerr.Message != "pruned" {
+		t.Fatalf("error chunk: have code %d message %q", rerr.Code, rerr.Message)
+	}
+	if _, err := cr.Next(); err != nil {
+		t.Fatalf("failed to read the header after the error: %v", err)
+	}
+	have := new(spectests.Checkpoint)
+	if err := cr.Decode(have); err != nil {
+		t.Fatalf("failed to decode the chunk after the error: %v", err)
+	}
+	if have.Epoch != want.Epoch {
+		t.Fatalf("epoch mismatch: have %d, want %d", have.Epoch, want.Epoch)
+	}
+}
+
+// Tests that the payloads beyond the max size of the type are rejected before
+// any of them is read.
+func TestOversizedPayload(t *testing.T) {
+	r := serve(t, func(w io.Writer) error {
+		return reqresp.WriteChunk(w, nil, newAttestation(2047))
+	})
+	cr := reqresp.NewChunkReader(r, 0)
+	if _, err := cr.Next(); err != nil {
+		t.Fatalf("failed to read the header: %v", err)
+	}
+	// The checkpoint is fixed-size, far smaller than the attestation
+	if err := cr.Decode(new(spectests.Checkpoint)); err != reqresp.ErrSizeOutOfBounds {
+		t.Fatalf("oversized chunk: have %v, want %v", err, reqresp.ErrSizeOutOfBounds)
+	}
+	r = serve(t, func(w io.Writer) error {
+		return reqresp.WriteRequest(w, newAttestation(10))
+	})
+	if err := reqresp.ReadRequest(r, new(spectests.Fork)); err != reqresp.ErrSizeOutOfBounds {
+		t.Fatalf("oversized request: have %v, want %v", err, reqresp.ErrSizeOutOfBounds)
+	}
+}
+
+// Tests that the responses cut off in the middle of a payload fail instead of
+// decoding a partial value.
+func TestTruncatedPayload(t *testing.T) {
+	var full bytes.Buffer
+	if err := reqresp.WriteChunk(&full, []byte{1, 2, 3, 4}, newAttestation(300)); err != nil {
+		t.Fatalf("failed to write the chunk: %v", err)
+	}
+	// Cut within the context bytes, the length prefix and the payload
+	for _, n := range []int{3, 5, 8, full.Len() - 1} {
+		r := serve(t, func(w io.Writer) error {
+			_, err := w.Write(full.Bytes()[:n])
+			return err
+		})
+		cr := reqresp.NewChunkReader(r, 4)
+		_, err := cr.Next()
+		if n < 5 {
+			if err != io.ErrUnexpectedEOF {
+				t.Fatalf("truncated at %d: have %v, want %v", n, err, io.ErrUnexpectedEOF)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("truncated at %d: failed to read the header: %v", n, err)
+		}
+		if err := cr.Decode(new(spectests.Attestation)); err == nil {
+			t.Fatalf("truncated at %d: payload accepted", n)
+		}
+	}
+}
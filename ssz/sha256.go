@@ -0,0 +1,88 @@
+package ssz
+
+import (
+	"crypto/sha256"
+	"sync/atomic"
+)
+
+// PairHasher hashes the pairs of the sibling chunks into their parents, which
+// is the hot path of the merkleization.
+type PairHasher interface {
+	// HashPairs hashes the consecutive 64-byte pairs in src into the 32-byte
+	// digests in dst, which holds half as many bytes as src. The dst and src
+	// never overlap.
+	HashPairs(dst []byte, src []byte)
+}
+
+// GoPairHasher is the default pair hasher, running the sha256 of the standard
+// library pair by pair.
+type GoPairHasher struct{}
+
+// HashPairs implements PairHasher.
+func (GoPairHasher) HashPairs(dst []byte, src []byte) {
+	for i := 0; i < len(src)/64; i++ {
+		digest := sha256.Sum256(src[i*64 : i*64+64])
+		copy(dst[i*32:], digest[:])
+	}
+}
+
+// CountingPairHasher wraps the pair hasher and counts the pairs hashed, e.g.
+// for checking the hashing done in tests. The wrapped one is the GoPairHasher
+// if not set.
+type CountingPairHasher struct {
+	Backend PairHasher
+	pairs   atomic.Uint64
+}
+
+// HashPairs implements PairHasher.
+func (c *CountingPairHasher) HashPairs(dst []byte, src []byte) {
+	c.pairs.Add(uint64(len(src) / 64))
+	if c.Backend == nil {
+		GoPairHasher{}.HashPairs(dst, src)
+		return
+	}
+	c.Backend.HashPairs(dst, src)
+}
+
+// Pairs returns the number of pairs hashed since the last reset.
+func (c *CountingPairHasher) Pairs() uint64 {
+	return c.pairs.Load()
+}
+
+// Reset zeroes the counter.
+func (c *CountingPairHasher) Reset() {
+	c.pairs.Store(0)
+}
+
+// pairHasherHolder holds the registered pair hasher, as the atomic values can't
+// store the interfaces of different types.
+type pairHasherHolder struct {
+	PairHasher
+}
+
+var pairHasher atomic.Pointer[pairHasherHolder]
+
+func init() {
+	SetPairHasher(nil)
+}
+
+// SetPairHasher registers the pair hasher used by the merkleization, e.g. a
+// vectorized one. The nil one restores the GoPairHasher.
+func SetPairHasher(p PairHasher) {
+	if p == nil {
+		p = GoPairHasher{}
+	}
+	pairHasher.Store(&pairHasherHolder{p})
+}
+
+// hashPairs hashes the pairs in src into dst with the registered pair hasher.
+func hashPairs(dst []byte, src []byte) {
+	pairHasher.Load().HashPairs(dst, src)
+}
+
+// hashPair returns the digest of the 64-byte pair.
+func hashPair(pair []byte) [32]byte {
+	var digest [32]byte
+	hashPairs(digest[:], pair)
+	return digest
+}
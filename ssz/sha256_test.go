@@ -0,0 +1,89 @@
+package ssz_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// overlapChecker is a pair hasher failing the test if the buffers handed to it
+// overlap.
+type overlapChecker struct {
+	t *testing.T
+}
+
+func (c overlapChecker) HashPairs(dst []byte, src []byte) {
+	if len(dst) > 0 && len(src) > 0 {
+		d, s := uintptr(unsafe.Pointer(&dst[0])), uintptr(unsafe.Pointer(&src[0]))
+		if d < s+uintptr(len(src)) && s < d+uintptr(len(dst)) {
+			c.t.Errorf("overlapping buffers: dst %x+%d, src %x+%d", d, len(dst), s, len(src))
+		}
+	}
+	ssz.GoPairHasher{}.HashPairs(dst, src)
+}
+
+// Tests that the registered pair hasher hashes all the pairs of a container,
+// with the root and the number of pairs hashed checked against the ones of the
+// merkleization done by hand.
+func TestCountingPairHasher(t *testing.T) {
+	counter := &ssz.CountingPairHasher{Backend: overlapChecker{t}}
+	ssz.SetPairHasher(counter)
+	t.Cleanup(func() { ssz.SetPairHasher(nil) })
+
+	validator := new(spectests.Validator)
+	validator.GenerateRandomSSZ(rand.New(rand.NewSource(1)), nil)
+
+	// The pubkey spans two chunks, the other fields one each, summing up to
+	// 1 + 4 + 2 + 1 pairs
+	hash := func(a, b [32]byte) [32]byte {
+		return sha256.Sum256(append(a[:], b[:]...))
+	}
+	uint64Chunk := func(v uint64) (chunk [32]byte) {
+		binary.LittleEndian.PutUint64(chunk[:], v)
+		return chunk
+	}
+	var pubkey [2][32]byte
+	copy(pubkey[0][:], validator.Pubkey)
+	copy(pubkey[1][:], validator.Pubkey[32:])
+
+	var credentials, slashed [32]byte
+	copy(credentials[:], validator.WithdrawalCredentials)
+	if validator.Slashed {
+		slashed[0] = 1
+	}
+	want := hash(
+		hash(
+			hash(hash(pubkey[0], pubkey[1]), credentials),
+			hash(uint64Chunk(validator.EffectiveBalance), slashed),
+		),
+		hash(
+			hash(uint64Chunk(validator.ActivationEligibilityEpoch), uint64Chunk(validator.ActivationEpoch)),
+			hash(uint64Chunk(validator.ExitEpoch), uint64Chunk(validator.WithdrawableEpoch)),
+		),
+	)
+	have, err := validator.HashTreeRoot()
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+	if pairs := counter.Pairs(); pairs != 8 {
+		t.Fatalf("pairs mismatch: have %d, want 8", pairs)
+	}
+	// The large layers are hashed in batches, none of them overlapping
+	counter.Reset()
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(rand.New(rand.NewSource(1)), nil)
+	if _, err := state.HashTreeRootForFork(ssz.ForkCapella); err != nil {
+		t.Fatalf("failed to hash the state: %v", err)
+	}
+	if counter.Pairs() == 0 {
+		t.Fatalf("no pairs hashed with the state")
+	}
+}
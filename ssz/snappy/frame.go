@@ -0,0 +1,126 @@
+package snappy
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// The chunk types of the framed format.
+const (
+	chunkCompressed   = 0x00
+	chunkUncompressed = 0x01
+	chunkSkippable    = 0x80 // first of the skippable chunk types, up to the padding
+	chunkStreamID     = 0xff
+)
+
+const (
+	streamID     = "sNaPpY"
+	checksumSize = 4
+	maxBlockSize = 65536 // maximum decompressed size of a chunk
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// frameReader decompresses the framed format chunk by chunk. Unlike the reader
+// of the snappy package, it keeps the data of the chunk not read yet in sight,
+// and reads the next chunk from r only once the data is needed.
+type frameReader struct {
+	r      io.Reader
+	header [4]byte
+	buf    []byte // chunk read from r
+	data   []byte // decompressed data of the chunk
+	left   []byte // data of the chunk not read yet
+	ident  bool   // whether the stream identifier was read
+}
+
+func (fr *frameReader) Read(p []byte) (int, error) {
+	if len(fr.left) == 0 {
+		if err := fr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, fr.left)
+	fr.left = fr.left[n:]
+	return n, nil
+}
+
+func (fr *frameReader) ReadByte() (byte, error) {
+	if len(fr.left) == 0 {
+		if err := fr.next(); err != nil {
+			return 0, err
+		}
+	}
+	b := fr.left[0]
+	fr.left = fr.left[1:]
+	return b, nil
+}
+
+// next reads the chunks from r until one holding data, skipping the others.
+func (fr *frameReader) next() error {
+	for {
+		if _, err := io.ReadFull(fr.r, fr.header[:]); err != nil {
+			return err
+		}
+		typ, size := fr.header[0], int(fr.header[1])|int(fr.header[2])<<8|int(fr.header[3])<<16
+		if size > checksumSize+snappy.MaxEncodedLen(maxBlockSize) {
+			return snappy.ErrCorrupt
+		}
+		if !fr.ident && typ != chunkStreamID {
+			return snappy.ErrCorrupt
+		}
+		if cap(fr.buf) < size {
+			fr.buf = make([]byte, size)
+		}
+		chunk := fr.buf[:size]
+		if _, err := io.ReadFull(fr.r, chunk); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch {
+		case typ == chunkStreamID:
+			if string(chunk) != streamID {
+				return snappy.ErrCorrupt
+			}
+			fr.ident = true
+
+		case typ == chunkCompressed || typ == chunkUncompressed:
+			if size < checksumSize {
+				return snappy.ErrCorrupt
+			}
+			data := chunk[checksumSize:]
+			if typ == chunkCompressed {
+				n, err := snappy.DecodedLen(data)
+				if err != nil || n > maxBlockSize {
+					return snappy.ErrCorrupt
+				}
+				if fr.data, err = snappy.Decode(fr.data[:cap(fr.data)], data); err != nil {
+					return err
+				}
+				data = fr.data
+			} else if len(data) > maxBlockSize {
+				return snappy.ErrCorrupt
+			}
+			if checksum(data) != binary.LittleEndian.Uint32(chunk) {
+				return snappy.ErrCorrupt
+			}
+			if len(data) != 0 {
+				fr.left = data
+				return nil
+			}
+
+		case typ < chunkSkippable:
+			return snappy.ErrUnsupported
+		}
+	}
+}
+
+// checksum returns the masked crc32c of the data, as stored in the chunks.
+func checksum(data []byte) uint32 {
+	c := crc32.Checksum(data, crcTable)
+	return (c>>15 | c<<17) + 0xa282ead8
+}
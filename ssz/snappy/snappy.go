@@ -0,0 +1,175 @@
+// Package snappy implements the snappy compressed ssz encodings, in the block
+// format used by the spec tests and gossip, and in the framed format used by
+// the req/resp protocols.
+package snappy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+var (
+	ErrTooLarge      = errors.New("snappy: decompressed size exceeds the limit")
+	ErrInvalidLength = errors.New("snappy: invalid length prefix")
+	ErrTrailingData  = errors.New("snappy: data beyond the decompressed size")
+)
+
+// MaxVarintLen is the maximum length of the unsigned varint length prefix.
+const MaxVarintLen = binary.MaxVarintLen64
+
+// EncodeBlock encodes the object and compresses it in the block format.
+func EncodeBlock(obj ssz.Encoder) ([]byte, error) {
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, enc), nil
+}
+
+// DecodeBlock decompresses the input in the block format and decodes it into
+// obj. The decompressed length is checked against maxSize before allocating.
+func DecodeBlock(input []byte, obj ssz.Decoder, maxSize uint64) error {
+	blob, err := DecompressBlock(input, maxSize)
+	if err != nil {
+		return err
+	}
+	return ssz.Unmarshal(blob, obj)
+}
+
+// DecompressBlock decompresses the input in the block format. The decompressed
+// length is checked against maxSize before allocating.
+func DecompressBlock(input []byte, maxSize uint64) ([]byte, error) {
+	n, err := snappy.DecodedLen(input)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return snappy.Decode(nil, input)
+}
+
+// WriteFramed encodes the object and writes it compressed in the framed format.
+// The length of the encoding is not written.
+func WriteFramed(w io.Writer, obj ssz.Encoder) error {
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		return err
+	}
+	return writeFramed(w, enc)
+}
+
+// EncodeFramed encodes the object and compresses it in the framed format.
+func EncodeFramed(obj ssz.Encoder) ([]byte, error) {
+	var b bytes.Buffer
+	if err := WriteFramed(&b, obj); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeFramed decompresses the input in the framed format and decodes it into
+// obj. The framed format has no length ahead, the decompression is aborted once
+// the output exceeds maxSize.
+func DecodeFramed(input []byte, obj ssz.Decoder, maxSize uint64) error {
+	if maxSize > math.MaxUint32 {
+		maxSize = math.MaxUint32 // limit of the ssz streams
+	}
+	blob, err := io.ReadAll(io.LimitReader(snappy.NewReader(bytes.NewReader(input)), int64(maxSize)+1))
+	if err != nil {
+		return err
+	}
+	if uint64(len(blob)) > maxSize {
+		return ErrTooLarge
+	}
+	return ssz.Unmarshal(blob, obj)
+}
+
+func writeFramed(w io.Writer, enc []byte) error {
+	sw := snappy.NewBufferedWriter(w)
+	if _, err := sw.Write(enc); err != nil {
+		return err
+	}
+	return sw.Close() // flushes the buffer, w is left open
+}
+
+// ReadFramed reads the encoding with the given decompressed size in the framed
+// format from r, and decodes it into obj. The compressed chunks are read from r
+// only as far as needed for the size, and the last one must not hold any data
+// beyond it.
+func ReadFramed(r io.Reader, size uint64, obj ssz.Decoder) error {
+	if size > math.MaxUint32 {
+		return ErrTooLarge
+	}
+	if size == 0 {
+		return ssz.Unmarshal(nil, obj) // nothing is written for the empty encoding
+	}
+	fr := &frameReader{r: r}
+	if err := ssz.Decode(fr, uint32(size), obj); err != nil {
+		return err
+	}
+	if len(fr.left) != 0 {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// WritePrefixed encodes the object and writes it compressed in the framed
+// format, prefixed with the uncompressed length as an unsigned varint.
+func WritePrefixed(w io.Writer, obj ssz.Encoder) error {
+	enc, err := obj.MarshalSSZ()
+	if err != nil {
+		return err
+	}
+	var prefix [MaxVarintLen]byte
+	if _, err := w.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(enc)))]); err != nil {
+		return err
+	}
+	return writeFramed(w, enc)
+}
+
+// ReadPrefixed reads the encoding prefixed with the uncompressed length from r,
+// and decodes it into obj. The length is checked against maxSize before any of
+// the encoding is read.
+func ReadPrefixed(r io.Reader, obj ssz.Decoder, maxSize uint64) error {
+	size, err := ReadLength(r)
+	if err != nil {
+		return err
+	}
+	if size > maxSize {
+		return ErrTooLarge
+	}
+	return ReadFramed(r, size, obj)
+}
+
+// ReadLength reads the unsigned varint length prefix from r, without reading
+// any byte beyond it.
+func ReadLength(r io.Reader) (uint64, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r: r}
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		err = ErrInvalidLength // overflowing varint
+	}
+	return size, err
+}
+
+// byteReader reads the bytes one by one from the reader without buffering, so
+// that the bytes following the length prefix are left in the reader.
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.buf[:])
+	return br.buf[0], err
+}
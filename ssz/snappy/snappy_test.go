@@ -0,0 +1,181 @@
+package snappy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/rjl493456442/sszgen/spectests"
+)
+
+// newPayload returns a payload encoded into multiple chunks, with some of the
+// transactions compressible and some not.
+func newPayload(r *rand.Rand) *spectests.ExecutionPayloadCapella {
+	payload := new(spectests.ExecutionPayloadCapella)
+	payload.GenerateRandomSSZ(r, nil)
+
+	random := make([]byte, 100000)
+	r.Read(random)
+	payload.Transactions = [][]byte{random, make([]byte, 150000), {0x01}}
+	return payload
+}
+
+// framed compresses the data in the framed format, as written by the snappy
+// package.
+func framed(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := writeFramed(&b, data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return b.Bytes()
+}
+
+// Tests the round trip of the block format and the decompressed size limit.
+func TestBlock(t *testing.T) {
+	payload := newPayload(rand.New(rand.NewSource(1)))
+	enc, err := EncodeBlock(payload)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	size := uint64(payload.SizeSSZ())
+
+	dec := new(spectests.ExecutionPayloadCapella)
+	if err := DecodeBlock(enc, dec, size); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !dec.Equal(payload) {
+		t.Fatalf("decoded payload mismatch")
+	}
+	if err := DecodeBlock(enc, dec, size-1); err != ErrTooLarge {
+		t.Fatalf("oversized block: error mismatch: have %v, want %v", err, ErrTooLarge)
+	}
+	if err := DecodeBlock(enc[:len(enc)/2], dec, size); err == nil {
+		t.Fatalf("truncated block decoded")
+	}
+}
+
+// Tests the round trip of the framed format and the decompressed size limit.
+func TestFramed(t *testing.T) {
+	payload := newPayload(rand.New(rand.NewSource(1)))
+	enc, err := EncodeFramed(payload)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	size := uint64(payload.SizeSSZ())
+
+	dec := new(spectests.ExecutionPayloadCapella)
+	if err := DecodeFramed(enc, dec, size); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !dec.Equal(payload) {
+		t.Fatalf("decoded payload mismatch")
+	}
+	if err := DecodeFramed(enc, dec, size-1); err != ErrTooLarge {
+		t.Fatalf("oversized frame: error mismatch: have %v, want %v", err, ErrTooLarge)
+	}
+	if err := ReadFramed(bytes.NewReader(enc), size, dec); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if !dec.Equal(payload) {
+		t.Fatalf("read payload mismatch")
+	}
+}
+
+// Tests that the length-prefixed encodings are read one after the other from
+// the stream, without reading beyond them.
+func TestPrefixed(t *testing.T) {
+	var (
+		r       = rand.New(rand.NewSource(1))
+		b       bytes.Buffer
+		objects []*spectests.ExecutionPayloadCapella
+	)
+	for i := 0; i < 3; i++ {
+		payload := newPayload(r)
+		if err := WritePrefixed(&b, payload); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		objects = append(objects, payload)
+	}
+	// Hide the byte reader of the buffer, the length prefix must be read
+	// without buffering anyway
+	stream := struct{ io.Reader }{&b}
+	for i, want := range objects {
+		have := new(spectests.ExecutionPayloadCapella)
+		if err := ReadPrefixed(stream, have, uint64(want.SizeSSZ())); err != nil {
+			t.Fatalf("object %d: failed to read: %v", i, err)
+		}
+		if !have.Equal(want) {
+			t.Fatalf("object %d: mismatch", i)
+		}
+	}
+	if b.Len() != 0 {
+		t.Fatalf("%d bytes left in the stream", b.Len())
+	}
+}
+
+// Tests that the length prefix exceeding the limit is rejected before reading
+// any of the encoding.
+func TestPrefixedTooLarge(t *testing.T) {
+	var (
+		payload = newPayload(rand.New(rand.NewSource(1)))
+		b       bytes.Buffer
+	)
+	if err := WritePrefixed(&b, payload); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	var (
+		total  = b.Len()
+		size   = uint64(payload.SizeSSZ())
+		prefix = len(binary.AppendUvarint(nil, size))
+	)
+	if err := ReadPrefixed(&b, new(spectests.ExecutionPayloadCapella), size-1); err != ErrTooLarge {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrTooLarge)
+	}
+	if b.Len() != total-prefix {
+		t.Fatalf("read beyond the prefix: have %d bytes left, want %d", b.Len(), total-prefix)
+	}
+}
+
+// Tests that the truncated and corrupt frames are rejected.
+func TestFramedMalformed(t *testing.T) {
+	var (
+		payload  = newPayload(rand.New(rand.NewSource(1)))
+		size     = uint64(payload.SizeSSZ())
+		enc, _   = payload.MarshalSSZ()
+		frame    = framed(t, enc)
+		checkErr = func(name string, frame []byte, want error) {
+			t.Helper()
+			err := ReadFramed(bytes.NewReader(frame), size, new(spectests.ExecutionPayloadCapella))
+			if !errors.Is(err, want) {
+				t.Errorf("%s: error mismatch: have %v, want %v", name, err, want)
+			}
+		}
+		modify = func(pos int, b byte) []byte {
+			cpy := append([]byte{}, frame...)
+			cpy[pos] = b
+			return cpy
+		}
+	)
+	for _, cut := range []int{0, 3, 10, 14, 100, len(frame) / 2, len(frame) - 1} {
+		checkErr("truncated", frame[:cut], io.ErrUnexpectedEOF)
+	}
+	checkErr("stream identifier", modify(4, 'S'), snappy.ErrCorrupt)
+	checkErr("no stream identifier", frame[10:], snappy.ErrCorrupt)
+	checkErr("checksum", modify(14, frame[14]^1), snappy.ErrCorrupt)
+	checkErr("unskippable chunk", modify(10, 0x02), snappy.ErrUnsupported)
+
+	// The skippable chunks are passed over
+	padded := append(append([]byte{}, frame[:10]...), 0xfe, 2, 0, 0, 0, 0)
+	checkErr("padding", append(padded, frame[10:]...), nil)
+
+	// The data beyond the size in the last chunk is rejected, but the chunks
+	// following it are not read
+	checkErr("trailing data", framed(t, append(enc, 0)), ErrTrailingData)
+	checkErr("next chunk", append(append([]byte{}, frame...), framed(t, []byte{0})[10:]...), nil)
+}
@@ -12,6 +12,8 @@ import (
 
 var (
 	ErrValueTooLarge = errors.New("ssz: value size exceeds available input length")
+	ErrInvalidOffset = errors.New("ssz: invalid offset")
+	ErrTrailingBytes = errors.New("ssz: input has trailing bytes")
 )
 
 type ByteReader interface {
@@ -19,16 +21,20 @@ type ByteReader interface {
 	io.ByteReader
 }
 
-type Stream struct {
-	reader    ByteReader
-	remaining uint32
-
-	// [4, 10, 14]
-	stack []uint32
+// frame is the decoding scope of a container or a variable-size list. It keeps
+// the offsets read in the scope for locating the blocks of variable-size items.
+type frame struct {
+	start   uint32   // absolute position where the scope starts
+	end     uint32   // absolute position where the scope ends, valid if bounded
+	bounded bool     // whether the size of the scope is known
+	offsets []uint32 // absolute positions of the variable-size items
+	next    int      // index of the next block to start
+}
 
-	// [END - lastOffset]
-	lastOffset uint32
-	current    uint32
+type Stream struct {
+	reader ByteReader
+	pos    uint32  // number of bytes consumed so far
+	frames []frame // scopes being decoded, innermost last
 }
 
 func NewStream(r io.Reader, size uint32) (*Stream, error) {
@@ -53,8 +59,8 @@ func NewStream(r io.Reader, size uint32) (*Stream, error) {
 		bufr = bufio.NewReader(r)
 	}
 	return &Stream{
-		reader:    bufr,
-		remaining: remaining,
+		reader: bufr,
+		frames: []frame{{end: remaining, bounded: remaining != 0}},
 	}, nil
 }
 
@@ -73,6 +79,7 @@ func (s *Stream) read(n int) ([]byte, error) {
 		nn, err = s.reader.Read(buf[read:])
 		read += nn
 	}
+	s.pos += uint32(read)
 	if err == io.EOF {
 		if read < n {
 			err = io.ErrUnexpectedEOF
@@ -94,18 +101,17 @@ func (s *Stream) readByte() (byte, error) {
 	if err == io.EOF {
 		err = io.ErrUnexpectedEOF
 	}
+	if err == nil {
+		s.pos += 1
+	}
 	return b, err
 }
 
+// readEnd reads all the remaining bytes in the innermost scope.
 func (s *Stream) readEnd() ([]byte, error) {
-	if s.current != 0 {
-		return s.read(int(s.current))
-	}
-	if len(s.stack) != 0 {
-		return nil, errors.New("unexpected readEnd operation")
-	}
-	if s.remaining != 0 {
-		return s.read(int(s.remaining))
+	f := &s.frames[len(s.frames)-1]
+	if f.bounded {
+		return s.read(int(f.end - s.pos))
 	}
 	var (
 		nn, n    int
@@ -118,6 +124,8 @@ func (s *Stream) readEnd() ([]byte, error) {
 		n += nn
 		buf = append(buf, internal[:nn]...)
 	}
+	s.pos += uint32(n)
+
 	// Readers are allowed to give EOF even though the read succeeded.
 	// In such cases, we discard the EOF, like io.ReadFull() does.
 	if err == io.EOF {
@@ -127,19 +135,11 @@ func (s *Stream) readEnd() ([]byte, error) {
 }
 
 // willRead is called before any read from the underlying stream. It checks
-// n against size limits, and updates the limits if n doesn't overflow them.
+// n against the boundary of the innermost scope, which is the tightest one.
 func (s *Stream) willRead(n uint32) error {
-	if s.remaining > 0 {
-		if n > s.remaining {
-			return ErrValueTooLarge
-		}
-		s.remaining -= n
-	}
-	if s.current != 0 {
-		if n > s.current {
-			return ErrValueTooLarge
-		}
-		s.current -= n
+	f := &s.frames[len(s.frames)-1]
+	if f.bounded && (n > f.end-s.pos) {
+		return ErrValueTooLarge
 	}
 	return nil
 }
@@ -149,13 +149,21 @@ func (s *Stream) decodeOffset() (uint32, error) {
 	if err != nil {
 		return 0, err
 	}
-	offset := binary.LittleEndian.Uint32(buf)
-	if s.lastOffset == 0 {
-		s.lastOffset = offset
-		return offset, nil
+	var (
+		f      = &s.frames[len(s.frames)-1]
+		offset = binary.LittleEndian.Uint32(buf)
+		pos    = f.start + offset
+	)
+	// Offsets can't point beyond the scope or backwards. The first offset is
+	// checked for pointing exactly at the end of fixed part when the first
+	// block is started.
+	if pos < f.start || (f.bounded && pos > f.end) {
+		return 0, ErrInvalidOffset
+	}
+	if len(f.offsets) > 0 && pos < f.offsets[len(f.offsets)-1] {
+		return 0, ErrInvalidOffset
 	}
-	s.stack = append(s.stack, offset-s.lastOffset)
-	s.lastOffset = offset
+	f.offsets = append(f.offsets, pos)
 	return offset, nil
 }
 
@@ -168,18 +176,97 @@ func (s *Stream) ReadOffset() (uint32, error) {
 	return s.decodeOffset()
 }
 
+// DecodeOffsets reads the offsets of a list with variable-size items in the
+// innermost scope and returns the number of items. The item number is derived
+// from the first offset, which points right after the offsets. The number is
+// bounded by the max, or by the size of the scope if the max is zero.
+func (s *Stream) DecodeOffsets(max int) (int, error) {
+	f := &s.frames[len(s.frames)-1]
+	if !f.bounded && max == 0 {
+		return 0, errors.New("ssz: unlimited list in unbounded scope")
+	}
+	if f.bounded && f.end == s.pos {
+		return 0, nil // empty list
+	}
+	first, err := s.decodeOffset()
+	if err != nil {
+		return 0, err
+	}
+	if first == 0 || first%BytesPerLengthOffset != 0 {
+		return 0, ErrInvalidOffset
+	}
+	n := int(first / BytesPerLengthOffset)
+	if max != 0 && n > max {
+		return 0, ErrListTooBig
+	}
+	for i := 1; i < n; i++ {
+		if _, err := s.decodeOffset(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// ListLength returns the number of fixed-size items in the innermost scope.
+func (s *Stream) ListLength(size int, max int) (int, error) {
+	f := &s.frames[len(s.frames)-1]
+	if !f.bounded {
+		return 0, errors.New("ssz: list in unbounded scope")
+	}
+	remain := int(f.end - s.pos)
+	if remain%size != 0 {
+		return 0, fmt.Errorf("ssz: list size %d is not a multiple of item size %d", remain, size)
+	}
+	n := remain / size
+	if max != 0 && n > max {
+		return 0, ErrListTooBig
+	}
+	return n, nil
+}
+
+// BlockStart enters the scope of the next variable-size item, whose position
+// is specified by the offsets read previously.
 func (s *Stream) BlockStart() error {
-	if s.current != 0 {
+	f := &s.frames[len(s.frames)-1]
+	if f.next >= len(f.offsets) {
+		return errors.New("no block available")
+	}
+	if s.pos != f.offsets[f.next] {
+		if f.next == 0 {
+			return ErrInvalidOffset // first offset is not at the end of fixed part
+		}
 		return errors.New("last block is not fully consumed")
 	}
-	if len(s.stack) == 0 {
-		return errors.New("no block available")
+	next := frame{start: s.pos, end: f.end, bounded: f.bounded}
+	if f.next+1 < len(f.offsets) {
+		next.end, next.bounded = f.offsets[f.next+1], true
 	}
-	// TODO
+	f.next += 1
+	s.frames = append(s.frames, next)
 	return nil
 }
 
+// BlockEnd leaves the scope of the current variable-size item, checking that
+// the item is fully consumed.
 func (s *Stream) BlockEnd() error {
-	// TODO
+	if len(s.frames) == 1 {
+		return errors.New("no block started")
+	}
+	f := &s.frames[len(s.frames)-1]
+	if f.bounded && s.pos != f.end {
+		return errors.New("block is not fully consumed")
+	}
+	s.frames = s.frames[:len(s.frames)-1]
+	return nil
+}
+
+// finish checks that the stream with known size is fully consumed.
+func (s *Stream) finish() error {
+	if len(s.frames) != 1 {
+		return errors.New("ssz: unfinished block")
+	}
+	if f := s.frames[0]; f.bounded && s.pos != f.end {
+		return ErrTrailingBytes
+	}
 	return nil
 }
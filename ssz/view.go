@@ -0,0 +1,268 @@
+package ssz
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrIndexOutOfRange = errors.New("ssz: index out of range")
+	ErrForkMismatch    = errors.New("ssz: view is at another fork")
+)
+
+// The helpers below back the generated views, which keep the values in their
+// merkle trees and read or replace the nodes of the fields in place.
+
+// Nodes returns the first count nodes at the depth under this node, from left
+// to right. The leaves found above the depth are regarded as the zero subtrees.
+func (n *Node) Nodes(depth int, count int) []*Node {
+	var (
+		nodes = make([]*Node, 0, count)
+		walk  func(node *Node, depth int)
+	)
+	walk = func(node *Node, depth int) {
+		if len(nodes) == count {
+			return
+		}
+		if depth == 0 {
+			nodes = append(nodes, node)
+			return
+		}
+		if node.IsLeaf() {
+			node = zeroNodes[depth]
+		}
+		walk(node.left, depth-1)
+		walk(node.right, depth-1)
+	}
+	walk(n, depth)
+	return nodes
+}
+
+// Bytes returns the first size bytes packed into the chunks at the depth under
+// this node.
+func (n *Node) Bytes(depth int, size int) []byte {
+	var (
+		nodes = n.Nodes(depth, (size+BytesPerChunk-1)/BytesPerChunk)
+		buf   = make([]byte, 0, len(nodes)*BytesPerChunk)
+	)
+	for _, node := range nodes {
+		root := node.Root()
+		buf = append(buf, root[:]...)
+	}
+	return buf[:size]
+}
+
+// ReadUint returns the little-endian unsigned integer of up to 8 bytes.
+func ReadUint(b []byte) uint64 {
+	var buf [8]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// ListOf returns the root of the items in the tree of the list, along with the
+// depth of the items below it and the length of the list. The limit is counted
+// in items of the given size, which is zero for the composite items. The zero
+// limit means the list is unbounded.
+func ListOf(list *Node, limit uint64, size int) (*Node, int, int) {
+	if list.IsLeaf() {
+		list = zeroNodes[1]
+	}
+	length := int(ReadUint(list.right.Bytes(0, 8)))
+	if limit == 0 {
+		limit = uint64(length)
+	}
+	return list.left, treeDepth(chunksOf(limit, size)), length
+}
+
+// ReadBitlist returns the bitlist in the tree, with the delimiter bit added.
+// The limit is counted in bits.
+func ReadBitlist(list *Node, limit uint64) []byte {
+	if list.IsLeaf() {
+		list = zeroNodes[1]
+	}
+	var (
+		bits  = int(ReadUint(list.right.Bytes(0, 8)))
+		depth = treeDepth((limit + 255) / 256)
+		buf   = make([]byte, bits/8+1)
+	)
+	copy(buf, list.left.Bytes(depth, (bits+7)/8))
+	buf[bits/8] |= 1 << (bits % 8)
+	return buf
+}
+
+// chunksOf returns the number of chunks holding the n items of the given size,
+// which is zero for the composite items taking a chunk each.
+func chunksOf(n uint64, size int) uint64 {
+	if size == 0 {
+		return n
+	}
+	return (n*uint64(size) + BytesPerChunk - 1) / BytesPerChunk
+}
+
+// Sequence is the layout of a list or vector in the tree of a container, for
+// accessing the items without converting the whole value.
+type Sequence struct {
+	GIndex uint64 // generalized index of the value in the tree of the container
+	List   bool   // whether the length is mixed in
+	Limit  uint64 // length of the vector or limit of the list
+	Size   int    // size of the basic items packed, zero for the composite ones
+}
+
+// Len returns the number of items in the tree.
+func (s Sequence) Len(root *Node) (int, error) {
+	if !s.List {
+		return int(s.Limit), nil
+	}
+	gindex, err := ConcatGIndex(s.GIndex, 3)
+	if err != nil {
+		return 0, err
+	}
+	node, err := root.Get(gindex)
+	if err != nil {
+		return 0, err
+	}
+	return int(ReadUint(node.Bytes(0, 8))), nil
+}
+
+// chunk returns the generalized index of the chunk holding the item.
+func (s Sequence) chunk(i int) (uint64, error) {
+	gindex := s.GIndex
+	if s.List {
+		var err error
+		if gindex, err = ConcatGIndex(gindex, 2); err != nil {
+			return 0, err
+		}
+	}
+	chunk := uint64(i)
+	if s.Size != 0 {
+		chunk = uint64(i*s.Size) / BytesPerChunk
+	}
+	depth := treeDepth(chunksOf(s.Limit, s.Size))
+	if depth >= 64 {
+		return 0, ErrGIndexOverflow
+	}
+	return ConcatGIndex(gindex, 1<<depth|chunk)
+}
+
+// check returns an error if the index is beyond the items in the tree.
+func (s Sequence) check(root *Node, i int) error {
+	n, err := s.Len(root)
+	if err != nil {
+		return err
+	}
+	if i < 0 || i >= n {
+		return ErrIndexOutOfRange
+	}
+	return nil
+}
+
+// Item returns the root node of the composite item.
+func (s Sequence) Item(root *Node, i int) (*Node, error) {
+	if err := s.check(root, i); err != nil {
+		return nil, err
+	}
+	gindex, err := s.chunk(i)
+	if err != nil {
+		return nil, err
+	}
+	return root.Get(gindex)
+}
+
+// SetItem returns the tree with the composite item replaced.
+func (s Sequence) SetItem(root *Node, i int, item *Node) (*Node, error) {
+	if err := s.check(root, i); err != nil {
+		return nil, err
+	}
+	return s.setItem(root, i, item)
+}
+
+func (s Sequence) setItem(root *Node, i int, item *Node) (*Node, error) {
+	gindex, err := s.chunk(i)
+	if err != nil {
+		return nil, err
+	}
+	return root.Set(gindex, item)
+}
+
+// Packed returns the basic item packed into the chunks.
+func (s Sequence) Packed(root *Node, i int) (uint64, error) {
+	if err := s.check(root, i); err != nil {
+		return 0, err
+	}
+	gindex, err := s.chunk(i)
+	if err != nil {
+		return 0, err
+	}
+	node, err := root.Get(gindex)
+	if err != nil {
+		return 0, err
+	}
+	chunk, offset := node.Root(), i*s.Size%BytesPerChunk
+	return ReadUint(chunk[offset : offset+s.Size]), nil
+}
+
+// SetPacked returns the tree with the basic item packed into the chunks
+// replaced.
+func (s Sequence) SetPacked(root *Node, i int, v uint64) (*Node, error) {
+	if err := s.check(root, i); err != nil {
+		return nil, err
+	}
+	return s.setPacked(root, i, v)
+}
+
+func (s Sequence) setPacked(root *Node, i int, v uint64) (*Node, error) {
+	gindex, err := s.chunk(i)
+	if err != nil {
+		return nil, err
+	}
+	node, err := root.Get(gindex)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		chunk  = node.Root()
+		buf    [8]byte
+		offset = i * s.Size % BytesPerChunk
+	)
+	binary.LittleEndian.PutUint64(buf[:], v)
+	copy(chunk[offset:offset+s.Size], buf[:])
+	return root.Set(gindex, NewLeaf(chunk))
+}
+
+// Append returns the tree with the composite item appended to the list.
+func (s Sequence) Append(root *Node, item *Node) (*Node, error) {
+	return s.append(root, func(root *Node, i int) (*Node, error) {
+		return s.setItem(root, i, item)
+	})
+}
+
+// AppendPacked returns the tree with the basic item appended to the list.
+func (s Sequence) AppendPacked(root *Node, v uint64) (*Node, error) {
+	return s.append(root, func(root *Node, i int) (*Node, error) {
+		return s.setPacked(root, i, v)
+	})
+}
+
+// append places the item after the last one with set and bumps the length.
+func (s Sequence) append(root *Node, set func(root *Node, i int) (*Node, error)) (*Node, error) {
+	if !s.List {
+		return nil, ErrSizeMismatch
+	}
+	n, err := s.Len(root)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) >= s.Limit {
+		return nil, ErrListTooBig
+	}
+	if root, err = set(root, n); err != nil {
+		return nil, err
+	}
+	gindex, err := ConcatGIndex(s.GIndex, 3)
+	if err != nil {
+		return nil, err
+	}
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(n+1))
+	return root.Set(gindex, NewLeaf(length))
+}
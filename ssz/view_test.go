@@ -0,0 +1,125 @@
+package ssz_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rjl493456442/sszgen/spectests"
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// Tests that the view of the state follows the changes made to the state, with
+// the copies taken before the changes left intact.
+func TestStateView(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	state := new(spectests.BeaconState)
+	state.GenerateRandomSSZ(r, nil)
+	state.Balances = []uint64{1, 2, 3, 4, 5}
+	state.InactivityScores = []uint64{6, 7}
+	state.Validators = []*spectests.Validator{new(spectests.Validator), new(spectests.Validator)}
+	state.Validators[1].GenerateRandomSSZ(r, nil)
+
+	view, err := spectests.NewBeaconStateView(state, ssz.ForkAltair)
+	if err != nil {
+		t.Fatalf("failed to create the view: %v", err)
+	}
+	var (
+		orig     = view.Copy()
+		origRoot = freshRoot(t, state, ssz.ForkAltair)
+		origSlot = state.Slot
+	)
+	if view.HashTreeRoot() != origRoot {
+		t.Fatalf("root mismatch: have %x, want %x", view.HashTreeRoot(), origRoot)
+	}
+	changes := []struct {
+		name   string
+		view   func() error
+		object func()
+	}{
+		{
+			"slot",
+			func() error { return view.SetSlot(origSlot + 1) },
+			func() { state.Slot = origSlot + 1 },
+		},
+		{
+			"balance",
+			func() error { return view.SetBalancesAt(2, 10) },
+			func() { state.Balances[2] = 10 },
+		},
+		{
+			"appended balance",
+			func() error { return view.AppendBalances(11) },
+			func() { state.Balances = append(state.Balances, 11) },
+		},
+		{
+			"balances",
+			func() error { return view.SetBalances([]uint64{12}) },
+			func() { state.Balances = []uint64{12} },
+		},
+		{
+			"inactivity score",
+			func() error { return view.SetInactivityScoresAt(1, 13) },
+			func() { state.InactivityScores[1] = 13 },
+		},
+		{
+			"randao mix",
+			func() error { return view.SetRandaoMixesAt(5, make([]byte, 32)) },
+			func() { state.RandaoMixes[5] = make([]byte, 32) },
+		},
+		{
+			"validator",
+			func() error {
+				v, err := view.GetValidatorsAt(0)
+				if err != nil {
+					return err
+				}
+				if err := v.SetEffectiveBalance(32000000000); err != nil {
+					return err
+				}
+				return view.SetValidatorsAt(0, v)
+			},
+			func() { state.Validators[0].EffectiveBalance = 32000000000 },
+		},
+		{
+			"appended validator",
+			func() error {
+				v, err := spectests.NewValidatorView(state.Validators[1])
+				if err != nil {
+					return err
+				}
+				return view.AppendValidators(v)
+			},
+			func() { state.Validators = append(state.Validators, state.Validators[1]) },
+		},
+	}
+	for _, change := range changes {
+		if err := change.view(); err != nil {
+			t.Fatalf("%s: failed to change the view: %v", change.name, err)
+		}
+		change.object()
+		if have, want := view.HashTreeRoot(), freshRoot(t, state, ssz.ForkAltair); have != want {
+			t.Fatalf("%s: root mismatch: have %x, want %x", change.name, have, want)
+		}
+	}
+	if have := view.GetBalances(); !reflect.DeepEqual(have, state.Balances) {
+		t.Fatalf("balances mismatch: have %v, want %v", have, state.Balances)
+	}
+	if have, _ := view.GetInactivityScoresAt(1); have != 13 {
+		t.Fatalf("inactivity score mismatch: have %d, want 13", have)
+	}
+	if view.LenValidators() != 3 {
+		t.Fatalf("validators length mismatch: have %d, want 3", view.LenValidators())
+	}
+	// The copy taken before the changes is intact
+	if orig.HashTreeRoot() != origRoot || orig.GetSlot() != origSlot || len(orig.GetBalances()) != 5 {
+		t.Fatalf("copy changed along the view")
+	}
+	// The items out of range are rejected
+	if err := view.SetBalancesAt(1, 0); err == nil {
+		t.Fatalf("balance set out of range")
+	}
+	if _, err := view.GetValidatorsAt(3); err == nil {
+		t.Fatalf("validator got out of range")
+	}
+}
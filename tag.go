@@ -4,51 +4,202 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
 )
 
 const (
 	sszTagIdent     = "ssz"
 	sszSizeTagIdent = "ssz-size"
 	sszMaxTagIdent  = "ssz-max"
+	sszNilTagIdent  = "ssz-nil"
+	sszIdxTagIdent  = "ssz-index"
+	sszForkTagIdent = "ssz-fork"
+	sszJSONTagIdent = "ssz-json"
+	jsonTagIdent    = "json"
+)
+
+const (
+	sszIgnoreOption  = "-"       // excludes the field from ssz
+	sszIncludeOption = "include" // opts the unexported field in ssz
+	sszBitlistOption = "bitlist" // marks the byte list as a bitlist
+	sszUint256Option = "uint256" // marks the 32 bytes as a little-endian uint256
+
+	sszBitvectorOption = "bitvector" // marks the bytes as a bitvector, sized in bits
+)
+
+// nilPolicy defines how nil pointers are handled when sizing and encoding.
+type nilPolicy int
+
+const (
+	nilDefault nilPolicy = iota // follow the policy configured for the run
+	nilZero                     // treat nil as the zero value of the pointed type
+	nilReject                   // reject nil with an error in encoding
 )
 
+func parseNilPolicy(input string) (nilPolicy, error) {
+	switch input {
+	case "zero":
+		return nilZero, nil
+	case "reject":
+		return nilReject, nil
+	default:
+		return nilDefault, fmt.Errorf("invalid nil policy %q", input)
+	}
+}
+
+// forkRange is the range of the forks in which the field is present, starting
+// from the fork `from` and ending before the fork `until`.
+type forkRange struct {
+	from  ssz.Fork
+	until ssz.Fork // zero if the field is never removed
+}
+
+// parseForkRange parses the comma separated fork conditions, e.g. "capella",
+// ">=bellatrix,<capella" or "<altair". A bare fork name is the fork which the
+// field is introduced in.
+func parseForkRange(input string) (forkRange, error) {
+	var r forkRange
+	for _, cond := range strings.Split(input, ",") {
+		var (
+			name  = strings.TrimPrefix(strings.TrimPrefix(cond, ">="), "<")
+			until = strings.HasPrefix(cond, "<")
+		)
+		fork, ok := ssz.ForkByName(name)
+		if !ok {
+			return forkRange{}, fmt.Errorf("unknown fork %q", name)
+		}
+		if until {
+			if fork == ssz.ForkPhase0 {
+				return forkRange{}, fmt.Errorf("empty fork range %q", input)
+			}
+			r.until = fork
+		} else {
+			r.from = fork
+		}
+	}
+	if r.until != 0 && r.until <= r.from {
+		return forkRange{}, fmt.Errorf("empty fork range %q", input)
+	}
+	return r, nil
+}
+
+// gated returns whether the field is absent in some of the forks.
+func (r forkRange) gated() bool {
+	return r.from != ssz.ForkPhase0 || r.until != 0
+}
+
+// active returns whether the field is present in the fork.
+func (r forkRange) active(fork ssz.Fork) bool {
+	return fork >= r.from && (r.until == 0 || fork < r.until)
+}
+
 // sizeTag describes the size restriction for types.
 type sizeTag struct {
 	size  int64 // 0 means the size is undefined
 	limit int64 // 0 means the limit is undefined
 }
 
-func parseTag(input string) (bool, []sizeTag, error) {
+// fieldTag is the ssz related tags of a struct field.
+type fieldTag struct {
+	ignored   bool
+	included  bool // whether the unexported field is opted in
+	bitlist   bool // whether the byte list is a bitlist, limited in bits
+	uint256   bool // whether the 32 bytes are a uint256
+	bitvector bool // whether the bytes are a bitvector, sized in bits
+	tagged    bool // whether any ssz tag is present except the ignore one
+	sizes     []sizeTag
+	nilPolicy nilPolicy
+	indexed   bool
+	index     int       // position in the ssz layout, valid only if indexed is set
+	forks     forkRange // forks in which the field is present
+	jsonName  string    // name of the field in json, empty if not specified
+}
+
+func parseTag(input string) (fieldTag, error) {
 	strs := strings.Split(input, " ")
 	if len(strs) == 0 {
-		return false, nil, fmt.Errorf("no tag found")
+		return fieldTag{}, fmt.Errorf("no tag found")
 	}
 	var (
-		ignored bool
-		tags    []sizeTag
-		setTag  = func(i int, v int64, ident string) {
-			if i >= len(tags) {
-				tags = append(tags, make([]sizeTag, i-len(tags)+1)...)
+		tag      fieldTag
+		jsonName string // name from the ssz-json tag, overriding the json one
+		setTag   = func(i int, v int64, ident string) {
+			if i >= len(tag.sizes) {
+				tag.sizes = append(tag.sizes, make([]sizeTag, i-len(tag.sizes)+1)...)
 			}
 			if ident == sszMaxTagIdent {
-				tags[i].limit = v
+				tag.sizes[i].limit = v
 			} else {
-				tags[i].size = v
+				tag.sizes[i].size = v
 			}
 		}
 	)
 	for _, str := range strs {
 		parts := strings.Split(str, ":")
 		if len(parts) != 2 {
-			return false, nil, fmt.Errorf("invalid tag %s", str)
+			return fieldTag{}, fmt.Errorf("invalid tag %s", str)
 		}
 		ident, remain := parts[0], strings.Trim(parts[1], "\"")
 		switch ident {
 		case sszTagIdent:
-			if remain == "-" {
-				ignored = true
+			for _, opt := range strings.Split(remain, ",") {
+				switch opt {
+				case sszIgnoreOption:
+					tag.ignored = true
+				case sszIncludeOption:
+					tag.included = true
+					tag.tagged = true
+				case sszBitlistOption:
+					tag.bitlist = true
+					tag.tagged = true
+				case sszUint256Option:
+					tag.uint256 = true
+					tag.tagged = true
+				case sszBitvectorOption:
+					tag.bitvector = true
+					tag.tagged = true
+				default:
+					return fieldTag{}, fmt.Errorf("unknown ssz option %q", opt)
+				}
+			}
+		case jsonTagIdent:
+			tag.jsonName = strings.Split(remain, ",")[0]
+		case sszJSONTagIdent:
+			// The name in the generated json methods, in place of the json
+			// tag. It's meant for the fork-gated fields sharing the name with
+			// the others, which the json tags can't hold as encoding/json
+			// rejects the duplicated names in a struct.
+			if remain == "" {
+				return fieldTag{}, fmt.Errorf("empty %s tag", sszJSONTagIdent)
+			}
+			jsonName = remain
+		case sszIdxTagIdent:
+			index, err := strconv.Atoi(remain)
+			if err != nil {
+				return fieldTag{}, err
 			}
+			if index < 0 {
+				return fieldTag{}, fmt.Errorf("invalid ssz index %d", index)
+			}
+			tag.indexed, tag.index = true, index
+			tag.tagged = true
+		case sszForkTagIdent:
+			forks, err := parseForkRange(remain)
+			if err != nil {
+				return fieldTag{}, err
+			}
+			tag.forks = forks
+			tag.tagged = true
+		case sszNilTagIdent:
+			policy, err := parseNilPolicy(remain)
+			if err != nil {
+				return fieldTag{}, err
+			}
+			tag.nilPolicy = policy
+			tag.tagged = true
 		case sszMaxTagIdent, sszSizeTagIdent:
+			tag.tagged = true
 			parts := strings.Split(remain, ",")
 			for i, p := range parts {
 				if p == "?" {
@@ -57,11 +208,14 @@ func parseTag(input string) (bool, []sizeTag, error) {
 				}
 				num, err := strconv.ParseInt(p, 10, 64)
 				if err != nil {
-					return false, nil, err
+					return fieldTag{}, err
 				}
 				setTag(i, num, ident)
 			}
 		}
 	}
-	return ignored, tags, nil
+	if jsonName != "" {
+		tag.jsonName = jsonName
+	}
+	return tag, nil
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// generateTests generates the tests for the ssz methods of all the struct
+// types, including a round-trip test, a fuzz target checking the decoding is
+// canonical and the benchmarks for encoding, decoding and hashing. The json round-trip
+// test is included if the json methods are generated. The fork-gated types are
+// tested bound to each of the forks changing them.
+func generateTests(ctx *genContext, types []sszType, json bool) []byte {
+	var (
+		b       bytes.Buffer
+		structs []*sszStruct
+	)
+	for _, typ := range types {
+		if s, ok := typ.(*sszStruct); ok {
+			structs = append(structs, s)
+		}
+	}
+	ctx.addImport("bytes", "")
+	ctx.addImport("math/rand", "")
+	ctx.addImport("testing", "")
+	ctx.addImport(pkgPath, "")
+
+	fmt.Fprint(&b, "type sszTestObject interface {\n")
+	fmt.Fprint(&b, "SizeSSZ() int\n")
+	fmt.Fprint(&b, "MarshalSSZ() ([]byte, error)\n")
+	fmt.Fprint(&b, "MarshalSSZTo(w []byte) ([]byte, error)\n")
+	fmt.Fprintf(&b, "UnmarshalSSZ(s *%s) error\n", ctx.qualifier(pkgPath, "Stream"))
+	fmt.Fprint(&b, "HashTreeRoot() ([32]byte, error)\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprint(&b, "var sszTestTypes = []struct {\n")
+	fmt.Fprint(&b, "name string\n")
+	fmt.Fprint(&b, "new func() sszTestObject\n")
+	fmt.Fprint(&b, "random func(r *rand.Rand) sszTestObject\n")
+	fmt.Fprint(&b, "equal func(a, b sszTestObject) bool\n")
+	fmt.Fprint(&b, "}{\n")
+	var gated bool
+	for _, s := range structs {
+		name := s.typeName()
+		if forkGated(s) {
+			// The fork-gated types are tested at the forks changing them,
+			// compared by the encodings as the absent fields are not decoded.
+			for _, fork := range gatingForks(s) {
+				f := forkIdent(ctx, fork)
+				fmt.Fprint(&b, "{\n")
+				fmt.Fprintf(&b, "name: \"%s/%s\",\n", name, fork)
+				fmt.Fprintf(&b, "new: func() sszTestObject { return ssz.AtFork(new(%s), %s) },\n", name, f)
+				fmt.Fprintf(&b, "random: func(r *rand.Rand) sszTestObject { obj := new(%s); obj.GenerateRandomSSZ(r, nil); return ssz.AtFork(obj, %s) },\n", name, f)
+				fmt.Fprint(&b, "equal: sszEqualEncoding,\n")
+				fmt.Fprint(&b, "},\n")
+			}
+			gated = true
+			continue
+		}
+		fmt.Fprint(&b, "{\n")
+		fmt.Fprintf(&b, "name: %q,\n", name)
+		fmt.Fprintf(&b, "new: func() sszTestObject { return new(%s) },\n", name)
+		fmt.Fprintf(&b, "random: func(r *rand.Rand) sszTestObject { obj := new(%s); obj.GenerateRandomSSZ(r, nil); return obj },\n", name)
+		fmt.Fprintf(&b, "equal: func(a, b sszTestObject) bool { return a.(*%s).Equal(b.(*%s)) },\n", name, name)
+		fmt.Fprint(&b, "},\n")
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	b.WriteString(sszTestsTemplate)
+	if gated {
+		b.WriteString(sszEqualEncodingTemplate)
+	}
+	if json {
+		ctx.addImport("encoding/json", "")
+		b.WriteString(sszJSONTestsTemplate)
+	}
+	return b.Bytes()
+}
+
+// sszTestsTemplate is the type independent part of the generated tests.
+const sszTestsTemplate = `func TestSSZRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 16; i++ {
+				obj := tt.random(r)
+				enc, err := obj.MarshalSSZ()
+				if err != nil {
+					t.Fatalf("failed to encode: %v", err)
+				}
+				if len(enc) != obj.SizeSSZ() {
+					t.Fatalf("encoding size mismatch, want: %d, got: %d", obj.SizeSSZ(), len(enc))
+				}
+				dec := tt.new()
+				if err := ssz.Unmarshal(enc, dec); err != nil {
+					t.Fatalf("failed to decode: %v", err)
+				}
+				if !tt.equal(obj, dec) {
+					t.Fatal("decoded object mismatches the original one")
+				}
+				want, err := obj.HashTreeRoot()
+				if err != nil {
+					t.Fatalf("failed to hash: %v", err)
+				}
+				if have, err := dec.HashTreeRoot(); err != nil || have != want {
+					t.Fatalf("decoded object root mismatch, want: %x, got: %x, err: %v", want, have, err)
+				}
+			}
+		})
+	}
+}
+
+func FuzzUnmarshalSSZ(f *testing.F) {
+	r := rand.New(rand.NewSource(1))
+	for i, tt := range sszTestTypes {
+		enc, err := tt.random(r).MarshalSSZ()
+		if err != nil {
+			f.Fatalf("failed to encode %s: %v", tt.name, err)
+		}
+		f.Add(append([]byte{byte(i)}, enc...))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		tt := sszTestTypes[int(data[0])%len(sszTestTypes)]
+		obj := tt.new()
+		if err := ssz.Unmarshal(data[1:], obj); err != nil {
+			return
+		}
+		enc, err := obj.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("failed to encode decoded %s: %v", tt.name, err)
+		}
+		if !bytes.Equal(enc, data[1:]) {
+			t.Fatalf("non-canonical decoding of %s", tt.name)
+		}
+	})
+}
+
+func BenchmarkMarshalSSZ(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		obj := tt.random(r)
+		b.Run(tt.name, func(b *testing.B) {
+			buf := make([]byte, 0, obj.SizeSSZ())
+			b.SetBytes(int64(obj.SizeSSZ()))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := obj.MarshalSSZTo(buf[:0]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalSSZ(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		enc, err := tt.random(r).MarshalSSZ()
+		if err != nil {
+			b.Fatalf("failed to encode %s: %v", tt.name, err)
+		}
+		b.Run(tt.name, func(b *testing.B) {
+			b.SetBytes(int64(len(enc)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := ssz.Unmarshal(enc, tt.new()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHashTreeRoot(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		obj := tt.random(r)
+		b.Run(tt.name, func(b *testing.B) {
+			b.SetBytes(int64(obj.SizeSSZ()))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := obj.HashTreeRoot(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+`
+
+// sszEqualEncodingTemplate compares the fork-gated types by the encodings.
+const sszEqualEncodingTemplate = `
+func sszEqualEncoding(a, b sszTestObject) bool {
+	encA, errA := a.MarshalSSZ()
+	encB, errB := b.MarshalSSZ()
+	return errA == nil && errB == nil && bytes.Equal(encA, encB)
+}
+`
+
+// sszJSONTestsTemplate is the json round-trip test, which relies on the random
+// instances and the Equal methods as well.
+const sszJSONTestsTemplate = `
+func TestJSONRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range sszTestTypes {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 16; i++ {
+				obj := tt.random(r)
+				enc, err := json.Marshal(obj)
+				if err != nil {
+					t.Fatalf("failed to encode: %v", err)
+				}
+				dec := tt.new()
+				if err := json.Unmarshal(enc, dec); err != nil {
+					t.Fatalf("failed to decode: %v", err)
+				}
+				if !tt.equal(obj, dec) {
+					t.Fatal("decoded object mismatches the original one")
+				}
+			}
+		})
+	}
+}
+`
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"go/types"
+	"strings"
 
 	"github.com/rjl493456442/sszgen/ssz"
 )
@@ -12,15 +13,24 @@ type sszType interface {
 	// Attributes
 	fixed() bool
 	fixedSize() int
+	minSize() uint64 // minimum encoded size, saturating on overflow
+	maxSize() uint64 // maximum encoded size, saturating on overflow
 	typeName() string
 
 	// Operations
 	genSize(ctx *genContext, w string, obj string) string
 	genEncoder(ctx *genContext, obj string) string
 	genDecoder(ctx *genContext, r string, obj string) string
+	genCopy(ctx *genContext, dst string, src string) string
+	genEqual(ctx *genContext, a string, b string) string
+	genRandom(ctx *genContext, obj string) string
+	jsonType(ctx *genContext) string
+	genMarshalJSON(ctx *genContext, dst string, src string) string
+	genUnmarshalJSON(ctx *genContext, dst string, src string) string
+	genHasher(ctx *genContext, obj string) string
 }
 
-func buildType(named *types.Named, typ types.Type, tags []sizeTag) (sszType, error) {
+func buildType(cache *typeCache, named *types.Named, typ types.Type, tags []sizeTag) (sszType, error) {
 	switch t := typ.(type) {
 	case *types.Named:
 		if isBigInt(typ) {
@@ -29,13 +39,29 @@ func buildType(named *types.Named, typ types.Type, tags []sizeTag) (sszType, err
 		if isUint256(typ) {
 			//return uint256Op{}, nil
 		}
-		return buildType(t, typ.Underlying(), tags)
+		key := cache.key(t, tags)
+		built, ok, err := cache.lookup(t, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return built, nil
+		}
+		cache.enter(t, key)
+		defer cache.leave()
+
+		built, err = buildType(cache, t, typ.Underlying(), tags)
+		if err != nil {
+			return nil, err
+		}
+		cache.store(key, built)
+		return built, nil
 	case *types.Basic:
 		return newBasic(named, t)
 	case *types.Array:
-		return newVector(named, t, tags)
+		return newVector(cache, named, t, tags)
 	case *types.Slice:
-		return newList(named, t, tags)
+		return newList(cache, named, t, tags)
 	case *types.Pointer:
 		if isBigInt(t.Elem()) {
 			//return bigIntOp{pointer: true}, nil
@@ -43,9 +69,9 @@ func buildType(named *types.Named, typ types.Type, tags []sizeTag) (sszType, err
 		if isUint256(t.Elem()) {
 			//return uint256Op{pointer: true}, nil
 		}
-		return newPointer(named, t, tags)
+		return newPointer(cache, named, t, tags)
 	case *types.Struct:
-		return newStruct(named, t)
+		return newStruct(cache, named, t)
 	}
 	return nil, fmt.Errorf("unsupported type %s", typ.String())
 }
@@ -111,7 +137,7 @@ func (b *sszBasic) genEncoder(ctx *genContext, obj string) string {
 	if b.named != nil {
 		obj = fmt.Sprintf("%s(%s)", b.typeName(), obj) // explicit type conversion
 	}
-	return fmt.Sprintf("%s(w, %s)\n", ctx.qualifier(pkgPath, b.encoder), obj)
+	return fmt.Sprintf("w = %s(w, %s)\n", ctx.qualifier(pkgPath, b.encoder), obj)
 }
 
 func (b *sszBasic) genDecoder(ctx *genContext, r string, obj string) string {
@@ -138,11 +164,13 @@ type sszVector struct {
 	elem    sszType
 	len     int64
 	tag     sizeTag
+	uint256 bool  // the bytes are a little-endian uint256
+	bits    int64 // number of bits if the bytes are a bitvector
 	encoder string
 	decoder string
 }
 
-func newVector(named *types.Named, typ *types.Array, tags []sizeTag) (*sszVector, error) {
+func newVector(cache *typeCache, named *types.Named, typ *types.Array, tags []sizeTag) (*sszVector, error) {
 	var (
 		tag    sizeTag
 		remain []sizeTag
@@ -156,7 +184,7 @@ func newVector(named *types.Named, typ *types.Array, tags []sizeTag) (*sszVector
 	if tag.limit != 0 {
 		return nil, fmt.Errorf("unexpected size limit tag")
 	}
-	elem, err := buildType(nil, typ.Elem(), remain)
+	elem, err := buildType(cache, nil, typ.Elem(), remain)
 	if err != nil {
 		return nil, err
 	}
@@ -209,17 +237,21 @@ func (v *sszVector) genSize(ctx *genContext, w string, obj string) string {
 }
 
 func (v *sszVector) genEncoder(ctx *genContext, obj string) string {
+	var b bytes.Buffer
 	if v.encoder != "" {
-		return fmt.Sprintf("%s(w, %s[:])\n", ctx.qualifier(pkgPath, v.encoder), obj)
+		if v.bits != 0 {
+			fmt.Fprint(&b, genBitvectorCheck(ctx, obj+"[:]", v.bits, "nil, "))
+		}
+		fmt.Fprintf(&b, "w = %s(w, %s[:])\n", ctx.qualifier(pkgPath, v.encoder), obj)
+		return b.String()
 	}
-	var b bytes.Buffer
 	if !v.elem.fixed() {
 		offset := ctx.tmpVar("o")
 		fmt.Fprintf(&b, "%s := len(%s)*4\n", offset, obj)
 
 		vid := ctx.tmpVar("v")
 		fmt.Fprintf(&b, "for _, %s := range %s {\n", vid, obj)
-		fmt.Fprintf(&b, "%s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), offset)
+		fmt.Fprintf(&b, "w = %s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), offset)
 		fmt.Fprintf(&b, "%s", v.elem.genSize(ctx, offset, vid))
 		fmt.Fprint(&b, "}\n")
 	}
@@ -241,6 +273,9 @@ func (v *sszVector) genDecoder(ctx *genContext, r string, obj string) string {
 		fmt.Fprintf(&b, "if %s != nil {\n", err)
 		fmt.Fprintf(&b, "return %s\n", err)
 		fmt.Fprint(&b, "}\n")
+		if v.bits != 0 {
+			fmt.Fprint(&b, genBitvectorCheck(ctx, vn, v.bits, ""))
+		}
 		fmt.Fprintf(&b, "%s = %s(%s)\n", obj, v.typeName(), vn)
 		return b.String()
 	}
@@ -273,11 +308,14 @@ type sszList struct {
 	named   *types.Named
 	elem    sszType
 	tag     sizeTag
+	bitlist bool  // the limit is counted in bits and the encoding is delimited
+	uint256 bool  // the bytes are a little-endian uint256
+	bits    int64 // number of bits if the bytes are a bitvector
 	encoder string
 	decoder string
 }
 
-func newList(named *types.Named, slice *types.Slice, tags []sizeTag) (*sszList, error) {
+func newList(cache *typeCache, named *types.Named, slice *types.Slice, tags []sizeTag) (*sszList, error) {
 	var (
 		tag    sizeTag
 		remain []sizeTag
@@ -285,7 +323,13 @@ func newList(named *types.Named, slice *types.Slice, tags []sizeTag) (*sszList,
 	if len(tags) > 0 {
 		tag, remain = tags[0], tags[1:]
 	}
-	elem, err := buildType(nil, slice.Elem(), remain)
+	// Lists without the size tag are variable-length, which makes recursive
+	// references to the enclosing types legal.
+	if tag.size == 0 {
+		cache.enterList()
+		defer cache.leave()
+	}
+	elem, err := buildType(cache, nil, slice.Elem(), remain)
 	if err != nil {
 		return nil, err
 	}
@@ -344,8 +388,46 @@ func (l *sszList) genSize(ctx *genContext, w string, obj string) string {
 }
 
 func (l *sszList) genEncoder(ctx *genContext, obj string) string {
+	var b bytes.Buffer
+	ctx.addImport(pkgPath, "")
+
+	if l.bitlist {
+		err := ctx.tmpVar("e")
+		fmt.Fprintf(&b, "if %s := %s(%s, %d); %s != nil {\n", err, ctx.qualifier(pkgPath, "ValidateBitlist"), obj, l.tag.limit, err)
+		fmt.Fprintf(&b, "return nil, %s\n", err)
+		fmt.Fprint(&b, "}\n")
+	} else if l.tag.limit != 0 {
+		fmt.Fprintf(&b, "if len(%s) > %d {\n", obj, l.tag.limit)
+		fmt.Fprintf(&b, "return nil, %s\n", ctx.qualifier(pkgPath, "ErrListTooBig"))
+		fmt.Fprint(&b, "}\n")
+	} else if l.bits != 0 {
+		fmt.Fprint(&b, genBitvectorCheck(ctx, obj, l.bits, "nil, "))
+	}
+	if l.tag.size == 0 {
+		fmt.Fprint(&b, l.genItemsEncoder(ctx, obj))
+		return b.String()
+	}
+	// The lists with fixed size must have the exact length. The empty ones
+	// are regarded as the zero value, if the zero value has fixed encoding.
+	if l.elem.fixed() {
+		fmt.Fprintf(&b, "if len(%s) == 0 {\n", obj)
+		fmt.Fprintf(&b, "w = %s(w, %d)\n", ctx.qualifier(pkgPath, "EncodeZeros"), l.fixedSize())
+		fmt.Fprintf(&b, "} else if len(%s) != %d {\n", obj, l.tag.size)
+	} else {
+		fmt.Fprintf(&b, "if len(%s) != %d {\n", obj, l.tag.size)
+	}
+	fmt.Fprintf(&b, "return nil, %s\n", ctx.qualifier(pkgPath, "ErrSizeMismatch"))
+	fmt.Fprint(&b, "} else {\n")
+	fmt.Fprint(&b, l.genItemsEncoder(ctx, obj))
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// genItemsEncoder generates the encoder of the list items without any
+// length check.
+func (l *sszList) genItemsEncoder(ctx *genContext, obj string) string {
 	if l.encoder != "" {
-		return fmt.Sprintf("%s(w, %s)\n", ctx.qualifier(pkgPath, l.encoder), obj)
+		return fmt.Sprintf("w = %s(w, %s)\n", ctx.qualifier(pkgPath, l.encoder), obj)
 	}
 	var b bytes.Buffer
 	if !l.elem.fixed() {
@@ -354,7 +436,7 @@ func (l *sszList) genEncoder(ctx *genContext, obj string) string {
 
 		vid := ctx.tmpVar("v")
 		fmt.Fprintf(&b, "for _, %s := range %s {\n", vid, obj)
-		fmt.Fprintf(&b, "%s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), oid)
+		fmt.Fprintf(&b, "w = %s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), oid)
 		fmt.Fprintf(&b, "%s", l.elem.genSize(ctx, oid, vid))
 		fmt.Fprint(&b, "}\n")
 	}
@@ -378,35 +460,63 @@ func (l *sszList) genDecoder(ctx *genContext, r string, obj string) string {
 		fmt.Fprintf(&b, "if %s != nil {\n", err)
 		fmt.Fprintf(&b, "return %s\n", err)
 		fmt.Fprint(&b, "}\n")
+		if l.bitlist {
+			fmt.Fprintf(&b, "if %s := %s(%s, %d); %s != nil {\n", err, ctx.qualifier(pkgPath, "ValidateBitlist"), v, l.tag.limit, err)
+			fmt.Fprintf(&b, "return %s\n", err)
+			fmt.Fprint(&b, "}\n")
+		} else if l.tag.limit != 0 {
+			fmt.Fprintf(&b, "if len(%s) > %d {\n", v, l.tag.limit)
+			fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrListTooBig"))
+			fmt.Fprint(&b, "}\n")
+		} else if l.bits != 0 {
+			fmt.Fprint(&b, genBitvectorCheck(ctx, v, l.bits, ""))
+		}
 		fmt.Fprintf(&b, "%s = %s\n", obj, v)
 		return b.String()
 	}
+	typ := ctx.typeString(l.slice)
+	if l.named != nil {
+		typ = ctx.typeString(l.named)
+	}
+	var (
+		cnt = ctx.tmpVar("i")
+		num = ctx.tmpVar("n")
+		err = ctx.tmpVar("e")
+	)
+	// Resolve the list length, which is either specified by the size tag,
+	// or derived from the block size for the fixed-size items, or from the
+	// first offset for the variable-size ones.
+	switch {
+	case l.tag.size != 0:
+		fmt.Fprintf(&b, "%s := %d\n", num, l.tag.size)
+	case l.elem.fixed():
+		fmt.Fprintf(&b, "%s, %s := %s.ListLength(%d, %d)\n", num, err, r, l.elem.fixedSize(), l.tag.limit)
+	default:
+		fmt.Fprintf(&b, "%s, %s := %s.DecodeOffsets(%d)\n", num, err, r, l.tag.limit)
+	}
+	if l.tag.size == 0 {
+		fmt.Fprintf(&b, "if %s != nil {\n", err)
+		fmt.Fprintf(&b, "return %s\n", err)
+		fmt.Fprint(&b, "}\n")
+	}
+	fmt.Fprintf(&b, "%s = make(%s, %s)\n", obj, typ, num)
+
 	if l.elem.fixed() {
-		var cnt = ctx.tmpVar("i")
-		fmt.Fprintf(&b, "for %s := 0; %s < len(%s); %s += 1 {\n", cnt, cnt, obj, cnt)
+		fmt.Fprintf(&b, "for %s := 0; %s < %s; %s += 1 {\n", cnt, cnt, num, cnt)
 		fmt.Fprintf(&b, "%s", l.elem.genDecoder(ctx, r, fmt.Sprintf("%s[%s]", obj, cnt)))
 		fmt.Fprint(&b, "}\n") // curly brace for loop
 		return b.String()
 	}
-	// Decode length
-
-	// length, err := s.DecodeOffset()
-	// for i := 1; i < length; i++ {
-	//      DecodeOffset
-	// }
-	// for i := 0; i < length; i++ {
-	//      DecodeOffset
-	// }
-
-	cnt := ctx.tmpVar("i")
-	fmt.Fprintf(&b, "for %s := 0; %s < len(%s); %s += 1 {\n", cnt, cnt, obj, cnt)
-	fmt.Fprintf(&b, "if err := %s.DecodeOffset(); err != nil{\n", r)
-	fmt.Fprintf(&b, "return err\n")
-	fmt.Fprint(&b, "}\n") // curly brace for if
-	fmt.Fprint(&b, "}\n") // curly brace for loop
-
+	// Decode offsets, they're already decoded if the length is unknown
+	if l.tag.size != 0 {
+		fmt.Fprintf(&b, "for %s := 0; %s < %s; %s += 1 {\n", cnt, cnt, num, cnt)
+		fmt.Fprintf(&b, "if err := %s.DecodeOffset(); err != nil{\n", r)
+		fmt.Fprintf(&b, "return err\n")
+		fmt.Fprint(&b, "}\n") // curly brace for if
+		fmt.Fprint(&b, "}\n") // curly brace for loop
+	}
 	// Decode elements
-	fmt.Fprintf(&b, "for %s := 0; %s < len(%s); %s += 1 {\n", cnt, cnt, obj, cnt)
+	fmt.Fprintf(&b, "for %s := 0; %s < %s; %s += 1 {\n", cnt, cnt, num, cnt)
 	wrapList(ctx, r, &b, func() {
 		fmt.Fprintf(&b, "%s", l.elem.genDecoder(ctx, r, fmt.Sprintf("%s[%s]", obj, cnt)))
 	})
@@ -419,45 +529,134 @@ type sszStruct struct {
 	named      *types.Named
 	fields     []sszType
 	fieldNames []string
+	jsonNames  []string        // "-" if the field is excluded from json
+	forks      []forkRange     // forks in which the fields are present
+	atFork     bool            // whether the struct is the view at a fork, see forkView
+	sizing     bool            // whether the max size is being resolved
+	cache      string          // name of the ssz.HashCache field, empty if none
+	each       map[string]bool // fields opted into the each decoders, see eachDirective
 }
 
-func newStruct(named *types.Named, typ *types.Struct) (*sszStruct, error) {
+func newStruct(cache *typeCache, named *types.Named, typ *types.Struct) (*sszStruct, error) {
+	// Register the struct before resolving the fields for the recursive
+	// references to it.
+	s := &sszStruct{
+		Struct: typ,
+		named:  named,
+	}
+	cache.define(named, s)
+
 	var (
-		fields     []sszType
-		fieldNames []string
+		indexes []int // explicit layout position of the fields, if any
+		indexed int   // number of fields with explicit position
 	)
 	for i := 0; i < typ.NumFields(); i++ {
 		f := typ.Field(i)
-		if !f.Exported() {
+
+		// The hash cache is not a part of the ssz layout, but opts the struct
+		// in caching the trees of the large fields.
+		if isHashCache(f.Type()) {
+			if s.cache != "" {
+				return nil, fmt.Errorf("duplicated hash cache fields %s and %s", s.cache, f.Name())
+			}
+			s.cache = f.Name()
 			continue
 		}
 		var (
-			err     error
-			ignored bool
-			tags    []sizeTag
+			err error
+			tag fieldTag
 		)
-		if tag := typ.Tag(i); tag != "" {
-			ignored, tags, err = parseTag(tag)
+		if raw := typ.Tag(i); raw != "" {
+			tag, err = parseTag(raw)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("field %s: %v", f.Name(), err)
 			}
 		}
-		if ignored {
+		if tag.ignored {
 			continue
 		}
-		field, err := buildType(nil, f.Type(), tags)
+		// Unexported fields are skipped unless explicitly opted in. Reject the
+		// ones tagged for ssz without the opt-in as they are likely mistakes.
+		if !f.Exported() {
+			if !tag.tagged {
+				continue
+			}
+			if !tag.included {
+				return nil, fmt.Errorf("unexported field %s is tagged for ssz without the %q option", f.Name(), sszIncludeOption)
+			}
+		}
+		// The size of the bitvector is counted in bits, resolve the bytes
+		// holding them for building the type.
+		var bits int64
+		if tag.bitvector {
+			if len(tag.sizes) != 1 || tag.sizes[0].size == 0 {
+				return nil, fmt.Errorf("field %s: bitvector must have a single size tag", f.Name())
+			}
+			bits = tag.sizes[0].size
+			tag.sizes[0].size = (bits + 7) / 8
+		}
+		field, err := buildType(cache, nil, f.Type(), tag.sizes)
 		if err != nil {
 			return nil, err
 		}
-		fields = append(fields, field)
-		fieldNames = append(fieldNames, f.Name())
+		if tag.bitvector {
+			if field, err = asBitvector(field, bits); err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.Name(), err)
+			}
+		}
+		if tag.bitlist {
+			if field, err = asBitlist(field); err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.Name(), err)
+			}
+		}
+		if tag.uint256 {
+			if field, err = asUint256(field); err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.Name(), err)
+			}
+		}
+		if tag.nilPolicy != nilDefault {
+			var ok bool
+			if field, ok = withNilPolicy(field, tag.nilPolicy); !ok {
+				return nil, fmt.Errorf("nil policy is set on field %s without pointer", f.Name())
+			}
+		}
+		if tag.indexed {
+			indexed += 1
+		}
+		s.fields = append(s.fields, field)
+		s.fieldNames = append(s.fieldNames, f.Name())
+		if tag.jsonName == "" {
+			tag.jsonName = f.Name()
+		}
+		s.jsonNames = append(s.jsonNames, tag.jsonName)
+		s.forks = append(s.forks, tag.forks)
+		indexes = append(indexes, tag.index)
 	}
-	return &sszStruct{
-		Struct:     typ,
-		named:      named,
-		fields:     fields,
-		fieldNames: fieldNames,
-	}, nil
+	if indexed == 0 {
+		return s, nil
+	}
+	// Reorder the fields by the explicit positions, which must be specified on
+	// all the fields and form a contiguous sequence starting from zero.
+	if indexed != len(s.fields) {
+		return nil, fmt.Errorf("ssz index is only specified on %d of %d fields of %s", indexed, len(s.fields), s.typeName())
+	}
+	var (
+		fields = make([]sszType, len(s.fields))
+		names  = make([]string, len(s.fields))
+		jsons  = make([]string, len(s.fields))
+		forks  = make([]forkRange, len(s.fields))
+	)
+	for i, index := range indexes {
+		if index >= len(fields) {
+			return nil, fmt.Errorf("ssz index %d of field %s is out of range, fields: %d", index, s.fieldNames[i], len(fields))
+		}
+		if fields[index] != nil {
+			return nil, fmt.Errorf("duplicated ssz index %d of fields %s and %s", index, names[index], s.fieldNames[i])
+		}
+		fields[index], names[index], jsons[index], forks[index] = s.fields[i], s.fieldNames[i], s.jsonNames[i], s.forks[i]
+	}
+	s.fields, s.fieldNames, s.jsonNames, s.forks = fields, names, jsons, forks
+	return s, nil
 }
 
 func (s *sszStruct) fixed() bool {
@@ -484,9 +683,37 @@ func (s *sszStruct) typeName() string {
 	return s.named.Obj().Name()
 }
 
+// fieldIndex returns the position of the named field in the Go struct.
+func (s *sszStruct) fieldIndex(name string) int {
+	for i := 0; i < s.NumFields(); i++ {
+		if s.Field(i).Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// genCacheReset generates the reset of the hash cache of the struct, if any,
+// for the fields being overwritten.
+func (s *sszStruct) genCacheReset(obj string) string {
+	if s.cache == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s.Reset()\n", obj, s.cache)
+}
+
+// call returns the invocation of the method on the nested struct, with the fork
+// passed along if the struct is viewed at the fork.
+func (s *sszStruct) call(method string, args ...string) string {
+	if s.atFork {
+		method, args = method+"ForFork", append(args, "fork")
+	}
+	return fmt.Sprintf("%s(%s)", method, strings.Join(args, ", "))
+}
+
 func (s *sszStruct) genSize(ctx *genContext, w string, obj string) string {
 	if !ctx.topType {
-		return fmt.Sprintf("%s += %s.SizeSSZ()\n", w, obj)
+		return fmt.Sprintf("%s += %s.%s\n", w, obj, s.call("SizeSSZ"))
 	}
 	ctx.topType = false
 
@@ -509,9 +736,15 @@ func (s *sszStruct) genSize(ctx *genContext, w string, obj string) string {
 func (s *sszStruct) genEncoder(ctx *genContext, obj string) string {
 	var b bytes.Buffer
 	if !ctx.topType {
-		fmt.Fprintf(&b, "if err := %s.MarshalSSZTo(w); err != nil {\n", obj)
-		fmt.Fprint(&b, "return err\n")
+		var (
+			wid = ctx.tmpVar("w")
+			err = ctx.tmpVar("e")
+		)
+		fmt.Fprintf(&b, "%s, %s := %s.%s\n", wid, err, obj, s.call("MarshalSSZTo", "w"))
+		fmt.Fprintf(&b, "if %s != nil {\n", err)
+		fmt.Fprintf(&b, "return nil, %s\n", err)
 		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "w = %s\n", wid)
 		return b.String()
 	}
 	ctx.topType = false
@@ -529,7 +762,7 @@ func (s *sszStruct) genEncoder(ctx *genContext, obj string) string {
 		if field.fixed() {
 			fmt.Fprintf(&b, "%s", field.genEncoder(ctx, fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
 		} else {
-			fmt.Fprintf(&b, "%s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), oid)
+			fmt.Fprintf(&b, "w = %s(w, uint32(%s))\n", ctx.qualifier(pkgPath, "EncodeUint32"), oid)
 			fmt.Fprintf(&b, "%s", field.genSize(ctx, oid, fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
 		}
 	}
@@ -545,13 +778,14 @@ func (s *sszStruct) genEncoder(ctx *genContext, obj string) string {
 func (s *sszStruct) genDecoder(ctx *genContext, r string, obj string) string {
 	var b bytes.Buffer
 	if !ctx.topType {
-		fmt.Fprintf(&b, "if err := %s.UnmarshalSSZ(%s); err != nil {\n", obj, r)
+		fmt.Fprintf(&b, "if err := %s.%s; err != nil {\n", obj, s.call("UnmarshalSSZ", r))
 		fmt.Fprint(&b, "return err\n")
 		fmt.Fprint(&b, "}\n")
 		return b.String()
 	}
 	ctx.topType = false
 
+	fmt.Fprint(&b, s.genCacheReset(obj))
 	for i, field := range s.fields {
 		if field.fixed() {
 			fmt.Fprintf(&b, "%s", field.genDecoder(ctx, r, fmt.Sprintf("%s.%s", obj, s.fieldNames[i])))
@@ -572,12 +806,13 @@ func (s *sszStruct) genDecoder(ctx *genContext, r string, obj string) string {
 
 type sszPointer struct {
 	*types.Pointer
-	named *types.Named
-	elem  sszType
+	named     *types.Named
+	elem      sszType
+	nilPolicy nilPolicy
 }
 
-func newPointer(named *types.Named, typ *types.Pointer, tags []sizeTag) (*sszPointer, error) {
-	elem, err := buildType(nil, typ.Elem(), tags)
+func newPointer(cache *typeCache, named *types.Named, typ *types.Pointer, tags []sizeTag) (*sszPointer, error) {
+	elem, err := buildType(cache, nil, typ.Elem(), tags)
 	if err != nil {
 		return nil, err
 	}
@@ -600,21 +835,46 @@ func (p *sszPointer) fixedSize() int {
 	return p.elem.fixedSize()
 }
 
+// policy returns the nil pointer policy in effect for the pointer.
+func (p *sszPointer) policy(ctx *genContext) nilPolicy {
+	if p.nilPolicy != nilDefault {
+		return p.nilPolicy
+	}
+	return ctx.nilPolicy
+}
+
+// genSize treats the nil pointer as the zero value without writing it back,
+// as sizing must not mutate the object. It's shared by both nil policies
+// since sizing can't fail.
 func (p *sszPointer) genSize(ctx *genContext, w string, obj string) string {
-	var b bytes.Buffer
-	fmt.Fprintf(&b, "if %s == nil {\n", obj)
-	fmt.Fprintf(&b, "%s = new(%s)\n", obj, p.elem.typeName())
+	var (
+		b   bytes.Buffer
+		pid = ctx.tmpVar("p")
+	)
+	fmt.Fprintf(&b, "%s := %s\n", pid, obj)
+	fmt.Fprintf(&b, "if %s == nil {\n", pid)
+	fmt.Fprintf(&b, "%s = new(%s)\n", pid, p.elem.typeName())
 	fmt.Fprint(&b, "}\n")
-	fmt.Fprintf(&b, "%s", p.elem.genSize(ctx, w, obj))
+	fmt.Fprintf(&b, "%s", p.elem.genSize(ctx, w, pid))
 	return b.String()
 }
 
 func (p *sszPointer) genEncoder(ctx *genContext, obj string) string {
 	var b bytes.Buffer
-	fmt.Fprintf(&b, "if %s == nil {\n", obj)
-	fmt.Fprintf(&b, "%s = new(%s)\n", obj, p.elem.typeName())
+	if p.policy(ctx) == nilReject {
+		ctx.addImport(pkgPath, "")
+		fmt.Fprintf(&b, "if %s == nil {\n", obj)
+		fmt.Fprintf(&b, "return nil, %s\n", ctx.qualifier(pkgPath, "ErrNilPointer"))
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "%s", p.elem.genEncoder(ctx, obj))
+		return b.String()
+	}
+	pid := ctx.tmpVar("p")
+	fmt.Fprintf(&b, "%s := %s\n", pid, obj)
+	fmt.Fprintf(&b, "if %s == nil {\n", pid)
+	fmt.Fprintf(&b, "%s = new(%s)\n", pid, p.elem.typeName())
 	fmt.Fprint(&b, "}\n")
-	fmt.Fprintf(&b, "%s", p.elem.genEncoder(ctx, obj))
+	fmt.Fprintf(&b, "%s", p.elem.genEncoder(ctx, pid))
 	return b.String()
 }
 
@@ -627,6 +887,109 @@ func (p *sszPointer) genDecoder(ctx *genContext, r string, obj string) string {
 	return b.String()
 }
 
+// withNilPolicy returns the variant of the pointer, or of the list and vector
+// with pointer elements, which applies the nil policy. The nodes are copied as
+// the types might be shared with the fields having another policy. False is
+// returned if there is no pointer to apply on.
+func withNilPolicy(typ sszType, policy nilPolicy) (sszType, bool) {
+	switch t := typ.(type) {
+	case *sszPointer:
+		cpy := *t
+		cpy.nilPolicy = policy
+		return &cpy, true
+	case *sszList:
+		elem, ok := withNilPolicy(t.elem, policy)
+		if !ok {
+			return nil, false
+		}
+		cpy := *t
+		cpy.elem = elem
+		return &cpy, true
+	case *sszVector:
+		elem, ok := withNilPolicy(t.elem, policy)
+		if !ok {
+			return nil, false
+		}
+		cpy := *t
+		cpy.elem = elem
+		return &cpy, true
+	}
+	return nil, false
+}
+
+// asBitlist returns the bitlist variant of the byte list. The node is copied
+// as the list type might be shared with the fields not tagged as bitlist.
+func asBitlist(typ sszType) (sszType, error) {
+	l, ok := typ.(*sszList)
+	if !ok || !isBytes(l.elem) {
+		return nil, fmt.Errorf("bitlist must be a byte list, got %s", typ.typeName())
+	}
+	if l.tag.size != 0 {
+		return nil, fmt.Errorf("bitlist must be variable-length")
+	}
+	cpy := *l
+	cpy.bitlist = true
+	return &cpy, nil
+}
+
+// asBitvector returns the bitvector variant of the fixed-size bytes. The node
+// is copied as the type might be shared with the fields not tagged as bitvector.
+func asBitvector(typ sszType, bits int64) (sszType, error) {
+	switch t := typ.(type) {
+	case *sszVector:
+		if isBytes(t.elem) {
+			cpy := *t
+			cpy.bits = bits
+			return &cpy, nil
+		}
+	case *sszList:
+		if isBytes(t.elem) && t.tag.size != 0 && !t.bitlist {
+			cpy := *t
+			cpy.bits = bits
+			return &cpy, nil
+		}
+	}
+	return nil, fmt.Errorf("bitvector must be fixed-size bytes, got %s", typ.typeName())
+}
+
+// genBitvectorCheck generates the check of the padding bits of the bitvector,
+// returning the error along with the given leading return values. Nothing is
+// checked if the bits fill up the bytes.
+func genBitvectorCheck(ctx *genContext, obj string, bits int64, ret string) string {
+	if bits%8 == 0 {
+		return ""
+	}
+	var (
+		b   bytes.Buffer
+		err = ctx.tmpVar("e")
+	)
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "if %s := %s(%s, %d); %s != nil {\n", err, ctx.qualifier(pkgPath, "ValidateBitvector"), obj, bits, err)
+	fmt.Fprintf(&b, "return %s%s\n", ret, err)
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// asUint256 returns the uint256 variant of the 32 bytes. The node is copied as
+// the type might be shared with the fields not tagged as uint256.
+func asUint256(typ sszType) (sszType, error) {
+	switch t := typ.(type) {
+	case *sszVector:
+		if isBytes(t.elem) && t.len == 32 {
+			cpy := *t
+			cpy.uint256 = true
+			return &cpy, nil
+		}
+	case *sszList:
+		if isBytes(t.elem) && t.tag.size == 32 && !t.bitlist {
+			cpy := *t
+			cpy.uint256 = true
+			return &cpy, nil
+		}
+	}
+	return nil, fmt.Errorf("uint256 must be 32 bytes, got %s", typ.typeName())
+}
+
 // isBigInt checks whether 'typ' is "math/big".Int.
 func isBigInt(typ types.Type) bool {
 	named, ok := typ.(*types.Named)
@@ -637,6 +1000,16 @@ func isBigInt(typ types.Type) bool {
 	return name.Pkg().Path() == "math/big" && name.Name() == "Int"
 }
 
+// isHashCache checks whether 'typ' is the ssz.HashCache.
+func isHashCache(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	name := named.Obj()
+	return name.Pkg().Path() == pkgPath && name.Name() == "HashCache"
+}
+
 // isUint256 checks whether 'typ' is "github.com/holiman/uint256".Int.
 func isUint256(typ types.Type) bool {
 	named, ok := typ.(*types.Named)
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// buildSource type-checks the source of a package and builds the named types
+// in it.
+func buildSource(t *testing.T, src string, names ...string) (*types.Package, []sszType, error) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", src, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the source: %v", err)
+	}
+	pkg, err := new(types.Config).Check("test", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("failed to check the source: %v", err)
+	}
+	built, err := parsePackage(pkg, names)
+	return pkg, built, err
+}
+
+// Tests that the nil policies of the fields sharing a named list type are kept
+// apart, regardless of the order of the fields.
+func TestNilPolicySharedType(t *testing.T) {
+	for _, fields := range []string{
+		"A Xs `ssz-max:\"4\" ssz-nil:\"reject\"`\nB Xs `ssz-max:\"4\"`",
+		"B Xs `ssz-max:\"4\"`\nA Xs `ssz-max:\"4\" ssz-nil:\"reject\"`",
+	} {
+		src := "package test\ntype T struct{ V uint64 }\ntype Xs []*T\ntype S struct {\n" + fields + "\n}\n"
+		pkg, built, err := buildSource(t, src, "S")
+		if err != nil {
+			t.Fatalf("failed to build: %v", err)
+		}
+		s := built[0].(*sszStruct)
+		for i, name := range s.fieldNames {
+			want := nilDefault
+			if name == "A" {
+				want = nilReject
+			}
+			if have := s.fields[i].(*sszList).elem.(*sszPointer).nilPolicy; have != want {
+				t.Errorf("field %s: nil policy mismatch: have %d, want %d", name, have, want)
+			}
+		}
+		code, err := generateEncoder(newGenContext(pkg, nilZero), s)
+		if err != nil {
+			t.Fatalf("failed to generate: %v", err)
+		}
+		if n := strings.Count(string(code), "ErrNilPointer"); n != 1 {
+			t.Errorf("nil pointers rejected %d times, want once:\n%s", n, code)
+		}
+	}
+}
+
+// Tests the layout of the fields by the ssz-index tags, and the rejection of
+// the partial, sparse and duplicated indexes.
+func TestFieldIndexes(t *testing.T) {
+	tests := []struct {
+		fields string
+		want   []string // field names in layout order
+		err    string
+	}{
+		{
+			fields: "A uint64 `ssz-index:\"1\"`\nB uint64 `ssz-index:\"2\"`\nC uint64 `ssz-index:\"0\"`",
+			want:   []string{"C", "A", "B"},
+		},
+		{
+			fields: "A uint64\nB uint64 `ssz-index:\"0\"`",
+			err:    "ssz index is only specified on 1 of 2 fields of S",
+		},
+		{
+			fields: "A uint64 `ssz-index:\"0\"`\nB uint64 `ssz-index:\"2\"`",
+			err:    "ssz index 2 of field B is out of range, fields: 2",
+		},
+		{
+			fields: "A uint64 `ssz-index:\"1\"`\nB uint64 `ssz-index:\"1\"`",
+			err:    "duplicated ssz index 1 of fields A and B",
+		},
+		{
+			fields: "A uint64 `ssz-index:\"-1\"`",
+			err:    "field A: invalid ssz index -1",
+		},
+	}
+	for _, tt := range tests {
+		_, built, err := buildSource(t, "package test\ntype S struct {\n"+tt.fields+"\n}\n", "S")
+		checkBuild(t, tt.fields, built, err, tt.want, tt.err)
+	}
+}
+
+// Tests that the unexported fields are only included with the opt-in, and the
+// unknown ssz options are rejected rather than changing the layout silently.
+func TestFieldInclusion(t *testing.T) {
+	tests := []struct {
+		fields string
+		want   []string // field names in layout order
+		err    string
+	}{
+		{
+			fields: "A uint64\nb uint64\nC uint64 `ssz:\"-\"`",
+			want:   []string{"A"},
+		},
+		{
+			fields: "A uint64\nb uint64 `ssz:\"include\"`",
+			want:   []string{"A", "b"},
+		},
+		{
+			fields: "A uint64\nb []byte `ssz:\"include\" ssz-max:\"8\"`",
+			want:   []string{"A", "b"},
+		},
+		{
+			fields: "A uint64\nb []byte `ssz-max:\"8\"`",
+			err:    "unexported field b is tagged for ssz without the \"include\" option",
+		},
+		{
+			fields: "A uint64\nb uint64 `ssz:\"inclde\"`",
+			err:    "field b: unknown ssz option \"inclde\"",
+		},
+		{
+			fields: "A []byte `ssz:\"bitlst\" ssz-max:\"8\"`",
+			err:    "field A: unknown ssz option \"bitlst\"",
+		},
+	}
+	for _, tt := range tests {
+		_, built, err := buildSource(t, "package test\ntype S struct {\n"+tt.fields+"\n}\n", "S")
+		checkBuild(t, tt.fields, built, err, tt.want, tt.err)
+	}
+}
+
+// checkBuild checks the fields of the built struct against the expected names,
+// or the build error against the expected one.
+func checkBuild(t *testing.T, fields string, built []sszType, err error, want []string, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || err.Error() != wantErr {
+			t.Errorf("%q: error mismatch: have %v, want %s", fields, err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%q: failed to build: %v", fields, err)
+		return
+	}
+	if have := built[0].(*sszStruct).fieldNames; strings.Join(have, ",") != strings.Join(want, ",") {
+		t.Errorf("%q: fields mismatch: have %v, want %v", fields, have, want)
+	}
+}
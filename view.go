@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"math/bits"
+	"strings"
+
+	"github.com/rjl493456442/sszgen/ssz"
+)
+
+// viewName returns the name of the view type of the struct, which keeps the
+// struct in its merkle tree.
+func viewName(s *sszStruct) string {
+	return s.typeName() + "View"
+}
+
+// viewLiteral returns the expression creating the view of the struct from the
+// node, along with the fork of the enclosing view if the struct is viewed at
+// the fork.
+func viewLiteral(s *sszStruct, node string) string {
+	if s.atFork {
+		return fmt.Sprintf("&%s{node: %s, fork: v.fork}", viewName(s), node)
+	}
+	return fmt.Sprintf("&%s{node: %s}", viewName(s), node)
+}
+
+// viewStruct returns the struct accessed through its view if the type is the
+// struct or the pointer to it.
+func viewStruct(typ sszType) *sszStruct {
+	switch t := typ.(type) {
+	case *sszStruct:
+		return t
+	case *sszPointer:
+		if s, ok := t.elem.(*sszStruct); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// goType returns the Go type expression of the type.
+func goType(ctx *genContext, typ sszType) string {
+	var named *types.Named
+	switch t := typ.(type) {
+	case *sszBasic:
+		if named = t.named; named == nil {
+			return ctx.typeString(t.basic)
+		}
+	case *sszVector:
+		if named = t.named; named == nil {
+			return ctx.typeString(t.array)
+		}
+	case *sszList:
+		if named = t.named; named == nil {
+			return ctx.typeString(t.slice)
+		}
+	case *sszPointer:
+		if named = t.named; named == nil {
+			return ctx.typeString(t.Pointer)
+		}
+	case *sszStruct:
+		named = t.named
+	}
+	return ctx.typeString(named)
+}
+
+// packedSize returns the size of the items packed into the chunks, or zero for
+// the composite items.
+func packedSize(elem sszType) int {
+	if b, ok := elem.(*sszBasic); ok {
+		return b.size
+	}
+	return 0
+}
+
+// viewDepth returns the depth of the tree of the n items, which are packed into
+// the chunks if they are basic values.
+func viewDepth(n int64, elem sszType) int {
+	chunks := n
+	if size := packedSize(elem); size != 0 {
+		chunks = (n*int64(size) + 31) / 32
+	}
+	if chunks <= 1 {
+		return 0
+	}
+	return bits.Len64(uint64(chunks - 1))
+}
+
+// viewBasic returns the expression of the basic value from the integer packed.
+func viewBasic(ctx *genContext, b *sszBasic, v string) string {
+	if b.basic.Kind() == types.Bool {
+		v += " != 0"
+		if b.named == nil {
+			return v
+		}
+	} else if b.named == nil && b.basic.Kind() == types.Uint64 {
+		return v
+	}
+	return fmt.Sprintf("%s(%s)", goType(ctx, b), v) // explicit type conversion
+}
+
+// readUint returns the expression of the integer in the bytes.
+func readUint(ctx *genContext, buf string) string {
+	return fmt.Sprintf("%s(%s)", ctx.qualifier(pkgPath, "ReadUint"), buf)
+}
+
+// genViewGet generates the conversion of the tree at the node into the value of
+// the type.
+func genViewGet(ctx *genContext, typ sszType, dst string, node string) string {
+	var b bytes.Buffer
+	switch t := typ.(type) {
+	case *sszBasic:
+		fmt.Fprintf(&b, "%s = %s\n", dst, viewBasic(ctx, t, readUint(ctx, fmt.Sprintf("%s.Bytes(0, %d)", node, t.size))))
+	case *sszVector:
+		genViewItems(ctx, &b, t.elem, dst, node, fmt.Sprint(viewDepth(t.len, t.elem)), fmt.Sprint(t.len))
+	case *sszList:
+		if t.bitlist {
+			fmt.Fprintf(&b, "%s = %s(%s, %d)\n", dst, ctx.qualifier(pkgPath, "ReadBitlist"), node, t.tag.limit)
+			break
+		}
+		if t.tag.size != 0 {
+			fmt.Fprintf(&b, "%s = make(%s, %d)\n", dst, goType(ctx, t), t.tag.size)
+			genViewItems(ctx, &b, t.elem, dst, node, fmt.Sprint(viewDepth(t.tag.size, t.elem)), fmt.Sprint(t.tag.size))
+			break
+		}
+		var (
+			items = ctx.tmpVar("d")
+			depth = ctx.tmpVar("depth")
+			n     = ctx.tmpVar("n")
+		)
+		fmt.Fprintf(&b, "%s, %s, %s := %s(%s, %d, %d)\n", items, depth, n, ctx.qualifier(pkgPath, "ListOf"), node, t.tag.limit, packedSize(t.elem))
+		fmt.Fprintf(&b, "%s = make(%s, %s)\n", dst, goType(ctx, t), n)
+		genViewItems(ctx, &b, t.elem, dst, items, depth, n)
+	case *sszPointer:
+		if s, ok := t.elem.(*sszStruct); ok {
+			fmt.Fprintf(&b, "%s = (%s).ToStruct()\n", dst, viewLiteral(s, node))
+			break
+		}
+		fmt.Fprintf(&b, "%s = new(%s)\n", dst, goType(ctx, t.elem))
+		fmt.Fprint(&b, genViewGet(ctx, t.elem, "(*"+dst+")", node))
+	case *sszStruct:
+		fmt.Fprintf(&b, "%s = *(%s).ToStruct()\n", dst, viewLiteral(t, node))
+	}
+	return b.String()
+}
+
+// genViewItems generates the conversion of the n items in the chunks at the
+// depth under the node into the vector or the list allocated.
+func genViewItems(ctx *genContext, b *bytes.Buffer, elem sszType, dst string, node string, depth string, n string) {
+	if isBytes(elem) {
+		fmt.Fprintf(b, "copy(%s[:], %s.Bytes(%s, %s))\n", dst, node, depth, n)
+		return
+	}
+	cnt := ctx.tmpVar("i")
+	if basic, ok := elem.(*sszBasic); ok {
+		buf := ctx.tmpVar("b")
+		fmt.Fprintf(b, "%s := %s.Bytes(%s, %s*%d)\n", buf, node, depth, n, basic.size)
+		fmt.Fprintf(b, "for %s := range %s {\n", cnt, dst)
+		fmt.Fprintf(b, "%s[%s] = %s\n", dst, cnt, viewBasic(ctx, basic, readUint(ctx, fmt.Sprintf("%s[%s*%d:(%s+1)*%d]", buf, cnt, basic.size, cnt, basic.size))))
+		fmt.Fprint(b, "}\n")
+		return
+	}
+	nodes := ctx.tmpVar("n")
+	fmt.Fprintf(b, "%s := %s.Nodes(%s, %s)\n", nodes, node, depth, n)
+	fmt.Fprintf(b, "for %s := range %s {\n", cnt, dst)
+	fmt.Fprint(b, genViewGet(ctx, elem, fmt.Sprintf("%s[%s]", dst, cnt), fmt.Sprintf("%s[%s]", nodes, cnt)))
+	fmt.Fprint(b, "}\n")
+}
+
+// genViewTree generates the building of the tree of the value into the node,
+// returning the error if the value can't be hashed.
+func genViewTree(ctx *genContext, typ sszType, fork bool, obj string) string {
+	var b bytes.Buffer
+	ctx.nvar, ctx.topType = 0, false
+
+	fmt.Fprintf(&b, "node, err := %s(func(h *%s) error {\n", ctx.qualifier(pkgPath, "TreeOf"), ctx.qualifier(pkgPath, "Hasher"))
+	if fork {
+		fmt.Fprint(&b, "fork := v.fork\n")
+	}
+	fmt.Fprint(&b, typ.genHasher(ctx, obj))
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "})\n")
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n")
+	return b.String()
+}
+
+// viewSequence returns the layout of the list or vector for accessing the items,
+// or an empty string if the items are not accessed one by one, e.g. the bytes.
+func viewSequence(ctx *genContext, typ sszType, gindex string) (string, sszType) {
+	var (
+		elem  sszType
+		list  bool
+		limit int64
+	)
+	switch t := typ.(type) {
+	case *sszVector:
+		elem, limit = t.elem, t.len
+	case *sszList:
+		if t.bitlist || (t.tag.size == 0 && t.tag.limit == 0) {
+			return "", nil
+		}
+		elem, list, limit = t.elem, t.tag.size == 0, t.tag.size
+		if list {
+			limit = t.tag.limit
+		}
+	default:
+		return "", nil
+	}
+	if isBytes(elem) {
+		return "", nil
+	}
+	fields := []string{"GIndex: " + gindex}
+	if list {
+		fields = append(fields, "List: true")
+	}
+	fields = append(fields, fmt.Sprintf("Limit: %d", limit))
+	if size := packedSize(elem); size != 0 {
+		fields = append(fields, fmt.Sprintf("Size: %d", size))
+	}
+	return fmt.Sprintf("%s{%s}", ctx.qualifier(pkgPath, "Sequence"), strings.Join(fields, ", ")), elem
+}
+
+// generateView generates the view of the struct, which keeps the struct in its
+// merkle tree with the fields read and replaced in place. The views are copied
+// in constant time, sharing the trees until they are modified.
+func generateView(ctx *genContext, typ sszType) ([]byte, error) {
+	var b bytes.Buffer
+	ctx.reset()
+
+	// TODO non-struct types are not supported yet
+	s, ok := typ.(*sszStruct)
+	if !ok {
+		return nil, nil
+	}
+	var (
+		view   = viewName(s)
+		forked = forkGated(s)
+		node   = ctx.qualifier(pkgPath, "Node")
+		fork   = ctx.qualifier(pkgPath, "Fork")
+		fields = s.fields
+	)
+	ctx.addImport(pkgPath, "")
+	fmt.Fprintf(&b, "type %s struct {\n", view)
+	fmt.Fprintf(&b, "node *%s\n", node)
+	if forked {
+		fmt.Fprintf(&b, "fork %s\n", fork)
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	if forked {
+		fmt.Fprintf(&b, "func New%s(obj *%s, fork %s) (*%s, error) {\n", view, s.typeName(), fork, view)
+		fmt.Fprintf(&b, "node, err := %s(%s(obj, fork))\n", ctx.qualifier(pkgPath, "BuildTree"), ctx.qualifier(pkgPath, "AtFork"))
+	} else {
+		fmt.Fprintf(&b, "func New%s(obj *%s) (*%s, error) {\n", view, s.typeName(), view)
+		fmt.Fprintf(&b, "node, err := %s(obj)\n", ctx.qualifier(pkgPath, "BuildTree"))
+	}
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return nil, err\n")
+	fmt.Fprint(&b, "}\n")
+	if forked {
+		fmt.Fprintf(&b, "return &%s{node: node, fork: fork}, nil\n", view)
+	} else {
+		fmt.Fprintf(&b, "return &%s{node: node}, nil\n", view)
+	}
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) Copy() *%s {\n", view, view)
+	fmt.Fprint(&b, "cpy := *v\n")
+	fmt.Fprint(&b, "return &cpy\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) HashTreeRoot() [32]byte {\n", view)
+	fmt.Fprint(&b, "return v.node.Root()\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) Tree() *%s {\n", view, node)
+	fmt.Fprint(&b, "return v.node\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) update(node *%s, err error) error {\n", view, node)
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, "v.node = node\n")
+	fmt.Fprint(&b, "return nil\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	// The fields of the fork-gated struct are placed by the fork, resolve the
+	// generalized indexes at runtime with zero for the ones absent.
+	gindex := func(i int) string {
+		return fmt.Sprint(fieldGIndex(s, i))
+	}
+	if forked {
+		fmt.Fprintf(&b, "func (v *%s) Fork() %s {\n", view, fork)
+		fmt.Fprint(&b, "return v.fork\n")
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func (v *%s) gindex(field int) uint64 {\n", view)
+		fmt.Fprint(&b, "fork := v.fork\n")
+		fmt.Fprint(&b, genForkSwitch(ctx, s, func(fv *sszStruct) string {
+			gindexes := make([]string, len(s.fields))
+			for i, name := range s.fieldNames {
+				gindexes[i] = "0"
+				for j := range fv.fields {
+					if fv.fieldNames[j] == name {
+						gindexes[i] = fmt.Sprint(fieldGIndex(fv, j))
+					}
+				}
+			}
+			return fmt.Sprintf("return [...]uint64{%s}[field]\n", strings.Join(gindexes, ", "))
+		}))
+		fmt.Fprint(&b, "}\n\n")
+
+		gindex = func(i int) string {
+			return fmt.Sprintf("v.gindex(%d)", i)
+		}
+		// The types of the fields are resolved at any fork, the nested structs
+		// are accessed through their views at the fork of this one anyway.
+		views := make(map[*sszStruct]*sszStruct)
+		fields = make([]sszType, len(s.fields))
+		for i, field := range s.fields {
+			fields[i] = forkView(field, ssz.ForkPhase0, views)
+		}
+	}
+	for i, field := range fields {
+		name := s.fieldNames[i]
+		fmt.Fprint(&b, genViewField(ctx, s, view, name, field, forkGated(s.fields[i]), gindex(i)))
+	}
+	fmt.Fprintf(&b, "func (v *%s) ToStruct() *%s {\n", view, s.typeName())
+	fmt.Fprintf(&b, "obj := new(%s)\n", s.typeName())
+	for i, field := range fields {
+		name := s.fieldNames[i]
+		if viewStruct(field) == nil {
+			fmt.Fprintf(&b, "obj.%s = v.Get%s()\n", name, name)
+			continue
+		}
+		deref := "*"
+		if _, ok := field.(*sszPointer); ok {
+			deref = ""
+		}
+		fmt.Fprintf(&b, "if c := v.Get%s(); c != nil {\n", name)
+		fmt.Fprintf(&b, "obj.%s = %sc.ToStruct()\n", name, deref)
+		fmt.Fprint(&b, "}\n")
+	}
+	fmt.Fprint(&b, "return obj\n")
+	fmt.Fprint(&b, "}\n")
+	return b.Bytes(), nil
+}
+
+// genViewField generates the accessors of the field in the view, along with the
+// ones of the items if the field is a list or vector.
+func genViewField(ctx *genContext, s *sszStruct, view string, name string, field sszType, forked bool, gindex string) string {
+	var b bytes.Buffer
+	ctx.nvar = 0
+
+	// The nested structs are accessed through their views sharing the tree,
+	// the other values are converted from and into the subtrees.
+	if child := viewStruct(field); child != nil {
+		fmt.Fprintf(&b, "func (v *%s) Get%s() *%s {\n", view, name, viewName(child))
+		fmt.Fprintf(&b, "node, err := v.node.Get(%s)\n", gindex)
+		fmt.Fprint(&b, "if err != nil {\n")
+		fmt.Fprint(&b, "return nil\n")
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "return %s\n", viewLiteral(child, "node"))
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func (v *%s) Set%s(x *%s) error {\n", view, name, viewName(child))
+		fmt.Fprint(&b, genViewCheck(ctx, child))
+		fmt.Fprintf(&b, "return v.update(v.node.Set(%s, x.node))\n", gindex)
+		fmt.Fprint(&b, "}\n\n")
+		return b.String()
+	}
+	typ := goType(ctx, field)
+	fmt.Fprintf(&b, "func (v *%s) Get%s() %s {\n", view, name, typ)
+	fmt.Fprintf(&b, "var x %s\n", typ)
+	fmt.Fprintf(&b, "node, err := v.node.Get(%s)\n", gindex)
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return x\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, genViewGet(ctx, field, "x", "node"))
+	fmt.Fprint(&b, "return x\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) Set%s(x %s) error {\n", view, name, typ)
+	fmt.Fprint(&b, genViewTree(ctx, field, forked, "x"))
+	fmt.Fprintf(&b, "return v.update(v.node.Set(%s, node))\n", gindex)
+	fmt.Fprint(&b, "}\n\n")
+
+	seq, elem := viewSequence(ctx, field, gindex)
+	if seq == "" {
+		return b.String()
+	}
+	_, list := field.(*sszList)
+	list = list && field.(*sszList).tag.size == 0
+
+	fmt.Fprintf(&b, "func (v *%s) Len%s() int {\n", view, name)
+	fmt.Fprintf(&b, "n, _ := %s.Len(v.node)\n", seq)
+	fmt.Fprint(&b, "return n\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	// Generate the accessors of the items by their kinds
+	ctx.nvar = 0
+	if basic, ok := elem.(*sszBasic); ok {
+		typ := goType(ctx, basic)
+		fmt.Fprintf(&b, "func (v *%s) Get%sAt(i int) (%s, error) {\n", view, name, typ)
+		fmt.Fprintf(&b, "n, err := %s.Packed(v.node, i)\n", seq)
+		fmt.Fprint(&b, "if err != nil {\n")
+		fmt.Fprintf(&b, "var x %s\n", typ)
+		fmt.Fprint(&b, "return x, err\n")
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "return %s, nil\n", viewBasic(ctx, basic, "n"))
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func (v *%s) Set%sAt(i int, x %s) error {\n", view, name, typ)
+		val := packedValue(&b, basic, "x")
+		fmt.Fprintf(&b, "return v.update(%s.SetPacked(v.node, i, %s))\n", seq, val)
+		fmt.Fprint(&b, "}\n\n")
+
+		if list {
+			fmt.Fprintf(&b, "func (v *%s) Append%s(x %s) error {\n", view, name, typ)
+			val := packedValue(&b, basic, "x")
+			fmt.Fprintf(&b, "return v.update(%s.AppendPacked(v.node, %s))\n", seq, val)
+			fmt.Fprint(&b, "}\n\n")
+		}
+		return b.String()
+	}
+	if child := viewStruct(elem); child != nil {
+		fmt.Fprintf(&b, "func (v *%s) Get%sAt(i int) (*%s, error) {\n", view, name, viewName(child))
+		fmt.Fprintf(&b, "node, err := %s.Item(v.node, i)\n", seq)
+		fmt.Fprint(&b, "if err != nil {\n")
+		fmt.Fprint(&b, "return nil, err\n")
+		fmt.Fprint(&b, "}\n")
+		fmt.Fprintf(&b, "return %s, nil\n", viewLiteral(child, "node"))
+		fmt.Fprint(&b, "}\n\n")
+
+		fmt.Fprintf(&b, "func (v *%s) Set%sAt(i int, x *%s) error {\n", view, name, viewName(child))
+		fmt.Fprint(&b, genViewCheck(ctx, child))
+		fmt.Fprintf(&b, "return v.update(%s.SetItem(v.node, i, x.node))\n", seq)
+		fmt.Fprint(&b, "}\n\n")
+
+		if list {
+			fmt.Fprintf(&b, "func (v *%s) Append%s(x *%s) error {\n", view, name, viewName(child))
+			fmt.Fprint(&b, genViewCheck(ctx, child))
+			fmt.Fprintf(&b, "return v.update(%s.Append(v.node, x.node))\n", seq)
+			fmt.Fprint(&b, "}\n\n")
+		}
+		return b.String()
+	}
+	typ = goType(ctx, elem)
+	fmt.Fprintf(&b, "func (v *%s) Get%sAt(i int) (%s, error) {\n", view, name, typ)
+	fmt.Fprintf(&b, "var x %s\n", typ)
+	fmt.Fprintf(&b, "node, err := %s.Item(v.node, i)\n", seq)
+	fmt.Fprint(&b, "if err != nil {\n")
+	fmt.Fprint(&b, "return x, err\n")
+	fmt.Fprint(&b, "}\n")
+	fmt.Fprint(&b, genViewGet(ctx, elem, "x", "node"))
+	fmt.Fprint(&b, "return x, nil\n")
+	fmt.Fprint(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "func (v *%s) Set%sAt(i int, x %s) error {\n", view, name, typ)
+	fmt.Fprint(&b, genViewTree(ctx, elem, forked, "x"))
+	fmt.Fprintf(&b, "return v.update(%s.SetItem(v.node, i, node))\n", seq)
+	fmt.Fprint(&b, "}\n\n")
+
+	if list {
+		fmt.Fprintf(&b, "func (v *%s) Append%s(x %s) error {\n", view, name, typ)
+		fmt.Fprint(&b, genViewTree(ctx, elem, forked, "x"))
+		fmt.Fprintf(&b, "return v.update(%s.Append(v.node, node))\n", seq)
+		fmt.Fprint(&b, "}\n\n")
+	}
+	return b.String()
+}
+
+// genViewCheck generates the check of the nested view being set, which must
+// be at the same fork as this one if the struct is viewed at the fork.
+func genViewCheck(ctx *genContext, s *sszStruct) string {
+	var b bytes.Buffer
+	fmt.Fprint(&b, "if x == nil {\n")
+	fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrNilPointer"))
+	fmt.Fprint(&b, "}\n")
+	if s.atFork {
+		fmt.Fprint(&b, "if x.fork != v.fork {\n")
+		fmt.Fprintf(&b, "return %s\n", ctx.qualifier(pkgPath, "ErrForkMismatch"))
+		fmt.Fprint(&b, "}\n")
+	}
+	return b.String()
+}
+
+// packedValue returns the expression of the basic value as the integer packed,
+// emitting the conversion of the booleans into b.
+func packedValue(b *bytes.Buffer, basic *sszBasic, obj string) string {
+	switch {
+	case basic.basic.Kind() == types.Bool:
+		fmt.Fprint(b, "var n uint64\n")
+		fmt.Fprintf(b, "if %s {\n", obj)
+		fmt.Fprint(b, "n = 1\n")
+		fmt.Fprint(b, "}\n")
+		return "n"
+	case basic.named == nil && basic.basic.Kind() == types.Uint64:
+		return obj
+	default:
+		return fmt.Sprintf("uint64(%s)", obj)
+	}
+}